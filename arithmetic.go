@@ -0,0 +1,156 @@
+package charlatan
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// arithmeticOperation is an addition or a subtraction
+type arithmeticOperation struct {
+	left     operand
+	operator operatorType
+	right    operand
+}
+
+// newArithmeticOperation creates a new arithmetic operation from the given
+// operands
+func newArithmeticOperation(left operand, operator operatorType, right operand) (*arithmeticOperation, error) {
+
+	if left == nil || right == nil {
+		return nil, fmt.Errorf("Can't creates a new arithmetic operation with a nil operand")
+	}
+
+	if !operator.isArithmetic() {
+		return nil, fmt.Errorf("The operator should be an arithmetic operator")
+	}
+
+	return &arithmeticOperation{left, operator, right}, nil
+}
+
+// Evaluate evaluates the operation against the given record. Null operands
+// give a null result.
+func (o *arithmeticOperation) Evaluate(record Record) (*Const, error) {
+	left, err := o.left.Evaluate(record)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := o.right.Evaluate(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if left.IsNull() || right.IsNull() {
+		return NullConst(), nil
+	}
+
+	if left.IsNumeric() && right.IsNumeric() {
+		return addNumerics(*left, o.operator == operatorMinus, *right), nil
+	}
+
+	if left.isTime() || right.isTime() {
+		if c, ok := addTimes(*left, o.operator == operatorMinus, *right); ok {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Can't compute %s(%v) %s %s(%v)",
+		left.constType, left.Value(), o.operator, right.constType, right.Value())
+}
+
+func (o *arithmeticOperation) String() string {
+	return fmt.Sprintf("%s %s %s", o.left, o.operator, o.right)
+}
+
+// addNumerics adds or subtracts two numerics. Ints which overflow become
+// bigints, and the operations on bigints and decimals are exact.
+func addNumerics(c1 Const, minus bool, c2 Const) *Const {
+	if c1.constType == constInt && c2.constType == constInt {
+		i1, i2 := c1.intValue, c2.intValue
+
+		if !minus && !(i2 > 0 && i1 > math.MaxInt64-i2) && !(i2 < 0 && i1 < math.MinInt64-i2) {
+			return IntConst(i1 + i2)
+		}
+		if minus && !(i2 < 0 && i1 > math.MaxInt64+i2) && !(i2 > 0 && i1 < math.MinInt64+i2) {
+			return IntConst(i1 - i2)
+		}
+	}
+
+	// floats stay floats unless they're mixed with bigints or decimals
+	bothInts := c1.constType == constInt && c2.constType == constInt
+	isBig := c1.isBig() || c2.isBig()
+
+	if (!isBig && !bothInts) || c1.floatRank() != 0 || c2.floatRank() != 0 {
+		if minus {
+			return FloatConst(c1.AsFloat() - c2.AsFloat())
+		}
+		return FloatConst(c1.AsFloat() + c2.AsFloat())
+	}
+
+	r := new(big.Rat)
+	if minus {
+		r.Sub(c1.asRat(), c2.asRat())
+	} else {
+		r.Add(c1.asRat(), c2.asRat())
+	}
+
+	if c1.constType == constDecimal || c2.constType == constDecimal ||
+		c1.constType == constFloat || c2.constType == constFloat {
+
+		return DecimalConst(r, maxInt(c1.decimalScale(), c2.decimalScale()))
+	}
+
+	if r.Num().IsInt64() {
+		return IntConst(r.Num().Int64())
+	}
+
+	return BigIntConst(r.Num())
+}
+
+// decimalScale returns the number of digits after the decimal point the
+// numeric const is written with
+func (c Const) decimalScale() int {
+	switch c.constType {
+	case constDecimal:
+		return c.scale
+	case constFloat:
+		return decimalScale(c.asRat())
+	}
+	return 0
+}
+
+// addTimes adds or subtracts a timestamp and a duration, two durations, or
+// subtracts two timestamps. One of the consts must be a timestamp or a
+// duration, the other one can be a string, which is then parsed. The boolean
+// is false if the operation isn't supported for the two consts.
+func addTimes(c1 Const, minus bool, c2 Const) (*Const, bool) {
+	t1, isTimestamp1 := c1.asTimestamp()
+	t2, isTimestamp2 := c2.asTimestamp()
+	d1, isDuration1 := c1.asDuration()
+	d2, isDuration2 := c2.asDuration()
+
+	if minus {
+		d2 = -d2
+	}
+
+	switch {
+	case isTimestamp1 && isDuration2:
+		return TimestampConst(t1.Add(d2)), true
+	case isDuration1 && isTimestamp2 && !minus:
+		return TimestampConst(t2.Add(d1)), true
+	case isTimestamp1 && isTimestamp2 && minus:
+		return DurationConst(t1.Sub(t2)), true
+	case isDuration1 && isDuration2:
+		return DurationConst(d1 + d2), true
+	}
+
+	return nil, false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
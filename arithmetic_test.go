@@ -0,0 +1,97 @@
+package charlatan
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewArithmeticOperation(t *testing.T) {
+	_, err := newArithmeticOperation(nil, operatorPlus, IntConst(1))
+	assert.NotNil(t, err)
+
+	_, err = newArithmeticOperation(IntConst(1), operatorPlus, nil)
+	assert.NotNil(t, err)
+
+	_, err = newArithmeticOperation(IntConst(1), operatorEq, IntConst(1))
+	assert.NotNil(t, err)
+
+	o, err := newArithmeticOperation(NewField("a"), operatorMinus, IntConst(1))
+	require.Nil(t, err)
+	assert.Equal(t, "a - 1", o.String())
+}
+
+func TestArithmeticOperationEvaluate(t *testing.T) {
+	for _, tc := range []struct {
+		left     *Const
+		operator operatorType
+		right    *Const
+		expected *Const
+	}{
+		// numerics
+		{IntConst(40), operatorPlus, IntConst(2), IntConst(42)},
+		{IntConst(40), operatorMinus, IntConst(-2), IntConst(42)},
+		{IntConst(math.MaxInt64), operatorPlus, IntConst(1), bigInt("9223372036854775808")},
+		{IntConst(math.MinInt64), operatorMinus, IntConst(1), bigInt("-9223372036854775809")},
+		{IntConst(1), operatorPlus, FloatConst(0.5), FloatConst(1.5)},
+		{FloatConst(0.5), operatorMinus, FloatConst(0.25), FloatConst(0.25)},
+		{bigInt("1e20"), operatorMinus, bigInt("1e20"), IntConst(0)},
+		{decimal("0.10"), operatorPlus, decimal("0.2"), decimal("0.30")},
+		{decimal("1.5"), operatorPlus, IntConst(1), decimal("2.5")},
+		{decimal("1"), operatorPlus, FloatConst(0.1), decimal("1.1")},
+		{decimal("1"), operatorPlus, FloatConst(math.Inf(1)), FloatConst(math.Inf(1))},
+
+		// times
+		{timestamp("2016-12-28T10:00:00Z"), operatorMinus, duration("1h"), timestamp("2016-12-28T09:00:00Z")},
+		{timestamp("2016-12-28T10:00:00Z"), operatorPlus, duration("1d"), timestamp("2016-12-29T10:00:00Z")},
+		{duration("1d"), operatorPlus, timestamp("2016-12-28T10:00:00Z"), timestamp("2016-12-29T10:00:00Z")},
+		{timestamp("2016-12-28T10:00:00Z"), operatorMinus, timestamp("2016-12-28T09:30:00Z"), duration("30m")},
+		{duration("1h"), operatorMinus, duration("90m"), duration("-30m")},
+		{duration("1h"), operatorPlus, duration("1s"), duration("1h0m1s")},
+
+		// strings are parsed
+		{StringConst("2016-12-28 10:00:00"), operatorPlus, duration("5m"), timestamp("2016-12-28T10:05:00Z")},
+		{timestamp("2016-12-28T10:00:00Z"), operatorMinus, StringConst("5m"), timestamp("2016-12-28T09:55:00Z")},
+		{timestamp("2016-12-28T10:00:00Z"), operatorMinus, StringConst("2016-12-28"), duration("10h")},
+
+		// nulls
+		{NullConst(), operatorPlus, IntConst(1), NullConst()},
+		{timestamp("2016-12-28T10:00:00Z"), operatorMinus, NullConst(), NullConst()},
+	} {
+		o, err := newArithmeticOperation(tc.left, tc.operator, tc.right)
+		require.Nil(t, err)
+
+		c, err := o.Evaluate(nil)
+		if assert.Nil(t, err, o.String()) {
+			assert.Equal(t, tc.expected.constType, c.constType, o.String())
+			assert.Equal(t, tc.expected.AsString(), c.AsString(), o.String())
+		}
+	}
+}
+
+func TestArithmeticOperationEvaluateErrors(t *testing.T) {
+	for _, tc := range [][2]*Const{
+		{StringConst("a"), IntConst(1)},
+		{BoolConst(true), IntConst(1)},
+		{duration("1h"), StringConst("a")},
+		{duration("1h"), IntConst(1)},
+		{timestamp("2016-12-28T10:00:00Z"), timestamp("2016-12-28T10:00:00Z")},
+		{StringConst("1h"), StringConst("1h")},
+	} {
+		o, err := newArithmeticOperation(tc[0], operatorPlus, tc[1])
+		require.Nil(t, err)
+
+		_, err = o.Evaluate(nil)
+		assert.NotNil(t, err, o.String())
+	}
+
+	// a duration minus a timestamp
+	o, err := newArithmeticOperation(duration("1h"), operatorMinus, TimestampConst(time.Now()))
+	require.Nil(t, err)
+
+	_, err = o.Evaluate(nil)
+	assert.NotNil(t, err)
+}
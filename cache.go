@@ -0,0 +1,221 @@
+package charlatan
+
+import (
+	"bytes"
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// QueryCache is a cache of parsed queries, safe for concurrent use.
+//
+// Queries are cached by their normalized text: whitespaces and keywords case
+// don't matter, and the constants of the WHERE clause are replaced by bind
+// parameters. This means "SELECT a FROM b WHERE c > 2" and
+// "select a from b where c > 42" share the same cache entry and are only
+// parsed once.
+type QueryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// most recently used first
+	lru *list.List
+
+	hits, misses uint64
+}
+
+// QueryCacheStats are the statistics of a QueryCache
+type QueryCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// the number of cached queries
+	Size int
+}
+
+type queryCacheEntry struct {
+	key   string
+	query *Query
+}
+
+// NewQueryCache returns a new cache that holds up to size queries. The least
+// recently used queries are evicted first. A size of zero or less means the
+// cache is unbounded.
+func NewQueryCache(size int) *QueryCache {
+	return &QueryCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns the query for the given string, parsing it only if its
+// normalized form isn't in the cache yet. The returned query must not be
+// modified; it can be evaluated concurrently.
+func (c *QueryCache) Get(s string) (*Query, error) {
+	key, values, err := normalizeQuery(s)
+	if err != nil {
+		c.mu.Lock()
+		c.misses++
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	q, ok := c.lookup(key)
+	if !ok {
+		if q, err = QueryFromString(key); err != nil {
+			// report the error with the positions of the original string
+			if _, origErr := QueryFromString(s); origErr != nil {
+				err = origErr
+			}
+			return nil, err
+		}
+		c.add(key, q)
+	}
+
+	if len(values) == 0 {
+		return q, nil
+	}
+
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+
+	return q.Bind(args...)
+}
+
+// Stats returns the current statistics of the cache
+func (c *QueryCache) Stats() QueryCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return QueryCacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+		Size:   c.lru.Len(),
+	}
+}
+
+func (c *QueryCache) lookup(key string) (*Query, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	c.lru.MoveToFront(e)
+
+	return e.Value.(*queryCacheEntry).query, true
+}
+
+func (c *QueryCache) add(key string, q *Query) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// another goroutine may have parsed it in the meantime
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&queryCacheEntry{key: key, query: q})
+
+	if c.size > 0 && c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*queryCacheEntry).key)
+	}
+}
+
+// normalizeQuery returns the normalized text of a query, along with the
+// constants of its WHERE clause that were replaced by positional parameters.
+// Queries that already have parameters are normalized without replacing
+// anything.
+func normalizeQuery(s string) (string, []*Const, error) {
+	toks := make([]*token, 0)
+	hasParameters := false
+
+	l := lexerFromString(s)
+
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			return "", nil, err
+		}
+		if tok.isEnd() {
+			break
+		}
+		if tok.isParameter() {
+			hasParameters = true
+		}
+		toks = append(toks, tok)
+	}
+
+	var buf bytes.Buffer
+	values := make([]*Const, 0)
+	inWhere := false
+
+	for i, tok := range toks {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+
+		switch tok.Type {
+		case tokWhere:
+			inWhere = true
+		case tokStarting, tokLimit:
+			inWhere = false
+		}
+
+		switch {
+		case tok.isConst() && inWhere && !hasParameters && !isTypedLiteral(toks, i):
+			c, err := tok.Const()
+			if err != nil {
+				return "", nil, err
+			}
+			values = append(values, c)
+			buf.WriteByte('?')
+
+		case tok.Type == tokString:
+			buf.WriteString(quoteString(tok.Value))
+
+		case tok.isConst():
+			buf.WriteString(tok.Value)
+
+		case tok.isField():
+			buf.WriteString(quoteField(tok.Value))
+
+		case tok.isParameter():
+			if tok.Value == "" {
+				buf.WriteByte('?')
+			} else {
+				buf.WriteString(":" + tok.Value)
+			}
+
+		default:
+			buf.WriteString(tok.Type.text())
+		}
+	}
+
+	return buf.String(), values, nil
+}
+
+// isTypedLiteral tests if the token at the given index is the value of a
+// typed literal, e.g. the string of TIMESTAMP '2016-12-28T10:00:00Z', which
+// can't be replaced by a parameter
+func isTypedLiteral(toks []*token, i int) bool {
+	if i == 0 || toks[i].Type != tokString || !toks[i-1].isField() {
+		return false
+	}
+
+	switch strings.ToUpper(toks[i-1].Value) {
+	case timestampLiteral, intervalLiteral:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package charlatan
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	for s, expected := range map[string]string{
+		"SELECT a FROM b":                                   "SELECT a FROM b",
+		"  select   a,b  from   `my file.csv`  ":            "SELECT a , b FROM `my file.csv`",
+		"SELECT a FROM b WHERE c > 2 && d = 'x' LIMIT 3, 4": "SELECT a FROM b WHERE c > ? AND d = ? LIMIT 3 , 4",
+		"SELECT a FROM b where (c) || true starting at 10":  "SELECT a FROM b WHERE ( c ) OR ? STARTING AT 10",
+		"SELECT a FROM b WHERE c BETWEEN 1 AND 2.5":         "SELECT a FROM b WHERE c BETWEEN ? AND ?",
+		"SELECT a FROM b WHERE c = ? AND d = 'x'":           `SELECT a FROM b WHERE c = ? AND d = "x"`,
+		`SELECT a FROM b WHERE c = :c AND d = 'say "hi"'`:   `SELECT a FROM b WHERE c = :c AND d = "say \"hi\""`,
+		"SELECT `a``b` FROM b":                              "SELECT `a``b` FROM b",
+		"SELECT a /* x */ FROM b -- y":                      "SELECT a FROM b",
+	} {
+		n, _, err := normalizeQuery(s)
+		require.Nil(t, err)
+		assert.Equal(t, expected, n)
+	}
+}
+
+func TestNormalizeQueryValues(t *testing.T) {
+	_, values, err := normalizeQuery("SELECT a FROM b WHERE c > 2 AND d = 'x' OR e = null LIMIT 3")
+	require.Nil(t, err)
+	require.Equal(t, 3, len(values))
+	assert.Equal(t, int64(2), values[0].AsInt())
+	assert.Equal(t, "x", values[1].AsString())
+	assert.True(t, values[2].IsNull())
+}
+
+func TestQueryCacheGet(t *testing.T) {
+	c := NewQueryCache(0)
+
+	q, err := c.Get("SELECT name FROM b WHERE age > 10")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT name FROM b WHERE age > 10", q.String())
+
+	m, err := q.Evaluate(&dummyPerson{name: "A", age: 15})
+	assert.Nil(t, err)
+	assert.True(t, m)
+
+	q, err = c.Get("select name from b where age > 20")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT name FROM b WHERE age > 20", q.String())
+
+	m, err = q.Evaluate(&dummyPerson{name: "A", age: 15})
+	assert.Nil(t, err)
+	assert.False(t, m)
+
+	assert.Equal(t, QueryCacheStats{Hits: 1, Misses: 1, Size: 1}, c.Stats())
+}
+
+func TestQueryCacheGetWithoutLiterals(t *testing.T) {
+	c := NewQueryCache(0)
+
+	q1, err := c.Get("SELECT name FROM b LIMIT 2")
+	require.Nil(t, err)
+
+	q2, err := c.Get("SELECT name   FROM b limit 2")
+	require.Nil(t, err)
+
+	assert.True(t, q1 == q2)
+
+	q3, err := c.Get("SELECT name FROM b LIMIT 3")
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), q3.Limit())
+
+	assert.Equal(t, QueryCacheStats{Hits: 1, Misses: 2, Size: 2}, c.Stats())
+}
+
+func TestQueryCacheGetWithParameters(t *testing.T) {
+	c := NewQueryCache(0)
+
+	q, err := c.Get("SELECT name FROM b WHERE age > ? AND name = 'A'")
+	require.Nil(t, err)
+	assert.Equal(t, 1, q.NumParameters())
+}
+
+func TestQueryCacheGetError(t *testing.T) {
+	c := NewQueryCache(0)
+
+	_, err := c.Get("SELECT name    FROM b WHERE age >")
+	require.NotNil(t, err)
+	assert.Equal(t, "Unexpected end of stream at position 34", err.Error())
+
+	_, err = c.Get("SELECT name FROM b WHERE age =! 2")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, QueryCacheStats{Hits: 0, Misses: 2, Size: 0}, c.Stats())
+}
+
+func TestQueryCacheEviction(t *testing.T) {
+	c := NewQueryCache(2)
+
+	for _, s := range []string{
+		"SELECT a FROM b",
+		"SELECT b FROM b",
+		"SELECT a FROM b",
+		"SELECT c FROM b",
+		"SELECT a FROM b",
+		"SELECT b FROM b",
+	} {
+		_, err := c.Get(s)
+		require.Nil(t, err)
+	}
+
+	assert.Equal(t, QueryCacheStats{Hits: 2, Misses: 4, Size: 2}, c.Stats())
+}
+
+func TestQueryCacheConcurrentGet(t *testing.T) {
+	c := NewQueryCache(10)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				q, err := c.Get(fmt.Sprintf("SELECT name FROM b WHERE age > %d", j))
+				if !assert.Nil(t, err) {
+					return
+				}
+
+				m, err := q.Evaluate(&dummyPerson{name: "A", age: 25})
+				assert.Nil(t, err)
+				assert.Equal(t, j < 25, m)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1000), stats.Hits+stats.Misses)
+	assert.Equal(t, 1, stats.Size)
+}
+
+func TestNormalizeQueryTypedLiterals(t *testing.T) {
+	n, values, err := normalizeQuery("select a from b where ts > timestamp '2016-12-28' - interval '1h' and DATE_TRUNC('day', ts) = 2")
+	require.Nil(t, err)
+	assert.Equal(t, `SELECT a FROM b WHERE ts > timestamp "2016-12-28" - interval "1h" AND DATE_TRUNC ( ? , ts ) = ?`, n)
+	assert.Equal(t, 2, len(values))
+
+	c := NewQueryCache(0)
+
+	q, err := c.Get("SELECT a FROM b WHERE DATE_TRUNC('day', ts) = TIMESTAMP '2016-12-28'")
+	require.Nil(t, err)
+	assert.Equal(t, `SELECT a FROM b WHERE DATE_TRUNC("day", ts) = TIMESTAMP "2016-12-28T00:00:00Z"`, q.String())
+
+	_, err = c.Get("SELECT a FROM b WHERE DATE_TRUNC('fortnight', ts) = TIMESTAMP '2016-12-28'")
+	assert.NotNil(t, err)
+}
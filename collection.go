@@ -0,0 +1,156 @@
+package charlatan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ArrayConst returns a new Const of type array
+func ArrayConst(values []*Const) *Const {
+	if values == nil {
+		values = []*Const{}
+	}
+	return &Const{arrayValue: values, constType: constArray}
+}
+
+// ObjectConst returns a new Const of type object
+func ObjectConst(values map[string]*Const) *Const {
+	if values == nil {
+		values = map[string]*Const{}
+	}
+	return &Const{objectValue: values, constType: constObject}
+}
+
+// IsArray tests if a const is an array
+func (c Const) IsArray() bool {
+	return c.constType == constArray
+}
+
+// IsObject tests if a const is an object
+func (c Const) IsObject() bool {
+	return c.constType == constObject
+}
+
+// Elements returns the elements of an array, or nil if the const isn't one
+func (c Const) Elements() []*Const {
+	return c.arrayValue
+}
+
+// Members returns the members of an object, or nil if the const isn't one
+func (c Const) Members() map[string]*Const {
+	return c.objectValue
+}
+
+// arrayFromValues converts the values into an array const
+func arrayFromValues(values []interface{}) (*Const, error) {
+	elements := make([]*Const, len(values))
+
+	for i, value := range values {
+		c, err := NewConst(value)
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = c
+	}
+
+	return ArrayConst(elements), nil
+}
+
+// objectFromValues converts the values into an object const
+func objectFromValues(values map[string]interface{}) (*Const, error) {
+	members := make(map[string]*Const, len(values))
+
+	for key, value := range values {
+		c, err := NewConst(value)
+		if err != nil {
+			return nil, err
+		}
+		members[key] = c
+	}
+
+	return ObjectConst(members), nil
+}
+
+// sortedKeys returns the keys of an object, sorted
+func sortedKeys(members map[string]*Const) []string {
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cmpArrays compares two arrays element by element, the shortest array is
+// the lowest if one is the beginning of the other
+func cmpArrays(a1, a2 []*Const) (int, error) {
+	for i := 0; i < len(a1) && i < len(a2); i++ {
+		r, err := a1[i].CompareTo(a2[i])
+		if err != nil || r != 0 {
+			return r, err
+		}
+	}
+
+	return cmpInts(int64(len(a1)), int64(len(a2))), nil
+}
+
+// cmpObjects compares two objects by their members, sorted by key
+func cmpObjects(o1, o2 map[string]*Const) (int, error) {
+	keys1, keys2 := sortedKeys(o1), sortedKeys(o2)
+
+	for i := 0; i < len(keys1) && i < len(keys2); i++ {
+		if r := cmpStrings(keys1[i], keys2[i]); r != 0 {
+			return r, nil
+		}
+
+		r, err := o1[keys1[i]].CompareTo(o2[keys2[i]])
+		if err != nil || r != 0 {
+			return r, err
+		}
+	}
+
+	return cmpInts(int64(len(keys1)), int64(len(keys2))), nil
+}
+
+// length returns the number of elements of an array, members of an object or
+// characters of a string
+func length(c *Const) (*Const, error) {
+	switch c.constType {
+	case constArray:
+		return IntConst(int64(len(c.arrayValue))), nil
+	case constObject:
+		return IntConst(int64(len(c.objectValue))), nil
+	case constString:
+		return IntConst(int64(utf8.RuneCountInString(c.stringValue))), nil
+	}
+
+	return nil, fmt.Errorf("Not an array, an object or a string: %s", c)
+}
+
+// contains tests if an array contains an element equal to the value, if an
+// object has a member with the value as key, or if a string contains the
+// value
+func contains(c, value *Const) (*Const, error) {
+	switch c.constType {
+	case constArray:
+		for _, element := range c.arrayValue {
+			// elements that can't be compared with the value aren't equal
+			// to it
+			if r, err := element.CompareTo(value); err == nil && r == 0 {
+				return BoolConst(true), nil
+			}
+		}
+		return BoolConst(false), nil
+
+	case constObject:
+		_, ok := c.objectValue[value.AsString()]
+		return BoolConst(ok), nil
+
+	case constString:
+		return BoolConst(strings.Contains(c.stringValue, value.AsString())), nil
+	}
+
+	return nil, fmt.Errorf("Not an array, an object or a string: %s", c)
+}
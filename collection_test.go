@@ -0,0 +1,155 @@
+package charlatan
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func array(values ...interface{}) *Const {
+	c, err := NewConst(values)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func object(values map[string]interface{}) *Const {
+	if values == nil {
+		return ObjectConst(nil)
+	}
+	c, err := NewConst(values)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestArrayConst(t *testing.T) {
+	c := array(1, "a", true, nil)
+
+	assert.True(t, c.IsArray())
+	assert.False(t, c.IsString())
+	assert.Equal(t, []interface{}{int64(1), "a", true, nil}, c.Value())
+	assert.Equal(t, `[1,"a",true,null]`, c.AsString())
+	assert.Equal(t, `[1,"a",true,null]`, c.String())
+	assert.Equal(t, int64(0), c.AsInt())
+	assert.Equal(t, 0.0, c.AsFloat())
+	assert.True(t, c.AsBool())
+	assert.True(t, ArrayConst(nil).AsBool())
+	assert.Equal(t, "[]", ArrayConst(nil).AsString())
+	// HTML characters aren't escaped
+	assert.Equal(t, `["<a>"]`, array("<a>").AsString())
+
+	require.Equal(t, 4, len(c.Elements()))
+	assert.Equal(t, "a", c.Elements()[1].AsString())
+}
+
+func TestObjectConst(t *testing.T) {
+	c := object(map[string]interface{}{"b": 1, "a": []interface{}{"x"}})
+
+	assert.True(t, c.IsObject())
+	assert.Equal(t, map[string]interface{}{"b": int64(1), "a": []interface{}{"x"}}, c.Value())
+	assert.Equal(t, `{"a":["x"],"b":1}`, c.AsString())
+	assert.True(t, c.AsBool())
+	assert.Equal(t, "{}", ObjectConst(nil).AsString())
+
+	require.Equal(t, 2, len(c.Members()))
+	assert.True(t, c.Members()["a"].IsArray())
+}
+
+func TestNewConstCollections(t *testing.T) {
+	c, err := NewConst([]interface{}{"a", []interface{}{1}})
+	require.Nil(t, err)
+	assert.Equal(t, `["a",[1]]`, c.AsString())
+
+	c, err = NewConst(map[string]interface{}{"a": 1})
+	require.Nil(t, err)
+	assert.Equal(t, `{"a":1}`, c.AsString())
+
+	c, err = NewConst([]*Const{IntConst(1)})
+	require.Nil(t, err)
+	assert.True(t, c.IsArray())
+
+	c, err = NewConst(map[string]*Const{"a": IntConst(1)})
+	require.Nil(t, err)
+	assert.True(t, c.IsObject())
+
+	_, err = NewConst([]interface{}{struct{}{}})
+	assert.NotNil(t, err)
+
+	_, err = NewConst(map[string]interface{}{"a": struct{}{}})
+	assert.NotNil(t, err)
+}
+
+func TestConstMarshalJSON(t *testing.T) {
+	for expected, c := range map[string]*Const{
+		`null`:                     NullConst(),
+		`42`:                       IntConst(42),
+		`1.5`:                      FloatConst(1.5),
+		`1e+21`:                    FloatConst(1e21),
+		`true`:                     BoolConst(true),
+		`"a \"b\""`:                StringConst(`a "b"`),
+		`99999999999999999999`:     bigInt("99999999999999999999"),
+		`0.10`:                     decimal("0.10"),
+		`"2016-12-28T10:00:00Z"`:   timestamp("2016-12-28T10:00:00Z"),
+		`"1h30m0s"`:                duration("90m"),
+		`[1,[2],{"a":null}]`:       array(1, array(2), object(map[string]interface{}{"a": nil})),
+		`{"a":"b","c":[1.5,null]}`: object(map[string]interface{}{"c": []interface{}{1.5, math.NaN()}, "a": "b"}),
+	} {
+		b, err := json.Marshal(c)
+		if assert.Nil(t, err, expected) {
+			assert.Equal(t, expected, string(b))
+		}
+	}
+}
+
+func TestLength(t *testing.T) {
+	for _, tc := range []struct {
+		value    *Const
+		expected int64
+	}{
+		{array(), 0},
+		{array(1, 2, 3), 3},
+		{object(map[string]interface{}{"a": 1, "b": 2}), 2},
+		{StringConst("héhé"), 4},
+	} {
+		c, err := length(tc.value)
+		if assert.Nil(t, err, tc.value.String()) {
+			assert.Equal(t, tc.expected, c.Value(), tc.value.String())
+		}
+	}
+
+	_, err := length(IntConst(42))
+	assert.NotNil(t, err)
+}
+
+func TestContains(t *testing.T) {
+	for _, tc := range []struct {
+		value, element *Const
+		expected       bool
+	}{
+		{array(1, 2, 3), IntConst(2), true},
+		{array(1, 2, 3), FloatConst(2), true},
+		{array(1, 2, 3), IntConst(4), false},
+		{array("42"), IntConst(42), true},
+		{array(array(1)), array(1), true},
+		// elements that can't be compared aren't equal
+		{array(duration("1h")), timestamp("2016-12-28T10:00:00Z"), false},
+		{object(map[string]interface{}{"a": 1}), StringConst("a"), true},
+		{object(map[string]interface{}{"a": 1}), IntConst(1), false},
+		{StringConst("foobar"), StringConst("oba"), true},
+		{StringConst("foobar"), StringConst("baz"), false},
+	} {
+		c, err := contains(tc.value, tc.element)
+		if assert.Nil(t, err, tc.value.String()) {
+			assert.Equal(t, tc.expected, c.AsBool(), "%s, %s", tc.value, tc.element)
+		}
+	}
+
+	_, err := contains(IntConst(42), IntConst(4))
+	assert.NotNil(t, err)
+}
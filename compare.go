@@ -0,0 +1,97 @@
+package charlatan
+
+import (
+	"math"
+	"time"
+)
+
+// the float64 values of the int64 bounds, both are exact. Any float greater
+// than or equal to the upper bound is greater than every int64.
+const (
+	minInt64AsFloat = -9223372036854775808.0
+	maxInt64AsFloat = 9223372036854775808.0
+)
+
+func cmpBools(b1, b2 bool) int {
+	if b1 == b2 {
+		return 0
+	}
+	if b1 {
+		return 1
+	}
+	return -1
+}
+
+func cmpStrings(s1, s2 string) int {
+	if s1 == s2 {
+		return 0
+	}
+	if s1 > s2 {
+		return 1
+	}
+	return -1
+}
+
+// cmpTimes compares two instants, whatever their timezones are
+func cmpTimes(t1, t2 time.Time) int {
+	switch {
+	case t1.Equal(t2):
+		return 0
+	case t1.After(t2):
+		return 1
+	}
+	return -1
+}
+
+func cmpInts(i1, i2 int64) int {
+	if i1 == i2 {
+		return 0
+	}
+	if i1 > i2 {
+		return 1
+	}
+	return -1
+}
+
+// cmpFloats compares two floats. NaN is equal to itself and lower than any
+// other value, including -Inf, so that all floats are ordered.
+func cmpFloats(f1, f2 float64) int {
+	nan1, nan2 := math.IsNaN(f1), math.IsNaN(f2)
+
+	switch {
+	case nan1 && nan2:
+		return 0
+	case nan1:
+		return -1
+	case nan2:
+		return 1
+	case f1 == f2:
+		return 0
+	case f1 > f2:
+		return 1
+	}
+	return -1
+}
+
+// cmpIntFloat compares an int to a float without converting the int to a
+// float, which would lose precision beyond 2^53.
+func cmpIntFloat(i int64, f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return 1
+	case f >= maxInt64AsFloat:
+		return -1
+	case f < minInt64AsFloat:
+		return 1
+	}
+
+	// f is in the int64 range, so is its integer part
+	t := math.Trunc(f)
+
+	if c := cmpInts(i, int64(t)); c != 0 {
+		return c
+	}
+
+	// same integer part, the fractional part decides
+	return cmpFloats(t, f)
+}
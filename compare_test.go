@@ -0,0 +1,303 @@
+package charlatan
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cmpTestCase struct {
+	c1, c2   *Const
+	expected int
+}
+
+func sign(i int) int {
+	switch {
+	case i > 0:
+		return 1
+	case i < 0:
+		return -1
+	}
+	return 0
+}
+
+var (
+	nan    = math.NaN()
+	posInf = math.Inf(1)
+	negInf = math.Inf(-1)
+)
+
+// cmpTestCases covers every pair of const types. Each case is also tested
+// the other way around.
+var cmpTestCases = []cmpTestCase{
+	// null, null
+	{NullConst(), NullConst(), 0},
+
+	// null, others: null is lower than everything
+	{NullConst(), IntConst(math.MinInt64), -1},
+	{NullConst(), FloatConst(negInf), -1},
+	{NullConst(), FloatConst(nan), -1},
+	{NullConst(), BoolConst(false), -1},
+	{NullConst(), StringConst(""), -1},
+	{NullConst(), bigInt("-1e30"), -1},
+	{NullConst(), decimal("-0.01"), -1},
+	{NullConst(), timestamp("0001-01-01"), -1},
+	{NullConst(), duration("-1h"), -1},
+	{NullConst(), array(), -1},
+	{NullConst(), object(nil), -1},
+
+	// int, int
+	{IntConst(42), IntConst(42), 0},
+	{IntConst(41), IntConst(42), -1},
+	{IntConst(-1), IntConst(1), -1},
+	{IntConst(math.MaxInt64), IntConst(-1), 1},
+	{IntConst(math.MinInt64), IntConst(1), -1},
+	{IntConst(math.MaxInt64), IntConst(math.MinInt64), 1},
+	{IntConst(math.MaxInt64), IntConst(math.MaxInt64 - 1), 1},
+
+	// float, float
+	{FloatConst(0.5), FloatConst(0.5), 0},
+	{FloatConst(0.3), FloatConst(0.5), -1},
+	{FloatConst(98882541.4), FloatConst(98882541.5), -1},
+	{FloatConst(-0.1), FloatConst(0.1), -1},
+	{FloatConst(math.Copysign(0, -1)), FloatConst(0), 0},
+	{FloatConst(1e308), FloatConst(-1e308), 1},
+	{FloatConst(posInf), FloatConst(posInf), 0},
+	{FloatConst(negInf), FloatConst(negInf), 0},
+	{FloatConst(posInf), FloatConst(math.MaxFloat64), 1},
+	{FloatConst(negInf), FloatConst(-math.MaxFloat64), -1},
+	{FloatConst(nan), FloatConst(nan), 0},
+	{FloatConst(nan), FloatConst(negInf), -1},
+	{FloatConst(nan), FloatConst(0), -1},
+
+	// int, float
+	{IntConst(42), FloatConst(42), 0},
+	{IntConst(0), FloatConst(0.3), -1},
+	{IntConst(0), FloatConst(-0.3), 1},
+	{IntConst(-3), FloatConst(-2.5), -1},
+	{IntConst(-2), FloatConst(-2.5), 1},
+	{IntConst(98882541), FloatConst(98882541.4), -1},
+	{IntConst(98882542), FloatConst(98882541.4), 1},
+	// 2^53 + 1 can't be represented as a float64
+	{IntConst(1<<53 + 1), FloatConst(1 << 53), 1},
+	{IntConst(1<<53 - 1), FloatConst(1 << 53), -1},
+	{IntConst(math.MaxInt64), FloatConst(1 << 63), -1},
+	{IntConst(math.MaxInt64), FloatConst(1<<63 - 1024), 1},
+	{IntConst(math.MinInt64), FloatConst(-(1 << 63)), 0},
+	{IntConst(math.MinInt64 + 1), FloatConst(-(1 << 63)), 1},
+	{IntConst(math.MinInt64), FloatConst(-1e19), 1},
+	{IntConst(math.MaxInt64), FloatConst(posInf), -1},
+	{IntConst(math.MinInt64), FloatConst(negInf), 1},
+	{IntConst(math.MinInt64), FloatConst(nan), 1},
+
+	// bool, bool
+	{BoolConst(true), BoolConst(true), 0},
+	{BoolConst(false), BoolConst(false), 0},
+	{BoolConst(false), BoolConst(true), -1},
+
+	// bool, others: the other one is converted to a bool
+	{BoolConst(true), IntConst(42), 0},
+	{BoolConst(false), IntConst(0), 0},
+	{BoolConst(false), IntConst(-1), -1},
+	{BoolConst(true), FloatConst(0.1), 0},
+	{BoolConst(true), FloatConst(0), 1},
+	{BoolConst(true), FloatConst(nan), 0},
+	{BoolConst(true), StringConst(""), 0},
+	{BoolConst(false), StringConst("false"), -1},
+
+	// string, string
+	{StringConst(""), StringConst(""), 0},
+	{StringConst("tutu"), StringConst("tutu"), 0},
+	{StringConst("tatu"), StringConst("tutu"), -1},
+	{StringConst(""), StringConst("a"), -1},
+	{StringConst("B"), StringConst("a"), -1},
+	{StringConst("été"), StringConst("ete"), 1},
+
+	// string, numerics: the number is converted to a string
+	{StringConst("42"), IntConst(42), 0},
+	{StringConst("9"), IntConst(10), 1},
+	{StringConst("0.50"), FloatConst(0.5), 0},
+	{StringConst("0.5"), FloatConst(0.5), -1},
+
+	// bigint, bigint
+	{bigInt("1e30"), bigInt("1e30"), 0},
+	{bigInt("-1e30"), bigInt("1e30"), -1},
+	{bigInt("1000000000000000000001"), bigInt("1e21"), 1},
+
+	// decimal, decimal: the scale doesn't matter
+	{decimal("0.10"), decimal("0.1"), 0},
+	{decimal("12345678901234567890.12"), decimal("12345678901234567890.13"), -1},
+	{decimal("-0.001"), decimal("0"), -1},
+
+	// bigint, others
+	{bigInt("1e19"), IntConst(math.MaxInt64), 1},
+	{bigInt("-1e19"), IntConst(math.MinInt64), -1},
+	{bigInt("1e19"), FloatConst(1e19), 0},
+	{bigInt("10000000000000000001"), FloatConst(1e19), 1},
+	{bigInt("1e300"), FloatConst(posInf), -1},
+	{bigInt("-1e300"), FloatConst(negInf), 1},
+	{bigInt("-1e300"), FloatConst(nan), 1},
+	{bigInt("1e19"), decimal("10000000000000000000.5"), -1},
+	{bigInt("1e19"), decimal("10000000000000000000.00"), 0},
+	{bigInt("1e19"), BoolConst(true), 0},
+	{bigInt("1e19"), StringConst("10000000000000000000"), 0},
+
+	// decimal, others
+	{decimal("42.00"), IntConst(42), 0},
+	{decimal("41.99"), IntConst(42), -1},
+	// 0.1 isn't exactly the float 0.1, but it's written as such
+	{decimal("0.1"), FloatConst(0.1), 0},
+	{decimal("0.30000000000000001"), FloatConst(0.3), 1},
+	{decimal("1e400"), FloatConst(posInf), -1},
+	{decimal("0"), FloatConst(nan), 1},
+	{decimal("0.00"), BoolConst(false), 0},
+	{decimal("1.50"), StringConst("1.50"), 0},
+	{decimal("1.5"), StringConst("1.50"), -1},
+
+	// timestamp, timestamp: the timezones don't matter
+	{timestamp("2016-12-28T10:00:00Z"), timestamp("2016-12-28T11:00:00+01:00"), 0},
+	{timestamp("2016-12-28T10:00:00Z"), timestamp("2016-12-28T10:00:00.001Z"), -1},
+	{timestamp("2017-01-01"), timestamp("2016-12-31T23:59:59Z"), 1},
+
+	// duration, duration
+	{duration("1h"), duration("60m"), 0},
+	{duration("-1s"), duration("1ms"), -1},
+
+	// timestamp, others: numbers are Unix times, strings are parsed
+	{timestamp("2016-12-28T10:00:00Z"), IntConst(1482919200), 0},
+	{timestamp("2016-12-28T10:00:00Z"), IntConst(1482919201), -1},
+	{timestamp("2016-12-28T10:00:00.5Z"), FloatConst(1482919200.5), 0},
+	{timestamp("2016-12-28T10:00:00Z"), FloatConst(nan), 1},
+	{timestamp("2016-12-28T10:00:00Z"), bigInt("1e20"), -1},
+	{timestamp("2016-12-28T10:00:00.25Z"), decimal("1482919200.25"), 0},
+	{timestamp("2016-12-28T10:00:00Z"), BoolConst(true), 0},
+	{timestamp("2016-12-28T10:00:00Z"), StringConst("2016-12-28 11:00:00+01:00"), 0},
+	{timestamp("2016-12-28T10:00:00Z"), StringConst("2016-12-29"), -1},
+
+	// duration, others: numbers are seconds, strings are parsed
+	{duration("1m"), IntConst(60), 0},
+	{duration("1.5s"), FloatConst(1.5), 0},
+	{duration("1s"), bigInt("1e30"), -1},
+	{duration("1ms"), decimal("0.001"), 0},
+	{duration("0s"), BoolConst(false), 0},
+	{duration("90m"), StringConst("1h30m"), 0},
+	{duration("1h"), StringConst("not a duration"), -1},
+
+	// array, array: element by element
+	{array(1, "a"), array(1, "a"), 0},
+	{array(1, 2), array(1, 3), -1},
+	{array(1, 2), array(1), 1},
+	{array(), array(NullConst()), -1},
+	{array(1.0), array(IntConst(1)), 0},
+
+	// object, object: member by member, sorted by key
+	{object(map[string]interface{}{"a": 1, "b": 2}), object(map[string]interface{}{"b": 2, "a": 1}), 0},
+	{object(map[string]interface{}{"a": 1}), object(map[string]interface{}{"b": 1}), -1},
+	{object(map[string]interface{}{"a": 2}), object(map[string]interface{}{"a": 1}), 1},
+	{object(nil), object(map[string]interface{}{"a": 1}), -1},
+
+	// array and object, others: they're true, and compared as JSON with
+	// strings
+	{array(), BoolConst(true), 0},
+	{array(1, "a"), StringConst(`[1,"a"]`), 0},
+	{object(nil), BoolConst(false), 1},
+	{object(map[string]interface{}{"a": 1}), StringConst(`{"a":1}`), 0},
+}
+
+// cmpErrorCases are the pairs of consts that can't be compared
+var cmpErrorCases = [][2]*Const{
+	{timestamp("2016-12-28T10:00:00Z"), duration("1h")},
+	{array(), object(nil)},
+	{array(timestamp("2016-12-28T10:00:00Z")), array(duration("1h"))},
+}
+
+func init() {
+	// arrays and objects can't be compared with numbers and times
+	for _, c := range []*Const{
+		IntConst(1), FloatConst(1), bigInt("1"), decimal("1"),
+		timestamp("2016-12-28T10:00:00Z"), duration("1h"),
+	} {
+		cmpErrorCases = append(cmpErrorCases, [2]*Const{array(1), c}, [2]*Const{object(nil), c})
+	}
+}
+
+func bigInt(s string) *Const {
+	r, _ := new(big.Rat).SetString(s)
+	return BigIntConst(r.Num())
+}
+
+func decimal(s string) *Const {
+	c, err := DecimalConstFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestConstCompareToMatrix(t *testing.T) {
+	for _, tc := range cmpTestCases {
+		msg := fmt.Sprintf("%s(%v) <=> %s(%v)",
+			tc.c1.constType, tc.c1.Value(), tc.c2.constType, tc.c2.Value())
+
+		r, err := tc.c1.CompareTo(tc.c2)
+		if assert.Nil(t, err, msg) {
+			assert.Equal(t, tc.expected, sign(r), msg)
+		}
+
+		r, err = tc.c2.CompareTo(tc.c1)
+		if assert.Nil(t, err, msg) {
+			assert.Equal(t, -tc.expected, sign(r), "reversed: "+msg)
+		}
+	}
+}
+
+func TestConstCompareToErrors(t *testing.T) {
+	for _, tc := range cmpErrorCases {
+		_, err := tc[0].CompareTo(tc[1])
+		assert.NotNil(t, err)
+
+		_, err = tc[1].CompareTo(tc[0])
+		assert.NotNil(t, err)
+	}
+}
+
+func TestConstCompareToCoversAllTypes(t *testing.T) {
+	types := []constType{
+		constNull, constInt, constFloat, constBool, constString, constBigInt,
+		constDecimal, constTimestamp, constDuration, constArray, constObject,
+	}
+	pairs := make(map[[2]constType]bool)
+
+	for _, tc := range cmpTestCases {
+		pairs[[2]constType{tc.c1.constType, tc.c2.constType}] = true
+		pairs[[2]constType{tc.c2.constType, tc.c1.constType}] = true
+	}
+
+	for _, tc := range cmpErrorCases {
+		pairs[[2]constType{tc[0].constType, tc[1].constType}] = true
+		pairs[[2]constType{tc[1].constType, tc[0].constType}] = true
+	}
+
+	for _, t1 := range types {
+		for _, t2 := range types {
+			assert.True(t, pairs[[2]constType{t1, t2}], "%s <=> %s isn't tested", t1, t2)
+		}
+	}
+}
+
+func TestCmpIntFloat(t *testing.T) {
+	for _, i := range []int64{math.MinInt64, -1 << 53, -1, 0, 1, 1 << 53, math.MaxInt64} {
+		for _, f := range []float64{-1e300, -1 << 53, -1.5, -0.5, 0, 0.5, 1.5, 1 << 53, 1e300} {
+			expected := 0
+			if float64(i) < f {
+				expected = -1
+			} else if float64(i) > f {
+				expected = 1
+			}
+			assert.Equal(t, expected, cmpIntFloat(i, f), "%d <=> %f", i, f)
+		}
+	}
+}
@@ -1,6 +1,23 @@
 package charlatan
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// the quantifiers of the quantified comparisons, e.g. x = ANY(values)
+const (
+	anyQuantifier = "ANY"
+	allQuantifier = "ALL"
+)
+
+// quantifiedOperand is the right operand of a quantified comparison, e.g.
+// ANY(tags) in "x = ANY(tags)". The left operand is compared with each
+// element of the array the operand evaluates to.
+type quantifiedOperand struct {
+	quantifier string
+	operand    operand
+}
 
 // newComparison creates a new comparison from the given operands
 func newComparison(left operand, operator operatorType, right operand) (*comparison, error) {
@@ -31,11 +48,58 @@ func (c *comparison) Evaluate(record Record) (*Const, error) {
 		return nil, err
 	}
 
+	if q, ok := c.right.(*quantifiedOperand); ok {
+		return c.evaluateQuantified(leftValue, q, record)
+	}
+
 	rightValue, err = c.right.Evaluate(record)
 	if err != nil {
 		return nil, err
 	}
 
+	return c.compare(leftValue, rightValue)
+}
+
+// evaluateQuantified compares the left value with each element of the
+// quantified operand. ANY is true if one of the comparisons is, ALL if all of
+// them are. If the operand isn't an array it's compared as is, and if it's
+// null both are false.
+func (c *comparison) evaluateQuantified(leftValue *Const, q *quantifiedOperand, record Record) (*Const, error) {
+	values, err := q.operand.Evaluate(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []*Const
+
+	switch {
+	case values.IsNull():
+		return BoolConst(false), nil
+	case values.IsArray():
+		elements = values.Elements()
+	default:
+		elements = []*Const{values}
+	}
+
+	all := q.quantifier == allQuantifier
+
+	for _, element := range elements {
+		r, err := c.compare(leftValue, element)
+		if err != nil {
+			return nil, err
+		}
+
+		// stop at the first comparison which decides the result
+		if r.AsBool() != all {
+			return BoolConst(!all), nil
+		}
+	}
+
+	return BoolConst(all), nil
+}
+
+// compare compares the two values with the operator of the comparison
+func (c *comparison) compare(leftValue, rightValue *Const) (*Const, error) {
 	r, err := leftValue.CompareTo(rightValue)
 	if err != nil {
 		return nil, err
@@ -62,3 +126,35 @@ func (c *comparison) Evaluate(record Record) (*Const, error) {
 func (c *comparison) String() string {
 	return fmt.Sprintf("%s %s %s", c.left, c.operator, c.right)
 }
+
+// newQuantifiedOperand creates a new quantified operand with the given
+// quantifier, which is case-insensitive
+func newQuantifiedOperand(quantifier string, args []operand) (*quantifiedOperand, error) {
+	quantifier = strings.ToUpper(quantifier)
+
+	if !isQuantifier(quantifier) {
+		return nil, fmt.Errorf("Unknown quantifier %s", quantifier)
+	}
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s expects 1 argument, got %d", quantifier, len(args))
+	}
+
+	return &quantifiedOperand{quantifier: quantifier, operand: args[0]}, nil
+}
+
+// isQuantifier tests if the name is the one of a quantifier
+func isQuantifier(name string) bool {
+	name = strings.ToUpper(name)
+	return name == anyQuantifier || name == allQuantifier
+}
+
+// Evaluate always fails, a quantified operand is evaluated by the comparison
+// it's the right operand of
+func (q *quantifiedOperand) Evaluate(Record) (*Const, error) {
+	return nil, fmt.Errorf("%s can only be the right operand of a comparison", q.quantifier)
+}
+
+func (q *quantifiedOperand) String() string {
+	return fmt.Sprintf("%s(%s)", q.quantifier, q.operand)
+}
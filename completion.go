@@ -0,0 +1,440 @@
+package charlatan
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TokenKind is the kind of a token, as exposed to editors for syntax
+// highlighting and completion
+type TokenKind int
+
+// token kinds group the token types by the way they should be displayed
+const (
+	KindInvalid TokenKind = iota
+	KindKeyword
+	KindOperator
+	KindField
+	KindString
+	KindNumber
+	KindLiteral
+	KindPunctuation
+	KindParameter
+	KindComment
+)
+
+// Span is a token found in a query string, with its position. Positions are
+// character (rune) offsets, starting at 0. End is excluded.
+type Span struct {
+	Kind  TokenKind
+	Text  string
+	Start int
+	End   int
+}
+
+// Suggestion is a token that can be typed at a given position
+type Suggestion struct {
+	Kind TokenKind
+	Text string
+}
+
+// Completion holds what can be typed at a given position in a query string
+type Completion struct {
+	// the kinds of tokens which are valid at this position
+	Kinds []TokenKind
+	// the partial word right before the position, if any
+	Prefix string
+	// the keywords, operators and fields that match the prefix
+	Suggestions []Suggestion
+}
+
+// Highlight splits the given query string into spans. It never fails: parts
+// of the string the lexer can't read are returned as KindInvalid spans.
+func Highlight(s string) []Span {
+	runes := []rune(s)
+	spans := make([]Span, 0)
+
+	l := lexerFromString(s)
+	l.keepComments = true
+
+	for {
+		start := l.index
+
+		tok, err := l.NextToken()
+		if err != nil {
+			// the lexer skipped the invalid input, mark it as such
+			for start < l.index && unicode.IsSpace(runes[start]) {
+				start++
+			}
+
+			if start >= l.index {
+				break
+			}
+
+			spans = append(spans, Span{
+				Kind:  KindInvalid,
+				Text:  string(runes[start:l.index]),
+				Start: start,
+				End:   l.index,
+			})
+			continue
+		}
+
+		if tok.isEnd() {
+			break
+		}
+
+		spans = append(spans, Span{
+			Kind:  tok.Type.kind(),
+			Text:  string(runes[tok.Pos-1 : tok.End]),
+			Start: tok.Pos - 1,
+			End:   tok.End,
+		})
+	}
+
+	return spans
+}
+
+// Complete returns what can be typed at the given position (a character
+// offset) of a partial query string. The fields are the ones the source is
+// known to have; they're suggested where a field is expected.
+func Complete(s string, cursor int, fields []string) *Completion {
+	runes := []rune(s)
+
+	if cursor < 0 {
+		cursor = 0
+	}
+	if cursor > len(runes) {
+		cursor = len(runes)
+	}
+
+	c := &Completion{
+		Kinds:       make([]TokenKind, 0),
+		Suggestions: make([]Suggestion, 0),
+	}
+
+	toks := make([]*token, 0)
+	l := lexerFromString(string(runes[:cursor]))
+
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			// we're e.g. in the middle of a string
+			if l.index >= cursor {
+				return c
+			}
+			continue
+		}
+
+		if tok.isEnd() {
+			break
+		}
+
+		toks = append(toks, tok)
+	}
+
+	// we're at the end of a line comment
+	if n := len(l.comments); n > 0 && l.comments[n-1].End == cursor &&
+		!strings.HasPrefix(l.comments[n-1].Value, "/*") {
+		return c
+	}
+
+	// the last word is being typed
+	if n := len(toks); n > 0 && toks[n-1].End == cursor {
+		if w := string(runes[toks[n-1].Pos-1 : cursor]); isWord(w) {
+			c.Prefix = w
+			toks = toks[:n-1]
+		}
+	}
+
+	p := parserFromString("")
+	p.tolerant = true
+
+	for _, tok := range toks {
+		p.step(tok)
+	}
+
+	seen := make(map[TokenKind]bool)
+
+	for _, ty := range p.expectedTokens() {
+		kind := ty.kind()
+
+		if !seen[kind] {
+			seen[kind] = true
+			c.Kinds = append(c.Kinds, kind)
+		}
+
+		switch ty {
+		case tokField:
+			// the FROM part isn't a field
+			if p.state == fromInitial {
+				continue
+			}
+			for _, f := range fields {
+				c.suggest(KindField, f)
+			}
+			// function calls and literals are only in the WHERE clause
+			if isExpressionState(p.state) {
+				// the quantifiers are only after a comparison operator
+				for _, name := range valueKeywords(p.state == operator) {
+					c.suggest(KindKeyword, name)
+				}
+			}
+		case tokInt, tokFloat, tokString, tokParameter:
+			// we can't guess those
+		default:
+			c.suggest(kind, ty.text())
+		}
+	}
+
+	return c
+}
+
+// suggest adds a suggestion if it matches the prefix
+func (c *Completion) suggest(kind TokenKind, text string) {
+	if strings.HasPrefix(strings.ToUpper(text), strings.ToUpper(c.Prefix)) {
+		c.Suggestions = append(c.Suggestions, Suggestion{Kind: kind, Text: text})
+	}
+}
+
+// valueKeywords returns the names of the functions and the types of the
+// literals, and the quantifiers if asked to, sorted
+func valueKeywords(quantifiers bool) []string {
+	names := []string{timestampLiteral, intervalLiteral}
+	if quantifiers {
+		names = append(names, anyQuantifier, allQuantifier)
+	}
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expectedTokens returns the types of the tokens the parser can accept in its
+// current state
+func (p *parser) expectedTokens() []tokenType {
+	return p.expectedTokensIn(p.state)
+}
+
+// expectedTokensIn returns the types of the tokens the parser can accept in
+// the given state
+func (p *parser) expectedTokensIn(s state) []tokenType {
+	values := []tokenType{
+		tokField, tokInt, tokFloat, tokString, tokTrue, tokFalse, tokNull,
+		tokParameter,
+	}
+	clauses := []tokenType{tokWhere, tokStarting, tokLimit}
+
+	var closing []tokenType
+	if len(p.stack) > 0 {
+		closing = []tokenType{tokRightParenthesis}
+	}
+
+	switch s {
+	case initial:
+		return []tokenType{tokSelect}
+	case selectInitial:
+		return []tokenType{tokField}
+	case selectField:
+		return []tokenType{tokComma, tokFrom}
+	case fromInitial:
+		return []tokenType{tokField}
+	case clauseEnd:
+		return clauses
+	case operationInitial, operator:
+		return append(values, tokLeftParenthesis)
+	case leftOperand:
+		types := []tokenType{
+			tokEq, tokNeq, tokLt, tokLte, tokGt, tokGte, tokBetween, tokAnd, tokOr,
+		}
+		types = append(types, closing...)
+		return append(types, tokStarting, tokLimit)
+	case rightOperand:
+		types := append([]tokenType{tokAnd, tokOr}, closing...)
+		return append(types, tokStarting, tokLimit)
+	case rangeMin, rangeMax:
+		return values
+	case rangeAnd:
+		return []tokenType{tokAnd}
+	case startingInitial:
+		return []tokenType{tokAt}
+	case startingAt, limitInitial, limitMax:
+		return []tokenType{tokInt}
+	case limitSep:
+		return append([]tokenType{tokComma}, clauses...)
+	case recovering:
+		types := append(clauses, tokLeftParenthesis)
+		return append(types, closing...)
+	case value:
+		return p.expectedValueTokens()
+	}
+
+	return nil
+}
+
+// expectedValueTokens returns the types of the tokens the value parser can
+// accept in its current state
+func (p *parser) expectedValueTokens() []tokenType {
+	v := p.value
+	frame := v.frame()
+
+	if v.state == valueTerm {
+		types := []tokenType{
+			tokField, tokInt, tokFloat, tokString, tokTrue, tokFalse, tokNull,
+			tokParameter,
+		}
+		if frame.function != "" && len(frame.args) == 0 && frame.expr == nil {
+			types = append(types, tokRightParenthesis)
+		}
+		return types
+	}
+
+	var types []tokenType
+
+	if v.state == valueWord {
+		if isFunction(v.word.Value) {
+			types = append(types, tokLeftParenthesis)
+		}
+		switch strings.ToUpper(v.word.Value) {
+		case timestampLiteral, intervalLiteral:
+			types = append(types, tokString)
+		}
+	}
+
+	types = append(types, tokPlus, tokMinus)
+
+	if frame.function == "" {
+		return append(types, p.expectedTokensIn(v.next)...)
+	}
+
+	types = append(types, tokComma)
+	if frame.function == "EXTRACT" && len(frame.args) == 0 {
+		types = append(types, tokFrom)
+	}
+
+	return append(types, tokRightParenthesis)
+}
+
+// kind returns the kind of the token type
+func (t tokenType) kind() TokenKind {
+	tok := token{Type: t}
+
+	switch {
+	case tok.isKeyword():
+		return KindKeyword
+	case tok.isOperator():
+		return KindOperator
+	case tok.isNumeric():
+		return KindNumber
+	}
+
+	switch t {
+	case tokField:
+		return KindField
+	case tokString:
+		return KindString
+	case tokTrue, tokFalse, tokNull:
+		return KindLiteral
+	case tokLeftParenthesis, tokRightParenthesis, tokComma:
+		return KindPunctuation
+	case tokParameter:
+		return KindParameter
+	case tokComment:
+		return KindComment
+	}
+
+	return KindInvalid
+}
+
+// text returns the canonical text of the token type, if it has one
+func (t tokenType) text() string {
+	switch t {
+	case tokSelect:
+		return "SELECT"
+	case tokFrom:
+		return "FROM"
+	case tokWhere:
+		return "WHERE"
+	case tokStarting:
+		return "STARTING"
+	case tokAt:
+		return "AT"
+	case tokBetween:
+		return "BETWEEN"
+	case tokLimit:
+		return "LIMIT"
+	case tokAnd:
+		return "AND"
+	case tokOr:
+		return "OR"
+	case tokEq:
+		return "="
+	case tokNeq:
+		return "!="
+	case tokLt:
+		return "<"
+	case tokLte:
+		return "<="
+	case tokGt:
+		return ">"
+	case tokGte:
+		return ">="
+	case tokPlus:
+		return "+"
+	case tokMinus:
+		return "-"
+	case tokTrue:
+		return "true"
+	case tokFalse:
+		return "false"
+	case tokNull:
+		return "null"
+	case tokLeftParenthesis:
+		return "("
+	case tokRightParenthesis:
+		return ")"
+	case tokComma:
+		return ","
+	}
+
+	return ""
+}
+
+// isWord tests if the string only contains word characters
+func isWord(s string) bool {
+	for _, r := range s {
+		if !isWordRune(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func (k TokenKind) String() string {
+	switch k {
+	case KindInvalid:
+		return "Invalid"
+	case KindKeyword:
+		return "Keyword"
+	case KindOperator:
+		return "Operator"
+	case KindField:
+		return "Field"
+	case KindString:
+		return "String"
+	case KindNumber:
+		return "Number"
+	case KindLiteral:
+		return "Literal"
+	case KindPunctuation:
+		return "Punctuation"
+	case KindParameter:
+		return "Parameter"
+	case KindComment:
+		return "Comment"
+	}
+
+	return "UNKNOWN"
+}
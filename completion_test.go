@@ -0,0 +1,180 @@
+package charlatan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func suggestionsTexts(c *Completion) []string {
+	texts := make([]string, len(c.Suggestions))
+	for i, s := range c.Suggestions {
+		texts[i] = s.Text
+	}
+	return texts
+}
+
+func TestHighlight(t *testing.T) {
+	spans := Highlight(`SELECT a, b FROM c WHERE (a >= 2.5) && b = "x y" LIMIT 3`)
+	require.Equal(t, 18, len(spans))
+
+	assert.Equal(t, Span{Kind: KindKeyword, Text: "SELECT", Start: 0, End: 6}, spans[0])
+	assert.Equal(t, Span{Kind: KindField, Text: "a", Start: 7, End: 8}, spans[1])
+	assert.Equal(t, Span{Kind: KindPunctuation, Text: ",", Start: 8, End: 9}, spans[2])
+	assert.Equal(t, Span{Kind: KindOperator, Text: ">=", Start: 28, End: 30}, spans[9])
+	assert.Equal(t, Span{Kind: KindNumber, Text: "2.5", Start: 31, End: 34}, spans[10])
+	assert.Equal(t, Span{Kind: KindOperator, Text: "&&", Start: 36, End: 38}, spans[12])
+	assert.Equal(t, Span{Kind: KindString, Text: `"x y"`, Start: 43, End: 48}, spans[15])
+	assert.Equal(t, Span{Kind: KindNumber, Text: "3", Start: 55, End: 56}, spans[17])
+}
+
+func TestHighlightInvalidInput(t *testing.T) {
+	spans := Highlight(`SELECT a FROM b WHERE a =! 2 [ 'foo`)
+	require.Equal(t, 10, len(spans))
+
+	assert.Equal(t, Span{Kind: KindInvalid, Text: "=!", Start: 24, End: 26}, spans[6])
+	assert.Equal(t, Span{Kind: KindNumber, Text: "2", Start: 27, End: 28}, spans[7])
+	assert.Equal(t, Span{Kind: KindInvalid, Text: "[", Start: 29, End: 30}, spans[8])
+	assert.Equal(t, Span{Kind: KindInvalid, Text: "'foo", Start: 31, End: 35}, spans[9])
+}
+
+func TestHighlightUnicode(t *testing.T) {
+	spans := Highlight(`SELECT é FROM ç`)
+	require.Equal(t, 4, len(spans))
+	assert.Equal(t, Span{Kind: KindField, Text: "ç", Start: 14, End: 15}, spans[3])
+}
+
+func TestCompleteEmpty(t *testing.T) {
+	c := Complete("", 0, nil)
+	assert.Equal(t, []TokenKind{KindKeyword}, c.Kinds)
+	assert.Equal(t, []string{"SELECT"}, suggestionsTexts(c))
+}
+
+func TestCompleteFields(t *testing.T) {
+	schema := []string{"name", "age", "stats.walking"}
+
+	c := Complete("SELECT ", 7, schema)
+	assert.Equal(t, []TokenKind{KindField}, c.Kinds)
+	assert.Equal(t, schema, suggestionsTexts(c))
+
+	c = Complete("SELECT na", 9, schema)
+	assert.Equal(t, "na", c.Prefix)
+	assert.Equal(t, []string{"name"}, suggestionsTexts(c))
+
+	c = Complete("SELECT name FROM x WHERE st", 27, schema)
+	assert.Equal(t, []string{"stats.walking", "STRFTIME"}, suggestionsTexts(c))
+}
+
+func TestCompleteNoFieldsInFrom(t *testing.T) {
+	c := Complete("SELECT name FROM ", 17, []string{"name"})
+	assert.Equal(t, []TokenKind{KindField}, c.Kinds)
+	assert.Empty(t, c.Suggestions)
+}
+
+func TestCompleteKeywords(t *testing.T) {
+	c := Complete("SELECT a, b ", 12, nil)
+	assert.Equal(t, []string{",", "FROM"}, suggestionsTexts(c))
+
+	c = Complete("SELECT a FROM b wh", 18, nil)
+	assert.Equal(t, "wh", c.Prefix)
+	assert.Equal(t, []string{"WHERE"}, suggestionsTexts(c))
+
+	c = Complete("SELECT a FROM b WHERE x BETWEEN 1 ", 34, nil)
+	assert.Equal(t, []string{"+", "-", "AND"}, suggestionsTexts(c))
+}
+
+func TestCompleteOperators(t *testing.T) {
+	c := Complete("SELECT a FROM b WHERE x ", 24, nil)
+	assert.Contains(t, c.Kinds, KindOperator)
+	assert.Contains(t, c.Kinds, KindKeyword)
+	assert.Contains(t, suggestionsTexts(c), "!=")
+	assert.Contains(t, suggestionsTexts(c), "BETWEEN")
+	assert.NotContains(t, suggestionsTexts(c), ")")
+
+	c = Complete("SELECT a FROM b WHERE (x = 2 ", 29, nil)
+	assert.Equal(t, []string{"+", "-", "AND", "OR", ")", "STARTING", "LIMIT"}, suggestionsTexts(c))
+}
+
+func TestCompleteInTheMiddle(t *testing.T) {
+	c := Complete("SELECT a FROM b WHERE x = 2", 22, []string{"x", "y"})
+	assert.Equal(t, []string{
+		"x", "y", "CONTAINS", "DATE_TRUNC", "EXTRACT", "INTERVAL", "LENGTH",
+		"NOW", "STRFTIME", "TIMESTAMP", "TIMEZONE", "true", "false", "null", "(",
+	}, suggestionsTexts(c))
+}
+
+func TestCompleteFunctions(t *testing.T) {
+	c := Complete("SELECT a FROM b WHERE DATE_TRUNC", 32, nil)
+	assert.Equal(t, []string{"DATE_TRUNC"}, suggestionsTexts(c))
+
+	c = Complete("SELECT a FROM b WHERE DATE_TRUNC ", 33, nil)
+	assert.Equal(t, "(", suggestionsTexts(c)[0])
+
+	c = Complete("SELECT a FROM b WHERE NOW(", 26, nil)
+	assert.Contains(t, suggestionsTexts(c), ")")
+
+	c = Complete("SELECT a FROM b WHERE EXTRACT(hour ", 35, nil)
+	assert.Equal(t, []string{"+", "-", ",", "FROM", ")"}, suggestionsTexts(c))
+
+	c = Complete("SELECT a FROM b WHERE STRFTIME('%Y', ts) ", 41, nil)
+	assert.Contains(t, suggestionsTexts(c), "=")
+	assert.Contains(t, suggestionsTexts(c), "+")
+
+	c = Complete("SELECT a FROM b WHERE ts > TIMESTAMP ", 37, nil)
+	assert.Contains(t, c.Kinds, KindString)
+}
+
+func TestCompleteQuantifiers(t *testing.T) {
+	c := Complete("SELECT a FROM b WHERE x = A", 27, nil)
+	assert.Equal(t, []string{"ALL", "ANY"}, suggestionsTexts(c))
+
+	// not on the left of a comparison
+	c = Complete("SELECT a FROM b WHERE A", 23, nil)
+	assert.Empty(t, suggestionsTexts(c))
+}
+
+func TestCompleteInString(t *testing.T) {
+	c := Complete("SELECT a FROM b WHERE x = 'fo", 29, []string{"x"})
+	assert.Empty(t, c.Kinds)
+	assert.Empty(t, c.Suggestions)
+}
+
+func TestCompleteAfterError(t *testing.T) {
+	c := Complete("SELECT a FROM b WHERE x = = 2 ", 30, nil)
+	assert.Equal(t, []string{"WHERE", "STARTING", "LIMIT", "("}, suggestionsTexts(c))
+}
+
+func TestTokenKindString(t *testing.T) {
+	for _, k := range []TokenKind{
+		KindInvalid, KindKeyword, KindOperator, KindField, KindString,
+		KindNumber, KindLiteral, KindPunctuation, KindParameter, KindComment,
+	} {
+		assert.NotEqual(t, "UNKNOWN", k.String())
+	}
+}
+
+func TestHighlightComments(t *testing.T) {
+	spans := Highlight("SELECT a /* b */ FROM c -- d")
+	require.Equal(t, 6, len(spans))
+	assert.Equal(t, Span{Kind: KindComment, Text: "/* b */", Start: 9, End: 16}, spans[2])
+	assert.Equal(t, Span{Kind: KindComment, Text: "-- d", Start: 24, End: 28}, spans[5])
+
+	spans = Highlight("SELECT a /* b")
+	require.Equal(t, 3, len(spans))
+	assert.Equal(t, Span{Kind: KindInvalid, Text: "/* b", Start: 9, End: 13}, spans[2])
+}
+
+func TestCompleteInComment(t *testing.T) {
+	c := Complete("SELECT a -- b", 13, []string{"a"})
+	assert.Empty(t, c.Suggestions)
+
+	c = Complete("SELECT a /* b", 13, []string{"a"})
+	assert.Empty(t, c.Suggestions)
+
+	c = Complete("SELECT a /* b */ ", 17, []string{"a"})
+	assert.Equal(t, []string{",", "FROM"}, suggestionsTexts(c))
+
+	c = Complete("SELECT a -- b\n", 14, []string{"a"})
+	assert.Equal(t, []string{",", "FROM"}, suggestionsTexts(c))
+}
@@ -1,21 +1,35 @@
 package charlatan
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // constType represents the constant types
 type constType int
 
-// types are either null, int, float, bool or string
+// types are either null, int, float, bool, string, one of the arbitrary
+// precision types: bigint and decimal, one of the time types: timestamp and
+// duration, or one of the collection types: array and object
 const (
 	constNull constType = iota
 	constInt
 	constFloat
 	constBool
 	constString
+	constBigInt
+	constDecimal
+	constTimestamp
+	constDuration
+	constArray
+	constObject
 )
 
 // Const represents a Constant
@@ -27,6 +41,18 @@ type Const struct {
 	floatValue  float64
 	boolValue   bool
 	stringValue string
+	// bigints and decimals, which are never modified once created. The scale
+	// is the number of digits after the decimal point a decimal is written
+	// with.
+	bigIntValue  *big.Int
+	decimalValue *big.Rat
+	scale        int
+	// timestamps and durations
+	timeValue     time.Time
+	durationValue time.Duration
+	// arrays and objects, which hold nested constants
+	arrayValue  []*Const
+	objectValue map[string]*Const
 }
 
 // NewConst creates a new constant whatever the type is
@@ -72,6 +98,28 @@ func NewConst(value interface{}) (*Const, error) {
 		return BoolConst(*value), nil
 	case *string:
 		return StringConst(*value), nil
+	case *big.Int:
+		return BigIntConst(value), nil
+	case *big.Rat:
+		return DecimalConst(value, decimalScale(value)), nil
+	case time.Time:
+		return TimestampConst(value), nil
+	case time.Duration:
+		return DurationConst(value), nil
+	case *time.Time:
+		return TimestampConst(*value), nil
+	case *time.Duration:
+		return DurationConst(*value), nil
+	case []interface{}:
+		return arrayFromValues(value)
+	case map[string]interface{}:
+		return objectFromValues(value)
+	case []*Const:
+		return ArrayConst(value), nil
+	case map[string]*Const:
+		return ObjectConst(value), nil
+	case *Const:
+		return value, nil
 	default:
 		return nil, fmt.Errorf("unexpected constant type %T", value)
 	}
@@ -102,11 +150,16 @@ func StringConst(value string) *Const {
 	return &Const{stringValue: value, constType: constString}
 }
 
-// ConstFromString parses a Const from a string
+// ConstFromString parses a Const from a string. Integers that don't fit in
+// an int64 are parsed as bigints.
 func ConstFromString(s string) *Const {
 
 	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
 		return IntConst(i)
+	} else if isRangeError(err) {
+		if b, ok := new(big.Int).SetString(s, 10); ok {
+			return BigIntConst(b)
+		}
 	}
 
 	if f, err := strconv.ParseFloat(s, 64); err == nil {
@@ -126,6 +179,38 @@ func (c Const) Evaluate(r Record) (*Const, error) {
 	return &c, nil
 }
 
+// quoteString returns the string as a double-quoted string literal, escaping
+// what needs to be
+func quoteString(s string) string {
+	var buf bytes.Buffer
+
+	buf.WriteByte('"')
+
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\n':
+			buf.WriteString("\\n")
+		case '\t':
+			buf.WriteString("\\t")
+		case '\r':
+			buf.WriteString("\\r")
+		default:
+			if unicode.IsControl(r) {
+				fmt.Fprintf(&buf, "\\u%04x", r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+
+	buf.WriteByte('"')
+
+	return buf.String()
+}
+
 func parseBool(s string) (bool, error) {
 	switch strings.ToUpper(s) {
 	case "TRUE":
@@ -137,9 +222,14 @@ func parseBool(s string) (bool, error) {
 	}
 }
 
-// IsNumeric tests if a const has a numeric type (int or float)
+// IsNumeric tests if a const has a numeric type (int, float, bigint or
+// decimal)
 func (c Const) IsNumeric() bool {
-	return c.constType == constInt || c.constType == constFloat
+	switch c.constType {
+	case constInt, constFloat, constBigInt, constDecimal:
+		return true
+	}
+	return false
 }
 
 // IsBool tests if a const is a bool
@@ -168,6 +258,26 @@ func (c Const) Value() interface{} {
 		return c.boolValue
 	case constString:
 		return c.stringValue
+	case constBigInt:
+		return c.bigIntValue
+	case constDecimal:
+		return c.decimalValue
+	case constTimestamp:
+		return c.timeValue
+	case constDuration:
+		return c.durationValue
+	case constArray:
+		values := make([]interface{}, len(c.arrayValue))
+		for i, element := range c.arrayValue {
+			values[i] = element.Value()
+		}
+		return values
+	case constObject:
+		values := make(map[string]interface{}, len(c.objectValue))
+		for key, member := range c.objectValue {
+			values[key] = member.Value()
+		}
+		return values
 	}
 	return nil
 }
@@ -175,14 +285,18 @@ func (c Const) Value() interface{} {
 func (c Const) String() string {
 	switch c.constType {
 	case constString:
-		return "\"" + c.stringValue + "\""
+		return quoteString(c.stringValue)
+	case constTimestamp:
+		return "TIMESTAMP " + quoteString(c.AsString())
+	case constDuration:
+		return "INTERVAL " + quoteString(c.AsString())
 	default:
 		return c.AsString()
 	}
 }
 
 // AsFloat converts into a float64
-// Returns 0 if the const is a string or null
+// Returns 0 if the const is a string, null, an array or an object
 func (c Const) AsFloat() float64 {
 	switch c.constType {
 	case constInt:
@@ -194,12 +308,24 @@ func (c Const) AsFloat() float64 {
 			return 1.0
 		}
 		return 0.0
+	case constBigInt:
+		f, _ := new(big.Float).SetInt(c.bigIntValue).Float64()
+		return f
+	case constDecimal:
+		f, _ := c.decimalValue.Float64()
+		return f
+	case constTimestamp:
+		return float64(c.timeValue.UnixNano()) / float64(time.Second)
+	case constDuration:
+		return c.durationValue.Seconds()
 	}
 	return 0
 }
 
 // AsInt converts into an int64
-// Returns 0 if the const is a string or null
+// Returns 0 if the const is a string, null, an array or an object. Bigints
+// and decimals out of the int64 range are clamped to it. Timestamps are
+// converted to Unix times and durations to seconds.
 func (c Const) AsInt() int64 {
 	switch c.constType {
 	case constInt:
@@ -211,6 +337,15 @@ func (c Const) AsInt() int64 {
 			return 1
 		}
 		return 0
+	case constBigInt:
+		return clampBigInt(c.bigIntValue)
+	case constDecimal:
+		// Quo truncates towards zero, like the float conversion
+		return clampBigInt(new(big.Int).Quo(c.decimalValue.Num(), c.decimalValue.Denom()))
+	case constTimestamp:
+		return c.timeValue.Unix()
+	case constDuration:
+		return int64(c.durationValue / time.Second)
 	}
 	return 0
 }
@@ -220,6 +355,9 @@ func (c Const) AsInt() int64 {
 //     - for null, returns false
 //     - for numeric, returns true if not 0
 //     - for strings, return true (test existence)
+//     - for timestamps, returns true if not the zero time
+//     - for durations, returns true if not 0
+//     - for arrays and objects, returns true (test existence)
 func (c Const) AsBool() bool {
 	switch c.constType {
 	case constNull:
@@ -232,11 +370,22 @@ func (c Const) AsBool() bool {
 		return c.boolValue
 	case constString:
 		return true
+	case constBigInt:
+		return c.bigIntValue.Sign() != 0
+	case constDecimal:
+		return c.decimalValue.Sign() != 0
+	case constTimestamp:
+		return !c.timeValue.IsZero()
+	case constDuration:
+		return c.durationValue != 0
+	case constArray, constObject:
+		return true
 	}
 	return false
 }
 
-// AsString converts into a string
+// AsString converts into a string. Arrays and objects are serialized in
+// JSON.
 func (c Const) AsString() string {
 	switch c.constType {
 	case constNull:
@@ -249,12 +398,94 @@ func (c Const) AsString() string {
 		return strconv.FormatBool(c.boolValue)
 	case constString:
 		return c.stringValue
+	case constBigInt:
+		return c.bigIntValue.String()
+	case constDecimal:
+		return c.decimalValue.FloatString(c.scale)
+	case constTimestamp:
+		return c.timeValue.Format(time.RFC3339Nano)
+	case constDuration:
+		return c.durationValue.String()
+	case constArray, constObject:
+		b, _ := c.MarshalJSON()
+		return string(b)
 	}
 
 	// fallback to sprintf .... should never append
 	return fmt.Sprintf("%v", c.Value())
 }
 
+// MarshalJSON implements the json.Marshaler interface. Bigints and decimals
+// are written as numbers, without losing precision, timestamps and durations
+// as strings, and floats which aren't numbers (NaN and infinities) as null.
+func (c Const) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := c.writeJSON(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeJSON writes the const in JSON
+func (c Const) writeJSON(buf *bytes.Buffer) error {
+	switch c.constType {
+	case constNull:
+		buf.WriteString("null")
+	case constFloat:
+		if math.IsNaN(c.floatValue) || math.IsInf(c.floatValue, 0) {
+			buf.WriteString("null")
+			return nil
+		}
+		b, err := json.Marshal(c.floatValue)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	case constString, constTimestamp, constDuration:
+		// don't escape HTML characters
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(c.AsString()); err != nil {
+			return err
+		}
+		// Encode adds a newline
+		buf.Truncate(buf.Len() - 1)
+	case constArray:
+		buf.WriteByte('[')
+		for i, element := range c.arrayValue {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := element.writeJSON(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case constObject:
+		buf.WriteByte('{')
+		for i, key := range sortedKeys(c.objectValue) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := StringConst(key).writeJSON(buf); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := c.objectValue[key].writeJSON(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		// ints, bools, bigints and decimals
+		buf.WriteString(c.AsString())
+	}
+
+	return nil
+}
+
 // CompareTo returns:
 //     - a positive integer if this Constant is greater than the given one
 //     - a negative integer if this Constant is lower than the given one
@@ -269,13 +500,25 @@ func (c Const) CompareTo(c2 *Const) (int, error) {
 		case constNull:
 			return 0, nil
 		case constInt:
-			return int(c.intValue - c2.intValue), nil
+			return cmpInts(c.intValue, c2.intValue), nil
 		case constFloat:
-			return int(c.floatValue - c2.floatValue), nil
+			return cmpFloats(c.floatValue, c2.floatValue), nil
 		case constBool:
 			return cmpBools(c.boolValue, c2.boolValue), nil
 		case constString:
 			return cmpStrings(c.stringValue, c2.stringValue), nil
+		case constBigInt:
+			return c.bigIntValue.Cmp(c2.bigIntValue), nil
+		case constDecimal:
+			return c.decimalValue.Cmp(c2.decimalValue), nil
+		case constTimestamp:
+			return cmpTimes(c.timeValue, c2.timeValue), nil
+		case constDuration:
+			return cmpInts(int64(c.durationValue), int64(c2.durationValue)), nil
+		case constArray:
+			return cmpArrays(c.arrayValue, c2.arrayValue)
+		case constObject:
+			return cmpObjects(c.objectValue, c2.objectValue)
 		default:
 			return 0, fmt.Errorf("Unknown const type: %v", c.constType)
 		}
@@ -288,8 +531,20 @@ func (c Const) CompareTo(c2 *Const) (int, error) {
 		return 1, nil
 	}
 	if c.IsNumeric() && c2.IsNumeric() {
-		return int(c.AsFloat() - c2.AsFloat()), nil
+		if c.isBig() || c2.isBig() {
+			return cmpBigNumerics(c, *c2), nil
+		}
 
+		// one is an int, the other is a float
+		if c.constType == constInt {
+			return cmpIntFloat(c.intValue, c2.floatValue), nil
+		}
+		return -cmpIntFloat(c2.intValue, c.floatValue), nil
+	}
+	if c.isTime() || c2.isTime() {
+		if r, ok := cmpTimeConsts(c, *c2); ok {
+			return r, nil
+		}
 	}
 	if c.IsBool() || c2.IsBool() {
 		return cmpBools(c.AsBool(), c2.AsBool()), nil
@@ -303,26 +558,6 @@ func (c Const) CompareTo(c2 *Const) (int, error) {
 		c.constType, c.Value(), c2.constType, c2.Value())
 }
 
-func cmpBools(b1, b2 bool) int {
-	if b1 == b2 {
-		return 0
-	}
-	if b1 {
-		return 1
-	}
-	return -1
-}
-
-func cmpStrings(s1, s2 string) int {
-	if s1 == s2 {
-		return 0
-	}
-	if s1 > s2 {
-		return 1
-	}
-	return -1
-}
-
 func (t constType) String() string {
 	switch t {
 	case constNull:
@@ -335,6 +570,18 @@ func (t constType) String() string {
 		return "BOOL"
 	case constString:
 		return "STRING"
+	case constBigInt:
+		return "BIGINT"
+	case constDecimal:
+		return "DECIMAL"
+	case constTimestamp:
+		return "TIMESTAMP"
+	case constDuration:
+		return "DURATION"
+	case constArray:
+		return "ARRAY"
+	case constObject:
+		return "OBJECT"
 	default:
 		return "UNDEFINED"
 	}
@@ -210,6 +210,22 @@ func TestConstValue(t *testing.T) {
 func TestConstString(t *testing.T) {
 	assert.Equal(t, "42", IntConst(42).String())
 	assert.Equal(t, "\"foobar\"", StringConst("foobar").String())
+	assert.Equal(t, `"say \"hi\""`, StringConst(`say "hi"`).String())
+	assert.Equal(t, `"O'Brien"`, StringConst("O'Brien").String())
+	assert.Equal(t, `"a\\b\nc\td\u0000"`, StringConst("a\\b\nc\td\x00").String())
+	assert.Equal(t, `"été"`, StringConst("été").String())
+}
+
+func TestConstStringRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"", "foo", `"`, "'", "`", `\`, "\n\t\r", "\x01\x7f", "日本語",
+	} {
+		tok, err := lexerFromString(StringConst(s).String()).NextToken()
+		if assert.Nil(t, err) {
+			assert.Equal(t, tokString, tok.Type)
+			assert.Equal(t, s, tok.Value)
+		}
+	}
 }
 
 func TestConstAsFloat(t *testing.T) {
@@ -0,0 +1,357 @@
+package charlatan
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the layouts accepted for timestamps, in addition to RFC 3339. Timestamps
+// without a timezone are in UTC. Fractional seconds are always accepted after
+// the seconds.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	// common log format, e.g. Apache and nginx logs
+	"02/Jan/2006:15:04:05 -0700",
+}
+
+// TimestampConst returns a new Const of type timestamp
+func TimestampConst(value time.Time) *Const {
+	return &Const{timeValue: value, constType: constTimestamp}
+}
+
+// DurationConst returns a new Const of type duration
+func DurationConst(value time.Duration) *Const {
+	return &Const{durationValue: value, constType: constDuration}
+}
+
+// ParseTimestamp parses a timestamp. It accepts RFC 3339 timestamps, e.g.
+// "2016-12-28T10:00:00Z", as well as some common variants: a space instead of
+// the "T", no seconds, no time at all, or no timezone, in which case the
+// timestamp is in UTC.
+func ParseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid timestamp: %s", s)
+}
+
+// ParseDuration parses a duration, e.g. "5m" or "1h30m". On top of the units
+// supported by time.ParseDuration it supports days ("d") and weeks ("w").
+func ParseDuration(s string) (time.Duration, error) {
+	var buf bytes.Buffer
+
+	s = strings.TrimSpace(s)
+	start := 0
+
+	// convert the days and weeks into hours
+	for i := 0; i < len(s); i++ {
+		if s[i] != 'd' && s[i] != 'w' {
+			continue
+		}
+
+		j := i
+		for j > start && (isDigit(s[j-1]) || s[j-1] == '.') {
+			j--
+		}
+
+		n, err := strconv.ParseFloat(s[j:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid duration: %s", s)
+		}
+
+		if s[i] == 'd' {
+			n *= 24
+		} else {
+			n *= 24 * 7
+		}
+
+		buf.WriteString(s[start:j])
+		buf.WriteString(strconv.FormatFloat(n, 'f', -1, 64))
+		buf.WriteByte('h')
+		start = i + 1
+	}
+
+	buf.WriteString(s[start:])
+
+	d, err := time.ParseDuration(buf.String())
+	if err != nil {
+		return 0, fmt.Errorf("Invalid duration: %s", s)
+	}
+
+	return d, nil
+}
+
+// loadLocation returns the timezone with the given name. It can be an IANA
+// name, e.g. "Europe/Paris", "UTC", or a fixed offset, e.g. "+02:00".
+func loadLocation(name string) (*time.Location, error) {
+	if name == "Z" {
+		return time.UTC, nil
+	}
+
+	if strings.HasPrefix(name, "+") || strings.HasPrefix(name, "-") {
+		for _, layout := range []string{"-07:00", "-0700", "-07"} {
+			if t, err := time.Parse(layout, name); err == nil {
+				_, offset := t.Zone()
+				return time.FixedZone(name, offset), nil
+			}
+		}
+	}
+
+	// "Local" depends on the machine the query runs on
+	if name != "" && name != "Local" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown timezone: %s", name)
+}
+
+// isTime tests if the const is a timestamp or a duration
+func (c Const) isTime() bool {
+	return c.constType == constTimestamp || c.constType == constDuration
+}
+
+// asTimestamp converts the const to a timestamp if it's a timestamp or a
+// string that can be parsed as such
+func (c Const) asTimestamp() (time.Time, bool) {
+	switch c.constType {
+	case constTimestamp:
+		return c.timeValue, true
+	case constString:
+		t, err := ParseTimestamp(c.stringValue)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// asDuration converts the const to a duration if it's a duration or a string
+// that can be parsed as such
+func (c Const) asDuration() (time.Duration, bool) {
+	switch c.constType {
+	case constDuration:
+		return c.durationValue, true
+	case constString:
+		d, err := ParseDuration(c.stringValue)
+		return d, err == nil
+	}
+	return 0, false
+}
+
+// timeRat returns the number of seconds since the Unix epoch of a timestamp,
+// or the number of seconds of a duration
+func (c Const) timeRat() *big.Rat {
+	var nanos *big.Int
+
+	if c.constType == constTimestamp {
+		nanos = new(big.Int).Mul(big.NewInt(c.timeValue.Unix()), big.NewInt(int64(time.Second)))
+		nanos.Add(nanos, big.NewInt(int64(c.timeValue.Nanosecond())))
+	} else {
+		nanos = big.NewInt(int64(c.durationValue))
+	}
+
+	return new(big.Rat).SetFrac(nanos, big.NewInt(int64(time.Second)))
+}
+
+// cmpTimeConsts compares a timestamp or a duration with another const:
+//   - strings are parsed as timestamps or durations
+//   - numbers are compared with the Unix time of timestamps, and the
+//     number of seconds of durations
+//
+// The boolean is false if the two consts can't be compared this way.
+func cmpTimeConsts(c1, c2 Const) (int, bool) {
+	if !c1.isTime() {
+		r, ok := cmpTimeConsts(c2, c1)
+		return -r, ok
+	}
+
+	if c2.IsNumeric() {
+		n := DecimalConst(c1.timeRat(), 0)
+		return cmpBigNumerics(*n, c2), true
+	}
+
+	if c1.constType == constTimestamp {
+		if t, ok := c2.asTimestamp(); ok {
+			return cmpTimes(c1.timeValue, t), true
+		}
+		return 0, false
+	}
+
+	if d, ok := c2.asDuration(); ok {
+		return cmpInts(int64(c1.durationValue), int64(d)), true
+	}
+
+	return 0, false
+}
+
+// truncateTime truncates a timestamp to the given unit, in its own timezone
+func truncateTime(unit string, t time.Time) (time.Time, error) {
+	year, month, day := t.Date()
+	loc := t.Location()
+
+	switch strings.ToLower(unit) {
+	case "second":
+		return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+	case "minute":
+		return time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, loc), nil
+	case "hour":
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, loc), nil
+	case "day":
+		return time.Date(year, month, day, 0, 0, 0, 0, loc), nil
+	case "week":
+		// weeks start on monday
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, loc), nil
+	case "month":
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc), nil
+	case "quarter":
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, loc), nil
+	case "year":
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, loc), nil
+	}
+
+	return time.Time{}, fmt.Errorf("Unknown time unit: %s", unit)
+}
+
+// extractTime returns a field of a timestamp, in its own timezone
+func extractTime(unit string, t time.Time) (*Const, error) {
+	switch strings.ToLower(unit) {
+	case "year":
+		return IntConst(int64(t.Year())), nil
+	case "quarter":
+		return IntConst(int64(t.Month()-1)/3 + 1), nil
+	case "month":
+		return IntConst(int64(t.Month())), nil
+	case "week":
+		_, week := t.ISOWeek()
+		return IntConst(int64(week)), nil
+	case "day":
+		return IntConst(int64(t.Day())), nil
+	case "dow":
+		// sunday is 0
+		return IntConst(int64(t.Weekday())), nil
+	case "doy":
+		return IntConst(int64(t.YearDay())), nil
+	case "hour":
+		return IntConst(int64(t.Hour())), nil
+	case "minute":
+		return IntConst(int64(t.Minute())), nil
+	case "second":
+		return IntConst(int64(t.Second())), nil
+	case "epoch":
+		if t.Nanosecond() == 0 {
+			return IntConst(t.Unix()), nil
+		}
+		return FloatConst(TimestampConst(t).AsFloat()), nil
+	}
+
+	return nil, fmt.Errorf("Unknown time unit: %s", unit)
+}
+
+// strftime formats a timestamp like the C function of the same name. The
+// supported directives are:
+//
+//	%a, %A  abbreviated and full weekday name
+//	%b, %B  abbreviated and full month name
+//	%d, %e  day of the month, zero-padded and space-padded
+//	%f      microseconds, zero-padded
+//	%F      same as %Y-%m-%d
+//	%H, %I  hour (24-hour and 12-hour clock), zero-padded
+//	%j      day of the year, zero-padded
+//	%m, %M  month and minute, zero-padded
+//	%p      AM or PM
+//	%s      seconds since the Unix epoch
+//	%S      second, zero-padded
+//	%T      same as %H:%M:%S
+//	%u, %w  day of the week, from 1 (monday) to 7, and from 0 (sunday) to 6
+//	%y, %Y  year without and with the century
+//	%z, %Z  timezone offset (+hhmm) and name
+//	%%      a literal %
+func strftime(format string, t time.Time) (string, error) {
+	var buf bytes.Buffer
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			buf.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		if i == len(format) {
+			return "", fmt.Errorf("Unterminated directive in format: %s", format)
+		}
+
+		switch format[i] {
+		case 'a':
+			buf.WriteString(t.Format("Mon"))
+		case 'A':
+			buf.WriteString(t.Format("Monday"))
+		case 'b':
+			buf.WriteString(t.Format("Jan"))
+		case 'B':
+			buf.WriteString(t.Format("January"))
+		case 'd':
+			buf.WriteString(t.Format("02"))
+		case 'e':
+			buf.WriteString(t.Format("_2"))
+		case 'f':
+			fmt.Fprintf(&buf, "%06d", t.Nanosecond()/1000)
+		case 'F':
+			buf.WriteString(t.Format("2006-01-02"))
+		case 'H':
+			buf.WriteString(t.Format("15"))
+		case 'I':
+			buf.WriteString(t.Format("03"))
+		case 'j':
+			fmt.Fprintf(&buf, "%03d", t.YearDay())
+		case 'm':
+			buf.WriteString(t.Format("01"))
+		case 'M':
+			buf.WriteString(t.Format("04"))
+		case 'p':
+			buf.WriteString(t.Format("PM"))
+		case 's':
+			buf.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'S':
+			buf.WriteString(t.Format("05"))
+		case 'T':
+			buf.WriteString(t.Format("15:04:05"))
+		case 'u':
+			buf.WriteString(strconv.Itoa((int(t.Weekday())+6)%7 + 1))
+		case 'w':
+			buf.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'y':
+			buf.WriteString(t.Format("06"))
+		case 'Y':
+			buf.WriteString(strconv.Itoa(t.Year()))
+		case 'z':
+			buf.WriteString(t.Format("-0700"))
+		case 'Z':
+			buf.WriteString(t.Format("MST"))
+		case '%':
+			buf.WriteByte('%')
+		default:
+			return "", fmt.Errorf("Unknown directive %%%c in format: %s", format[i], format)
+		}
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,242 @@
+package charlatan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseTimestamp(s string) time.Time {
+	t, err := ParseTimestamp(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func timestamp(s string) *Const {
+	return TimestampConst(mustParseTimestamp(s))
+}
+
+func duration(s string) *Const {
+	d, err := ParseDuration(s)
+	if err != nil {
+		panic(err)
+	}
+	return DurationConst(d)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2016, 12, 28, 10, 0, 0, 0, time.UTC)
+
+	for _, s := range []string{
+		"2016-12-28T10:00:00Z",
+		"2016-12-28T11:00:00+01:00",
+		"2016-12-28T10:00:00",
+		"2016-12-28 10:00:00",
+		"2016-12-28 10:00:00Z",
+		"2016-12-28 05:00:00 -0500",
+		"2016-12-28T10:00",
+		"2016-12-28 10:00",
+		"28/Dec/2016:10:00:00 +0000",
+		" 2016-12-28T10:00:00Z ",
+	} {
+		ts, err := ParseTimestamp(s)
+		if assert.Nil(t, err, s) {
+			assert.True(t, expected.Equal(ts), "%s: %s", s, ts)
+		}
+	}
+
+	ts, err := ParseTimestamp("2016-12-28T10:00:00.123456789Z")
+	require.Nil(t, err)
+	assert.Equal(t, 123456789, ts.Nanosecond())
+
+	ts, err = ParseTimestamp("2016-12-28")
+	require.Nil(t, err)
+	assert.Equal(t, time.Date(2016, 12, 28, 0, 0, 0, 0, time.UTC), ts)
+
+	for _, s := range []string{"", "2016", "2016-13-01", "yesterday", "1482919200"} {
+		_, err := ParseTimestamp(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"5m":     5 * time.Minute,
+		"1h30m":  90 * time.Minute,
+		"-1h":    -time.Hour,
+		"1.5s":   1500 * time.Millisecond,
+		"1d":     24 * time.Hour,
+		"2w":     14 * 24 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"0.5d":   12 * time.Hour,
+		"-1d1h":  -25 * time.Hour,
+		"300ms":  300 * time.Millisecond,
+		" 10s ":  10 * time.Second,
+		"1w2d3h": (7*24 + 2*24 + 3) * time.Hour,
+	} {
+		d, err := ParseDuration(s)
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, expected, d, s)
+		}
+	}
+
+	for _, s := range []string{"", "5", "d", "1x", "1 hour", "1dd"} {
+		_, err := ParseDuration(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := loadLocation("UTC")
+	require.Nil(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	loc, err = loadLocation("+02:00")
+	require.Nil(t, err)
+	_, offset := time.Date(2016, 1, 1, 0, 0, 0, 0, loc).Zone()
+	assert.Equal(t, 2*3600, offset)
+
+	loc, err = loadLocation("-0530")
+	require.Nil(t, err)
+	_, offset = time.Date(2016, 1, 1, 0, 0, 0, 0, loc).Zone()
+	assert.Equal(t, -(5*3600 + 30*60), offset)
+
+	for _, name := range []string{"", "Local", "Nowhere/Somewhere", "+25:00"} {
+		_, err := loadLocation(name)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestTruncateTime(t *testing.T) {
+	// a wednesday
+	ts := mustParseTimestamp("2016-08-17T10:42:17.5+02:00")
+
+	for unit, expected := range map[string]string{
+		"second":  "2016-08-17T10:42:17+02:00",
+		"MINUTE":  "2016-08-17T10:42:00+02:00",
+		"hour":    "2016-08-17T10:00:00+02:00",
+		"day":     "2016-08-17T00:00:00+02:00",
+		"week":    "2016-08-15T00:00:00+02:00",
+		"month":   "2016-08-01T00:00:00+02:00",
+		"quarter": "2016-07-01T00:00:00+02:00",
+		"year":    "2016-01-01T00:00:00+02:00",
+	} {
+		tr, err := truncateTime(unit, ts)
+		if assert.Nil(t, err, unit) {
+			assert.Equal(t, expected, tr.Format(time.RFC3339Nano), unit)
+		}
+	}
+
+	_, err := truncateTime("fortnight", ts)
+	assert.NotNil(t, err)
+}
+
+func TestExtractTime(t *testing.T) {
+	// a sunday
+	ts := mustParseTimestamp("2017-01-01T23:59:30Z")
+
+	for unit, expected := range map[string]int64{
+		"year":    2017,
+		"quarter": 1,
+		"month":   1,
+		"week":    52,
+		"day":     1,
+		"dow":     0,
+		"doy":     1,
+		"hour":    23,
+		"Minute":  59,
+		"second":  30,
+		"epoch":   1483315170,
+	} {
+		c, err := extractTime(unit, ts)
+		if assert.Nil(t, err, unit) {
+			assert.Equal(t, expected, c.Value(), unit)
+		}
+	}
+
+	c, err := extractTime("epoch", ts.Add(500*time.Millisecond))
+	require.Nil(t, err)
+	assert.Equal(t, 1483315170.5, c.Value())
+
+	_, err = extractTime("century", ts)
+	assert.NotNil(t, err)
+}
+
+func TestStrftime(t *testing.T) {
+	ts := mustParseTimestamp("2016-02-09T15:04:05.123456+01:00")
+
+	for format, expected := range map[string]string{
+		"%Y-%m-%d %H:%M:%S": "2016-02-09 15:04:05",
+		"%F %T":             "2016-02-09 15:04:05",
+		"%a %A %b %B":       "Tue Tuesday Feb February",
+		"%e|%j|%y":          " 9|040|16",
+		"%I%p":              "03PM",
+		"%f":                "123456",
+		"%u %w":             "2 2",
+		"%z":                "+0100",
+		"%s":                "1455026645",
+		"100%%":             "100%",
+		"no directive":      "no directive",
+	} {
+		s, err := strftime(format, ts)
+		if assert.Nil(t, err, format) {
+			assert.Equal(t, expected, s, format)
+		}
+	}
+
+	for _, format := range []string{"%", "%Y-%Q"} {
+		_, err := strftime(format, ts)
+		assert.NotNil(t, err, format)
+	}
+}
+
+func TestTimestampConst(t *testing.T) {
+	ts := time.Date(2016, 12, 28, 10, 0, 0, 500000000, time.UTC)
+	c := TimestampConst(ts)
+
+	assert.Equal(t, constTimestamp, c.constType)
+	assert.False(t, c.IsNumeric())
+	assert.Equal(t, ts, c.Value())
+	assert.Equal(t, "2016-12-28T10:00:00.5Z", c.AsString())
+	assert.Equal(t, `TIMESTAMP "2016-12-28T10:00:00.5Z"`, c.String())
+	assert.Equal(t, int64(1482919200), c.AsInt())
+	assert.Equal(t, 1482919200.5, c.AsFloat())
+	assert.True(t, c.AsBool())
+	assert.False(t, TimestampConst(time.Time{}).AsBool())
+
+	c, err := NewConst(ts)
+	require.Nil(t, err)
+	assert.Equal(t, constTimestamp, c.constType)
+}
+
+func TestDurationConst(t *testing.T) {
+	c := DurationConst(90 * time.Minute)
+
+	assert.Equal(t, constDuration, c.constType)
+	assert.False(t, c.IsNumeric())
+	assert.Equal(t, 90*time.Minute, c.Value())
+	assert.Equal(t, "1h30m0s", c.AsString())
+	assert.Equal(t, `INTERVAL "1h30m0s"`, c.String())
+	assert.Equal(t, int64(5400), c.AsInt())
+	assert.Equal(t, 5400.0, c.AsFloat())
+	assert.True(t, c.AsBool())
+	assert.False(t, DurationConst(0).AsBool())
+
+	c, err := NewConst(time.Second)
+	require.Nil(t, err)
+	assert.Equal(t, constDuration, c.constType)
+}
+
+func TestTimestampCompareToUnparsableString(t *testing.T) {
+	// compared as strings
+	r, err := timestamp("2016-12-28T10:00:00Z").CompareTo(StringConst("2016"))
+	require.Nil(t, err)
+	assert.Equal(t, 1, r)
+
+	_, err = timestamp("2016-12-28T10:00:00Z").CompareTo(duration("1h"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,235 @@
+package charlatan
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// the maximum number of digits after the decimal point used for the decimals
+// which can't be written exactly, e.g. 1/3
+const maxDecimalScale = 16
+
+var (
+	minInt64 = big.NewInt(math.MinInt64)
+	maxInt64 = big.NewInt(math.MaxInt64)
+)
+
+// BigIntConst returns a new Const of type bigint. The value must not be
+// modified afterwards.
+func BigIntConst(value *big.Int) *Const {
+	return &Const{bigIntValue: value, constType: constBigInt}
+}
+
+// DecimalConst returns a new Const of type decimal, written with the given
+// number of digits after the decimal point. The value must not be modified
+// afterwards.
+func DecimalConst(value *big.Rat, scale int) *Const {
+	if scale < 0 {
+		scale = 0
+	}
+	return &Const{decimalValue: value, scale: scale, constType: constDecimal}
+}
+
+// DecimalConstFromString parses a decimal, e.g. "12345678901234567890.12" or
+// "1.5e-3". The scale is the one of the string, so that trailing zeros are
+// kept.
+func DecimalConstFromString(s string) (*Const, error) {
+	if !isDecimalString(s) {
+		return nil, fmt.Errorf("Invalid decimal: %s", s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("Invalid decimal: %s", s)
+	}
+
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		exponent, _ = strconv.Atoi(s[i+1:])
+	}
+
+	scale := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		scale = len(mantissa) - i - 1
+	}
+
+	return DecimalConst(r, scale-exponent), nil
+}
+
+// ConstFromStringDecimal is like ConstFromString but parses non-integer
+// numbers as decimals instead of floats, so that they're never rounded.
+func ConstFromStringDecimal(s string) *Const {
+	c := ConstFromString(s)
+
+	if c.constType == constFloat {
+		if d, err := DecimalConstFromString(s); err == nil {
+			return d
+		}
+	}
+
+	return c
+}
+
+// numericLiteralConst returns the const for a numeric literal of a query.
+// Floats that can't be represented exactly as a float64 are kept as decimals,
+// so that e.g. "WHERE amount = 12345678901234567890.12" works on decimals.
+func numericLiteralConst(s string) *Const {
+	c := ConstFromString(s)
+
+	if c.constType != constFloat {
+		return c
+	}
+
+	d, err := DecimalConstFromString(s)
+	if err != nil || d.decimalValue.Cmp(floatToRat(c.floatValue)) == 0 {
+		return c
+	}
+
+	return d
+}
+
+// isDecimalString tests if the string is a number written in decimal, with an
+// optional exponent. Unlike strconv.ParseFloat and big.Rat.SetString this
+// excludes special values, hexadecimal numbers and fractions.
+func isDecimalString(s string) bool {
+	i := 0
+
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+
+	digits := 0
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		digits++
+	}
+
+	if i < len(s) && s[i] == '.' {
+		i++
+		for ; i < len(s) && isDigit(s[i]); i++ {
+			digits++
+		}
+	}
+
+	if digits == 0 {
+		return false
+	}
+
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+
+		exponent := 0
+		for ; i < len(s) && isDigit(s[i]); i++ {
+			exponent++
+		}
+
+		if exponent == 0 {
+			return false
+		}
+	}
+
+	return i == len(s)
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isRangeError(err error) bool {
+	if err, ok := err.(*strconv.NumError); ok {
+		return err.Err == strconv.ErrRange
+	}
+	return false
+}
+
+// decimalScale returns the number of digits needed after the decimal point
+// to write the given value exactly, up to maxDecimalScale
+func decimalScale(r *big.Rat) int {
+	ten := big.NewInt(10)
+	v := new(big.Rat).Set(r)
+
+	for scale := 0; scale < maxDecimalScale; scale++ {
+		if v.IsInt() {
+			return scale
+		}
+		v.Mul(v, new(big.Rat).SetInt(ten))
+	}
+
+	return maxDecimalScale
+}
+
+// clampBigInt converts the value to an int64, clamping it to the int64 range
+func clampBigInt(b *big.Int) int64 {
+	switch {
+	case b.Cmp(minInt64) < 0:
+		return math.MinInt64
+	case b.Cmp(maxInt64) > 0:
+		return math.MaxInt64
+	}
+	return b.Int64()
+}
+
+// floatToRat returns the exact value of the shortest decimal representation of
+// a finite float, i.e. 0.1 is the decimal 0.1, not the float closest to it.
+func floatToRat(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return r
+}
+
+// isBig tests if the const is one of the arbitrary precision types
+func (c Const) isBig() bool {
+	return c.constType == constBigInt || c.constType == constDecimal
+}
+
+// asRat converts a numeric const, which must not be a NaN or infinite
+// float, to a rational
+func (c Const) asRat() *big.Rat {
+	switch c.constType {
+	case constInt:
+		return new(big.Rat).SetInt64(c.intValue)
+	case constFloat:
+		return floatToRat(c.floatValue)
+	case constBigInt:
+		return new(big.Rat).SetInt(c.bigIntValue)
+	case constDecimal:
+		return c.decimalValue
+	}
+	return new(big.Rat)
+}
+
+// floatRank orders the special floats: NaN is the lowest, then -Inf, then
+// every finite number (including non-float ones), then +Inf
+func (c Const) floatRank() int {
+	if c.constType != constFloat {
+		return 0
+	}
+
+	switch f := c.floatValue; {
+	case math.IsNaN(f):
+		return -2
+	case math.IsInf(f, -1):
+		return -1
+	case math.IsInf(f, 1):
+		return 1
+	}
+	return 0
+}
+
+// cmpBigNumerics compares two numerics when at least one of them is a bigint
+// or a decimal. Floats are compared by their shortest decimal representation.
+func cmpBigNumerics(c1, c2 Const) int {
+	r1, r2 := c1.floatRank(), c2.floatRank()
+
+	if r1 != 0 || r2 != 0 {
+		return cmpInts(int64(r1), int64(r2))
+	}
+
+	return c1.asRat().Cmp(c2.asRat())
+}
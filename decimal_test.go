@@ -0,0 +1,135 @@
+package charlatan
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigIntConst(t *testing.T) {
+	b, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	c := BigIntConst(b)
+
+	assert.Equal(t, constBigInt, c.constType)
+	assert.True(t, c.IsNumeric())
+	assert.Equal(t, b, c.Value())
+	assert.Equal(t, "123456789012345678901234567890", c.AsString())
+	assert.Equal(t, int64(math.MaxInt64), c.AsInt())
+	assert.Equal(t, 1.2345678901234568e29, c.AsFloat())
+	assert.True(t, c.AsBool())
+	assert.False(t, BigIntConst(new(big.Int)).AsBool())
+}
+
+func TestDecimalConst(t *testing.T) {
+	c := DecimalConst(big.NewRat(-5, 2), 3)
+
+	assert.Equal(t, constDecimal, c.constType)
+	assert.True(t, c.IsNumeric())
+	assert.Equal(t, "-2.500", c.AsString())
+	assert.Equal(t, int64(-2), c.AsInt())
+	assert.Equal(t, -2.5, c.AsFloat())
+	assert.True(t, c.AsBool())
+	assert.False(t, DecimalConst(new(big.Rat), 0).AsBool())
+}
+
+func TestDecimalConstFromString(t *testing.T) {
+	for s, expected := range map[string]string{
+		"12345678901234567890.12": "12345678901234567890.12",
+		"0.10":                    "0.10",
+		"-1.5":                    "-1.5",
+		"+3":                      "3",
+		".5":                      "0.5",
+		"5.":                      "5",
+		"1.5e-3":                  "0.0015",
+		"1.5E3":                   "1500",
+		"25e-1":                   "2.5",
+	} {
+		c, err := DecimalConstFromString(s)
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, expected, c.AsString(), s)
+		}
+	}
+
+	for _, s := range []string{"", "-", ".", "1/3", "NaN", "Inf", "0x10", "1e", "1.2.3", "a"} {
+		_, err := DecimalConstFromString(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestConstFromStringBigInt(t *testing.T) {
+	c := ConstFromString("-99999999999999999999")
+	require.Equal(t, constBigInt, c.constType)
+	assert.Equal(t, "-99999999999999999999", c.AsString())
+	assert.Equal(t, int64(math.MinInt64), c.AsInt())
+
+	assert.Equal(t, constInt, ConstFromString("9223372036854775807").constType)
+}
+
+func TestConstFromStringDecimal(t *testing.T) {
+	assert.Equal(t, constInt, ConstFromStringDecimal("42").constType)
+	assert.Equal(t, constBigInt, ConstFromStringDecimal("99999999999999999999").constType)
+	assert.Equal(t, constString, ConstFromStringDecimal("foo").constType)
+	assert.Equal(t, constBool, ConstFromStringDecimal("true").constType)
+	// not a decimal
+	assert.Equal(t, constFloat, ConstFromStringDecimal("NaN").constType)
+
+	c := ConstFromStringDecimal("12345678901234567890.12")
+	require.Equal(t, constDecimal, c.constType)
+	assert.Equal(t, "12345678901234567890.12", c.AsString())
+}
+
+func TestNewConstBig(t *testing.T) {
+	c, err := NewConst(big.NewInt(42))
+	require.Nil(t, err)
+	assert.Equal(t, constBigInt, c.constType)
+
+	c, err = NewConst(big.NewRat(1, 8))
+	require.Nil(t, err)
+	assert.Equal(t, constDecimal, c.constType)
+	assert.Equal(t, "0.125", c.AsString())
+
+	c, err = NewConst(big.NewRat(1, 3))
+	require.Nil(t, err)
+	assert.Equal(t, "0.3333333333333333", c.AsString())
+}
+
+func TestNumericLiteralConst(t *testing.T) {
+	assert.Equal(t, constInt, numericLiteralConst("42").constType)
+	assert.Equal(t, constFloat, numericLiteralConst("0.5").constType)
+	assert.Equal(t, constFloat, numericLiteralConst("0.1").constType)
+	assert.Equal(t, constBigInt, numericLiteralConst("12345678901234567890").constType)
+
+	c := numericLiteralConst("12345678901234567890.12")
+	require.Equal(t, constDecimal, c.constType)
+	assert.Equal(t, "12345678901234567890.12", c.String())
+}
+
+func TestDecimalQuery(t *testing.T) {
+	q, err := QueryFromString("SELECT a FROM b WHERE amount = 12345678901234567890.12")
+	require.Nil(t, err)
+
+	ok, err := q.Evaluate(&constRecord{ConstFromStringDecimal("12345678901234567890.120")})
+	require.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = q.Evaluate(&constRecord{ConstFromStringDecimal("12345678901234567890.13")})
+	require.Nil(t, err)
+	assert.False(t, ok)
+
+	// the float is rounded
+	ok, err = q.Evaluate(&constRecord{ConstFromString("12345678901234567890.13")})
+	require.Nil(t, err)
+	assert.False(t, ok)
+}
+
+// constRecord is a record whose fields all have the same value
+type constRecord struct {
+	value *Const
+}
+
+func (r *constRecord) Find(f *Field) (*Const, error) {
+	return r.value, nil
+}
@@ -1,14 +1,27 @@
 package charlatan
 
+import "strings"
+
 // Field is a field, contained into the SELECT part and the condition.
 // A field is an operand, it can return the value extracted into the Record.
 type Field struct {
 	name string
+	// the path, parsed once from the name
+	path FieldPath
+	// the column index the field is bound to, if any
+	index int
+	bound bool
 }
 
-// NewField returns a new field from the given string
+// NewField returns a new field from the given string. Its path is parsed from
+// it, if it's not a valid path the name is split on dots instead.
 func NewField(name string) *Field {
-	return &Field{name}
+	path, err := ParseFieldPath(name)
+	if err != nil {
+		path = keysPath(name)
+	}
+
+	return &Field{name: name, path: path}
 }
 
 // Evaluate evaluates the field on a record
@@ -21,6 +34,36 @@ func (f Field) Name() string {
 	return f.name
 }
 
+// Path returns the field's path, e.g. the keys "a" and "b" and the index 0
+// for "a.b[0]"
+func (f Field) Path() FieldPath {
+	return f.path
+}
+
+// Index returns the column index the field is bound to, see
+// Query.BindFields. The boolean is false if it isn't bound.
+func (f Field) Index() (int, bool) {
+	return f.index, f.bound
+}
+
+// WithIndex returns a copy of the field bound to the given column index
+func (f Field) WithIndex(index int) *Field {
+	f.index = index
+	f.bound = true
+	return &f
+}
+
 func (f Field) String() string {
-	return f.name
+	return quoteField(f.name)
+}
+
+// quoteField returns the field name as it should be written in a query, i.e.
+// surrounded by backquotes if it can't be read as a bare field name
+func quoteField(name string) string {
+	tok, err := lexerFromString(name).NextToken()
+	if err == nil && tok.Type == tokField && tok.Value == name && tok.Pos == 1 &&
+		tok.End == len([]rune(name)) {
+		return name
+	}
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
 }
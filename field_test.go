@@ -4,8 +4,73 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFieldName(t *testing.T) {
 	assert.Equal(t, "yo", NewField("yo").Name())
 }
+
+func TestQuoteField(t *testing.T) {
+	for name, quoted := range map[string]string{
+		"yo":         "yo",
+		"a.b.c":      "a.b.c",
+		"$1":         "$1",
+		"data/f.csv": "data/f.csv",
+		"with space": "`with space`",
+		"42":         "`42`",
+		"true":       "`true`",
+		"where":      "`where`",
+		"a(b)":       "`a(b)`",
+		"":           "``",
+		" leading":   "` leading`",
+		"quote'":     "`quote'`",
+		"back`quote": "`back``quote`",
+		"a[0].b[*]":  "a[0].b[*]",
+		`a["b c"]`:   `a["b c"]`,
+		"a[":         "`a[`",
+	} {
+		assert.Equal(t, quoted, quoteField(name))
+	}
+}
+
+func TestFieldString(t *testing.T) {
+	assert.Equal(t, "yo", NewField("yo").String())
+	assert.Equal(t, "`y o`", NewField("y o").String())
+}
+
+func TestFieldPath(t *testing.T) {
+	path := NewField("items[0].price").Path()
+	require.Equal(t, 3, len(path))
+
+	key, ok := path[0].Key()
+	assert.True(t, ok)
+	assert.Equal(t, "items", key)
+
+	_, ok = path[1].Key()
+	assert.False(t, ok)
+
+	// invalid paths are split on dots
+	path = NewField("a[.b").Path()
+	require.Equal(t, 2, len(path))
+	key, _ = path[0].Key()
+	assert.Equal(t, "a[", key)
+}
+
+func TestFieldIndex(t *testing.T) {
+	f := NewField("a")
+
+	_, ok := f.Index()
+	assert.False(t, ok)
+
+	b := f.WithIndex(3)
+	i, ok := b.Index()
+	assert.True(t, ok)
+	assert.Equal(t, 3, i)
+	assert.Equal(t, "a", b.Name())
+	assert.Equal(t, "a", b.String())
+
+	// the original field isn't bound
+	_, ok = f.Index()
+	assert.False(t, ok)
+}
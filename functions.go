@@ -0,0 +1,288 @@
+package charlatan
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// function is a function that can be called in a query
+type function struct {
+	// the number of arguments
+	minArgs, maxArgs int
+	// check validates the arguments which are known when the query is
+	// parsed, it's optional
+	check func(args []operand) error
+	// call computes the result, the arguments are never null
+	call func(args []*Const) (*Const, error)
+}
+
+// functionCall is a call to a function
+type functionCall struct {
+	name string
+	args []operand
+	fn   *function
+}
+
+// now returns the current time, it's a variable so that tests can change it
+var now = time.Now
+
+var functions = map[string]*function{
+	// NOW()
+	"NOW": &function{
+		call: func(args []*Const) (*Const, error) {
+			return TimestampConst(now().UTC()), nil
+		},
+	},
+
+	// DATE_TRUNC(unit, timestamp[, timezone])
+	"DATE_TRUNC": &function{
+		minArgs: 2,
+		maxArgs: 3,
+		check: func(args []operand) error {
+			return checkConstArgs(args, checkTruncateUnit, nil, checkTimezone)
+		},
+		call: func(args []*Const) (*Const, error) {
+			t, err := timestampArg(args, 1, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			t, err = truncateTime(args[0].AsString(), t)
+			if err != nil {
+				return nil, err
+			}
+
+			return TimestampConst(t), nil
+		},
+	},
+
+	// EXTRACT(unit FROM timestamp), or EXTRACT(unit, timestamp[, timezone])
+	"EXTRACT": &function{
+		minArgs: 2,
+		maxArgs: 3,
+		check: func(args []operand) error {
+			return checkConstArgs(args, checkExtractUnit, nil, checkTimezone)
+		},
+		call: func(args []*Const) (*Const, error) {
+			t, err := timestampArg(args, 1, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			return extractTime(args[0].AsString(), t)
+		},
+	},
+
+	// STRFTIME(format, timestamp[, timezone])
+	"STRFTIME": &function{
+		minArgs: 2,
+		maxArgs: 3,
+		check: func(args []operand) error {
+			return checkConstArgs(args, checkFormat, nil, checkTimezone)
+		},
+		call: func(args []*Const) (*Const, error) {
+			t, err := timestampArg(args, 1, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			s, err := strftime(args[0].AsString(), t)
+			if err != nil {
+				return nil, err
+			}
+
+			return StringConst(s), nil
+		},
+	},
+
+	// TIMEZONE(timezone, timestamp) converts the timestamp to the timezone
+	"TIMEZONE": &function{
+		minArgs: 2,
+		maxArgs: 2,
+		check: func(args []operand) error {
+			return checkConstArgs(args, checkTimezone)
+		},
+		call: func(args []*Const) (*Const, error) {
+			args = []*Const{args[1], args[0]}
+
+			t, err := timestampArg(args, 0, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			return TimestampConst(t), nil
+		},
+	},
+
+	// LENGTH(array, object or string)
+	"LENGTH": &function{
+		minArgs: 1,
+		maxArgs: 1,
+		call: func(args []*Const) (*Const, error) {
+			return length(args[0])
+		},
+	},
+
+	// CONTAINS(array, object or string, value)
+	"CONTAINS": &function{
+		minArgs: 2,
+		maxArgs: 2,
+		call: func(args []*Const) (*Const, error) {
+			return contains(args[0], args[1])
+		},
+	},
+}
+
+// newFunctionCall creates a call to the function with the given name, which
+// is case-insensitive
+func newFunctionCall(name string, args []operand) (*functionCall, error) {
+	name = strings.ToUpper(name)
+
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown function %s", name)
+	}
+
+	if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+		if fn.minArgs == fn.maxArgs {
+			return nil, fmt.Errorf("%s expects %d arguments, got %d",
+				name, fn.minArgs, len(args))
+		}
+		return nil, fmt.Errorf("%s expects %d to %d arguments, got %d",
+			name, fn.minArgs, fn.maxArgs, len(args))
+	}
+
+	// EXTRACT(hour FROM ts): the unit is parsed as a field
+	if name == "EXTRACT" {
+		if f, ok := args[0].(*Field); ok {
+			args = append([]operand{StringConst(f.Name())}, args[1:]...)
+		}
+	}
+
+	if fn.check != nil {
+		if err := fn.check(args); err != nil {
+			return nil, err
+		}
+	}
+
+	return &functionCall{name: name, args: args, fn: fn}, nil
+}
+
+// isFunction tests if there's a function with the given name
+func isFunction(name string) bool {
+	_, ok := functions[strings.ToUpper(name)]
+	return ok
+}
+
+// Evaluate evaluates the arguments against the given record and calls the
+// function. If any of the arguments is null, the result is null.
+func (f *functionCall) Evaluate(record Record) (*Const, error) {
+	args := make([]*Const, len(f.args))
+
+	for i, arg := range f.args {
+		c, err := arg.Evaluate(record)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.IsNull() {
+			return NullConst(), nil
+		}
+
+		args[i] = c
+	}
+
+	c, err := f.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", f.name, err)
+	}
+
+	return c, nil
+}
+
+func (f *functionCall) String() string {
+	var buf bytes.Buffer
+
+	buf.WriteString(f.name)
+	buf.WriteByte('(')
+
+	sep := ""
+
+	for i, arg := range f.args {
+		buf.WriteString(sep)
+		sep = ", "
+
+		// EXTRACT(unit FROM timestamp)
+		if i == 0 && f.name == "EXTRACT" && len(f.args) == 2 {
+			if c, ok := arg.(*Const); ok && c.IsString() {
+				buf.WriteString(quoteField(c.AsString()))
+				sep = " FROM "
+				continue
+			}
+		}
+
+		buf.WriteString(arg.String())
+	}
+
+	buf.WriteByte(')')
+
+	return buf.String()
+}
+
+// timestampArg returns the timestamp argument at the given index, converted
+// to the timezone at the given index if it's there
+func timestampArg(args []*Const, index, tzIndex int) (time.Time, error) {
+	t, ok := args[index].asTimestamp()
+	if !ok {
+		return time.Time{}, fmt.Errorf("Not a timestamp: %s", args[index])
+	}
+
+	if tzIndex < len(args) {
+		loc, err := loadLocation(args[tzIndex].AsString())
+		if err != nil {
+			return time.Time{}, err
+		}
+		t = t.In(loc)
+	}
+
+	return t, nil
+}
+
+// checkConstArgs calls the check function at the same index on each argument
+// that is a constant. A nil function means there's nothing to check.
+func checkConstArgs(args []operand, checks ...func(string) error) error {
+	for i, arg := range args {
+		c, ok := arg.(*Const)
+		if !ok || i >= len(checks) || checks[i] == nil || c.IsNull() {
+			continue
+		}
+
+		if err := checks[i](c.AsString()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkTruncateUnit(unit string) error {
+	_, err := truncateTime(unit, time.Time{})
+	return err
+}
+
+func checkExtractUnit(unit string) error {
+	_, err := extractTime(unit, time.Time{})
+	return err
+}
+
+func checkFormat(format string) error {
+	_, err := strftime(format, time.Time{})
+	return err
+}
+
+func checkTimezone(name string) error {
+	_, err := loadLocation(name)
+	return err
+}
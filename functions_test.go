@@ -0,0 +1,116 @@
+package charlatan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func evaluateCall(t *testing.T, name string, args ...operand) (*Const, error) {
+	f, err := newFunctionCall(name, args)
+	require.Nil(t, err)
+	return f.Evaluate(nil)
+}
+
+func TestNewFunctionCall(t *testing.T) {
+	f, err := newFunctionCall("date_trunc", []operand{StringConst("day"), NewField("ts")})
+	require.Nil(t, err)
+	assert.Equal(t, `DATE_TRUNC("day", ts)`, f.String())
+
+	for _, tc := range []struct {
+		name string
+		args []operand
+	}{
+		{"NOPE", nil},
+		{"NOW", []operand{IntConst(1)}},
+		{"DATE_TRUNC", []operand{StringConst("day")}},
+		{"DATE_TRUNC", []operand{StringConst("fortnight"), NewField("ts")}},
+		{"DATE_TRUNC", []operand{StringConst("day"), NewField("ts"), StringConst("Nowhere")}},
+		{"EXTRACT", []operand{NewField("century"), NewField("ts")}},
+		{"STRFTIME", []operand{StringConst("%Q"), NewField("ts")}},
+		{"TIMEZONE", []operand{StringConst("+99:00"), NewField("ts")}},
+	} {
+		_, err := newFunctionCall(tc.name, tc.args)
+		assert.NotNil(t, err, tc.name)
+	}
+
+	// the arguments that aren't constants are checked when evaluated
+	f, err = newFunctionCall("DATE_TRUNC", []operand{NewField("unit"), NewField("ts")})
+	require.Nil(t, err)
+	_, err = f.Evaluate(&constRecord{StringConst("fortnight")})
+	assert.NotNil(t, err)
+}
+
+func TestFunctionCallExtractString(t *testing.T) {
+	f, err := newFunctionCall("EXTRACT", []operand{NewField("hour"), NewField("ts")})
+	require.Nil(t, err)
+	assert.Equal(t, "EXTRACT(hour FROM ts)", f.String())
+
+	f, err = newFunctionCall("EXTRACT", []operand{NewField("hour"), NewField("ts"), StringConst("UTC")})
+	require.Nil(t, err)
+	assert.Equal(t, `EXTRACT("hour", ts, "UTC")`, f.String())
+}
+
+func TestFunctionNow(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return mustParseTimestamp("2016-12-28T11:00:00+01:00") }
+
+	c, err := evaluateCall(t, "NOW")
+	require.Nil(t, err)
+	assert.Equal(t, "2016-12-28T10:00:00Z", c.AsString())
+}
+
+func TestFunctionDateTrunc(t *testing.T) {
+	c, err := evaluateCall(t, "DATE_TRUNC", StringConst("hour"), timestamp("2016-12-28T10:42:00Z"))
+	require.Nil(t, err)
+	assert.Equal(t, "2016-12-28T10:00:00Z", c.AsString())
+
+	// strings are parsed
+	c, err = evaluateCall(t, "DATE_TRUNC", StringConst("day"), StringConst("2016-12-28 23:00:00"), StringConst("+02:00"))
+	require.Nil(t, err)
+	assert.Equal(t, "2016-12-29T00:00:00+02:00", c.AsString())
+
+	c, err = evaluateCall(t, "DATE_TRUNC", StringConst("day"), timestamp("2016-12-28T23:00:00Z"), StringConst("America/New_York"))
+	require.Nil(t, err)
+	assert.Equal(t, "2016-12-28T00:00:00-05:00", c.AsString())
+
+	c, err = evaluateCall(t, "DATE_TRUNC", StringConst("day"), NullConst())
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+
+	_, err = evaluateCall(t, "DATE_TRUNC", StringConst("day"), StringConst("not a timestamp"))
+	assert.NotNil(t, err)
+}
+
+func TestFunctionExtract(t *testing.T) {
+	c, err := evaluateCall(t, "EXTRACT", NewField("hour"), timestamp("2016-12-28T23:00:00Z"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(23), c.Value())
+
+	c, err = evaluateCall(t, "EXTRACT", StringConst("hour"), timestamp("2016-12-28T23:00:00Z"), StringConst("Europe/Paris"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), c.Value())
+}
+
+func TestFunctionStrftime(t *testing.T) {
+	c, err := evaluateCall(t, "STRFTIME", StringConst("%Y-%m-%d %H:%M"), timestamp("2016-12-28T23:30:00Z"))
+	require.Nil(t, err)
+	assert.Equal(t, "2016-12-28 23:30", c.AsString())
+
+	c, err = evaluateCall(t, "STRFTIME", StringConst("%H:%M %z"), timestamp("2016-12-28T23:30:00Z"), StringConst("-05:00"))
+	require.Nil(t, err)
+	assert.Equal(t, "18:30 -0500", c.AsString())
+}
+
+func TestFunctionTimezone(t *testing.T) {
+	c, err := evaluateCall(t, "TIMEZONE", StringConst("Asia/Tokyo"), timestamp("2016-12-28T23:30:00Z"))
+	require.Nil(t, err)
+	assert.Equal(t, "2016-12-29T08:30:00+09:00", c.AsString())
+
+	// it's the same instant
+	r, err := c.CompareTo(timestamp("2016-12-28T23:30:00Z"))
+	require.Nil(t, err)
+	assert.Equal(t, 0, r)
+}
@@ -8,12 +8,20 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf16"
 )
 
 // lexer is a lexer
 type lexer struct {
 	r     *bufio.Reader
 	index int
+
+	// the comments skipped so far
+	comments []*token
+	// if true, comments are returned as tokens instead of being skipped
+	keepComments bool
+	// true if the last token ended with a closing parenthesis or quote
+	closed bool
 }
 
 // lexerFromString creates a new lexer from the given string
@@ -22,8 +30,10 @@ func lexerFromString(s string) *lexer {
 }
 
 func (l *lexer) readRune() (rune, error) {
-	l.index++
 	r, _, err := l.r.ReadRune()
+	if err == nil {
+		l.index++
+	}
 	return r, err
 }
 
@@ -48,12 +58,32 @@ func (l *lexer) skipWhiteSpaces() error {
 
 // NextToken reads the next token and returns it
 func (l *lexer) NextToken() (*token, error) {
-	if err := l.skipWhiteSpaces(); err != nil {
-		if err == io.EOF {
-			return l.eof()
+	// an operator right after ")" or a closing quote can't be part of a word
+	closed, start := l.closed, l.index
+	l.closed = false
+
+	for {
+		if err := l.skipWhiteSpaces(); err != nil {
+			if err == io.EOF {
+				return l.eof()
+			}
+
+			return nil, err
 		}
 
-		return nil, err
+		comment, err := l.readComment()
+		if err != nil {
+			return nil, err
+		}
+		if comment == nil {
+			break
+		}
+
+		l.comments = append(l.comments, comment)
+
+		if l.keepComments {
+			return comment, nil
+		}
 	}
 
 	r, err := l.readRune()
@@ -66,14 +96,16 @@ func (l *lexer) NextToken() (*token, error) {
 	// delimiters: `, ", '
 	switch r {
 	case '`', '"', '\'':
-		v, err := l.readUntil(r)
-		if err != nil {
-			return nil, err
+		// backslash escapes are only supported in strings
+		v, err := l.readQuoted(r, r != '`')
+		if err == io.EOF {
+			return nil, errorAt(index, "Unterminated %c", r)
 		}
-		// consume the trailing delimiter
-		if _, err := l.readRune(); err != nil {
+		if err != nil {
 			return nil, err
 		}
+
+		l.closed = true
 		// `foo`
 		if r == '`' {
 			return l.field(v, index)
@@ -81,11 +113,31 @@ func (l *lexer) NextToken() (*token, error) {
 		// "foo" or 'foo'
 		return l.str(v, index)
 	case '(':
-		return l.simpleToken(tokLeftParenthesis, index)
+		return l.token(tokLeftParenthesis, "(", index)
 	case ')':
-		return l.simpleToken(tokRightParenthesis, index)
+		l.closed = true
+		return l.token(tokRightParenthesis, ")", index)
 	case ',':
-		return l.simpleToken(tokComma, index)
+		return l.token(tokComma, ",", index)
+	case '?':
+		return l.simpleToken(tokParameter, index)
+	case ':':
+		name, err := l.readWhile(isParameterNameRune)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return nil, errorAt(index, "Empty parameter name")
+		}
+		return l.token(tokParameter, name, index)
+	case '+':
+		if closed && start == index-1 {
+			return l.token(tokPlus, "+", index)
+		}
+	case '-':
+		if closed && start == index-1 {
+			return l.token(tokMinus, "-", index)
+		}
 	}
 
 	if err := l.unread(); err != nil {
@@ -130,6 +182,15 @@ func (l *lexer) NextToken() (*token, error) {
 		return l.token(tokNull, "null", index)
 	}
 
+	// arithmetic operators must be surrounded by whitespaces, "a-b" is a
+	// field and "-1" is a number, unless they follow ")" or a closing quote
+	switch w {
+	case "+":
+		return l.token(tokPlus, w, index)
+	case "-":
+		return l.token(tokMinus, w, index)
+	}
+
 	if _, err := strconv.ParseInt(w, 10, 64); err == nil {
 		return l.token(tokInt, w, index)
 	}
@@ -169,18 +230,30 @@ func (l *lexer) NextToken() (*token, error) {
 	}
 
 	if op != "" {
-		return nil, fmt.Errorf("Invalid operator '%s'", op)
+		return nil, errorAt(index, "Invalid operator '%s'", op)
 	}
 
-	return nil, fmt.Errorf("No known alternative at input %d", index)
+	// skip the unknown rune so that the caller can continue if it wants to
+	if _, err := l.readRune(); err != nil {
+		return nil, err
+	}
+
+	return nil, errorAt(index, "No known alternative")
+}
+
+// errorAt returns a syntax error at the given position of the input, which
+// is appended to the message
+func errorAt(index int, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	return &SyntaxError{Pos: index, Msg: fmt.Sprintf("%s at position %d", msg, index)}
 }
 
 func (l *lexer) token(typ tokenType, v string, index int) (*token, error) {
-	return &token{Type: typ, Value: v, Pos: index}, nil
+	return &token{Type: typ, Value: v, Pos: index, End: l.index}, nil
 }
 
 func (l *lexer) eof() (*token, error) {
-	return l.token(tokEnd, "", l.index)
+	return l.token(tokEnd, "", l.index+1)
 }
 
 func (l *lexer) field(v string, index int) (*token, error) {
@@ -195,7 +268,53 @@ func (l *lexer) simpleToken(typ tokenType, index int) (*token, error) {
 	return l.token(typ, "", index)
 }
 
-func (l *lexer) readUntil(delim rune) (string, error) {
+// readComment reads a -- line comment or a /* block */ comment if there's one
+// at the current position. It returns nil otherwise.
+func (l *lexer) readComment() (*token, error) {
+	var buf bytes.Buffer
+
+	start, _ := l.r.Peek(2)
+
+	switch string(start) {
+	case "--":
+		index := l.index + 1
+
+		text, err := l.readWhile(func(r rune) bool { return r != '\n' })
+		if err != nil {
+			return nil, err
+		}
+
+		return l.token(tokComment, text, index)
+
+	case "/*":
+		index := l.index + 1
+
+		for {
+			r, err := l.readRune()
+			if err == io.EOF {
+				return nil, errorAt(index, "Unterminated comment")
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			buf.WriteRune(r)
+
+			// the buffer starts with "/*", which must not be read as "/*/"
+			if r == '/' && buf.Len() > 3 && bytes.HasSuffix(buf.Bytes(), []byte("*/")) {
+				return l.token(tokComment, buf.String(), index)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// readQuoted reads a quoted value up to its closing delimiter, which is
+// consumed. The delimiter can be escaped by doubling it. If backslashes is
+// true, backslash escapes are also supported: \n, \t, \r, \\, \', \", \` and
+// \uXXXX.
+func (l *lexer) readQuoted(delim rune, backslashes bool) (string, error) {
 	var buf bytes.Buffer
 
 	for {
@@ -205,19 +324,185 @@ func (l *lexer) readUntil(delim rune) (string, error) {
 		}
 
 		if r == delim {
+			next, err := l.readRune()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+
+			// a doubled delimiter
+			if next == delim {
+				buf.WriteRune(delim)
+				continue
+			}
+
 			l.unread()
 			break
 		}
 
+		if r == '\\' && backslashes {
+			if r, err = l.readEscape(); err != nil {
+				return "", err
+			}
+		}
+
 		buf.WriteRune(r)
 	}
 
 	return buf.String(), nil
 }
 
-func (l *lexer) readWord() (string, error)     { return l.readWhile(isWordRune) }
+// readEscape reads an escape sequence, right after its backslash
+func (l *lexer) readEscape() (rune, error) {
+	index := l.index
+
+	r, err := l.readRune()
+	if err != nil {
+		return 0, err
+	}
+
+	switch r {
+	case 'n':
+		return '\n', nil
+	case 't':
+		return '\t', nil
+	case 'r':
+		return '\r', nil
+	case '\\', '\'', '"', '`':
+		return r, nil
+	case 'u':
+		r, err = l.readUnicodeEscape(index)
+		if err != nil || !utf16.IsSurrogate(r) {
+			return r, err
+		}
+
+		// a surrogate pair is made of two \uXXXX escapes
+		if next, err := l.readRune(); err != nil || next != '\\' {
+			return 0, errorAt(index, "Invalid surrogate pair")
+		}
+		if next, err := l.readRune(); err != nil || next != 'u' {
+			return 0, errorAt(index, "Invalid surrogate pair")
+		}
+
+		low, err := l.readUnicodeEscape(index)
+		if err != nil {
+			return 0, err
+		}
+
+		if r = utf16.DecodeRune(r, low); r == unicode.ReplacementChar {
+			return 0, errorAt(index, "Invalid surrogate pair")
+		}
+
+		return r, nil
+	}
+
+	return 0, errorAt(index, "Invalid escape sequence '\\%c'", r)
+}
+
+// readUnicodeEscape reads the four hexadecimal digits of an \uXXXX escape
+func (l *lexer) readUnicodeEscape(index int) (rune, error) {
+	var code rune
+
+	for i := 0; i < 4; i++ {
+		r, err := l.readRune()
+		if err != nil {
+			return 0, err
+		}
+
+		digit := strings.IndexRune("0123456789abcdef", unicode.ToLower(r))
+		if digit < 0 {
+			return 0, errorAt(index, "Invalid unicode escape")
+		}
+
+		code = code<<4 | rune(digit)
+	}
+
+	return code, nil
+}
+
 func (l *lexer) readOperator() (string, error) { return l.readWhile(isOperatorRune) }
 
+// readWord reads a word. Brackets which follow a word are part of it, e.g.
+// items[0] or a["b.c"], and quotes can be used in them.
+func (l *lexer) readWord() (string, error) {
+	var buf bytes.Buffer
+
+	for {
+		w, err := l.readWhile(isWordRune)
+		if err != nil {
+			return "", err
+		}
+
+		buf.WriteString(w)
+
+		if buf.Len() == 0 {
+			break
+		}
+
+		r, err := l.readRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if r != '[' {
+			l.unread()
+			break
+		}
+
+		if err := l.readBrackets(&buf); err != nil {
+			return "", err
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// readBrackets reads brackets up to the closing one, right after the opening
+// one. Closing brackets in quotes are skipped.
+func (l *lexer) readBrackets(buf *bytes.Buffer) error {
+	var quote rune
+
+	index := l.index
+	buf.WriteRune('[')
+
+	for {
+		r, err := l.readRune()
+		if err == io.EOF {
+			return errorAt(index, "Unterminated [")
+		}
+		if err != nil {
+			return err
+		}
+
+		buf.WriteRune(r)
+
+		switch {
+		case quote != 0 && r == '\\':
+			// the escaped character is copied as is
+			r, err = l.readRune()
+			if err == io.EOF {
+				return errorAt(index, "Unterminated [")
+			}
+			if err != nil {
+				return err
+			}
+			buf.WriteRune(r)
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ']':
+			return nil
+		}
+	}
+}
+
 func (l *lexer) readWhile(cond func(rune) bool) (string, error) {
 	var buf bytes.Buffer
 
@@ -243,6 +528,10 @@ func isWordRune(r rune) bool {
 	return !unicode.IsSpace(r) && strings.IndexRune("(),`'\"|&=!<>[]", r) == -1
 }
 
+func isParameterNameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
 func isOperatorRune(r rune) bool {
 	return strings.IndexRune("<>=!&|", r) > -1
 }
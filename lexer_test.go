@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func assertNextToken(t *testing.T, l *lexer, expected tokenType) {
@@ -66,3 +67,155 @@ func TestLexerStringSingleQuotes(t *testing.T) {
 	l := lexerFromString(`'some string'`)
 	assertNextTokens(t, l, tokString, tokEnd)
 }
+
+func TestLexerParameters(t *testing.T) {
+	l := lexerFromString("a = ? AND b = :name_2")
+	assertNextTokens(t, l, tokField, tokEq, tokParameter, tokAnd, tokField,
+		tokEq, tokParameter, tokEnd)
+
+	l = lexerFromString(":foo.bar")
+	tok, err := l.NextToken()
+	assert.Nil(t, err)
+	assert.Equal(t, tokParameter, tok.Type)
+	assert.Equal(t, "foo", tok.Value)
+
+	l = lexerFromString("a = :")
+	assertNextTokens(t, l, tokField, tokEq)
+	_, err = l.NextToken()
+	assert.NotNil(t, err)
+}
+
+func assertNextTokenValue(t *testing.T, l *lexer, ty tokenType, value string) {
+	tok, err := l.NextToken()
+	if assert.Nil(t, err) {
+		assert.Equal(t, ty, tok.Type)
+		assert.Equal(t, value, tok.Value)
+	}
+}
+
+func TestLexerStringDoubledQuotes(t *testing.T) {
+	assertNextTokenValue(t, lexerFromString(`'O''Brien'`), tokString, "O'Brien")
+	assertNextTokenValue(t, lexerFromString(`"say ""hi"""`), tokString, `say "hi"`)
+	assertNextTokenValue(t, lexerFromString(`''''`), tokString, "'")
+	assertNextTokenValue(t, lexerFromString(`''`), tokString, "")
+
+	l := lexerFromString(`'a' 'b'`)
+	assertNextTokenValue(t, l, tokString, "a")
+	assertNextTokenValue(t, l, tokString, "b")
+}
+
+func TestLexerStringBackslashEscapes(t *testing.T) {
+	for s, value := range map[string]string{
+		`"say \"hi\""`:         `say "hi"`,
+		`'it\'s'`:              "it's",
+		`"a\nb\tc\rd"`:         "a\nb\tc\rd",
+		`"back\\slash"`:        `back\slash`,
+		`"\u00e9t\u00C9"`:      "étÉ",
+		`"\ud83d\ude00 smile"`: "\U0001F600 smile",
+	} {
+		assertNextTokenValue(t, lexerFromString(s), tokString, value)
+	}
+}
+
+func TestLexerStringInvalidEscapes(t *testing.T) {
+	for _, s := range []string{
+		`"\x"`,
+		`"\u12"`,
+		`"\u12g4"`,
+		`"\ud83d"`,
+		`"\ud83d\u0041"`,
+		`"unterminated\"`,
+		`'unterminated''`,
+	} {
+		_, err := lexerFromString(s).NextToken()
+		assert.NotNil(t, err, "There should be an error reading %s", s)
+	}
+}
+
+func TestLexerFieldEscapes(t *testing.T) {
+	assertNextTokenValue(t, lexerFromString("`a``b`"), tokField, "a`b")
+	// backslashes are kept as-is in field names
+	assertNextTokenValue(t, lexerFromString("`C:\\data\\f.csv`"), tokField, `C:\data\f.csv`)
+}
+
+func TestLexerComments(t *testing.T) {
+	l := lexerFromString("SELECT a -- the a field\n, b /* the\n b field */FROM c --")
+	assertNextTokens(t, l, tokSelect, tokField, tokComma, tokField, tokFrom,
+		tokField, tokEnd)
+
+	require.Equal(t, 3, len(l.comments))
+	assert.Equal(t, "-- the a field", l.comments[0].Value)
+	assert.Equal(t, 10, l.comments[0].Pos)
+	assert.Equal(t, "/* the\n b field */", l.comments[1].Value)
+	assert.Equal(t, 29, l.comments[1].Pos)
+	assert.Equal(t, "--", l.comments[2].Value)
+}
+
+func TestLexerCommentsPositions(t *testing.T) {
+	l := lexerFromString("/* x */ a")
+	tok, err := l.NextToken()
+	require.Nil(t, err)
+	assert.Equal(t, 9, tok.Pos)
+}
+
+func TestLexerEdgeComments(t *testing.T) {
+	for _, s := range []string{"/**/", "/***/", "/* a **/", "--", "-- a\n--b"} {
+		assertNextTokens(t, lexerFromString(s), tokEnd)
+	}
+
+	assertNextTokens(t, lexerFromString("/*/ a */ b"), tokField, tokEnd)
+	assertNextTokens(t, lexerFromString("-1 - 2"), tokInt, tokMinus, tokInt, tokEnd)
+}
+
+func TestLexerArithmeticOperators(t *testing.T) {
+	assertNextTokens(t, lexerFromString("a + b - c"),
+		tokField, tokPlus, tokField, tokMinus, tokField, tokEnd)
+
+	// without whitespaces these are fields and numbers
+	assertNextTokens(t, lexerFromString("a-b +1 -c"), tokField, tokInt, tokField, tokEnd)
+
+	// unless they follow a closing parenthesis or quote
+	assertNextTokens(t, lexerFromString("NOW()-INTERVAL '1h'"),
+		tokField, tokLeftParenthesis, tokRightParenthesis, tokMinus, tokField, tokString, tokEnd)
+	assertNextTokens(t, lexerFromString("'a'+b `c`-1 (d)+-2"),
+		tokString, tokPlus, tokField, tokField, tokMinus, tokInt,
+		tokLeftParenthesis, tokField, tokRightParenthesis, tokPlus, tokInt, tokEnd)
+	assertNextTokens(t, lexerFromString("f() -1 'a' +b"),
+		tokField, tokLeftParenthesis, tokRightParenthesis, tokInt, tokString, tokField, tokEnd)
+}
+
+func TestLexerFieldPaths(t *testing.T) {
+	l := lexerFromString(`items[0].price = a["b.c"][1:2] AND x[*]`)
+	assertNextTokenValue(t, l, tokField, "items[0].price")
+	assertNextToken(t, l, tokEq)
+	assertNextTokenValue(t, l, tokField, `a["b.c"][1:2]`)
+	assertNextToken(t, l, tokAnd)
+	assertNextTokenValue(t, l, tokField, "x[*]")
+	assertNextToken(t, l, tokEnd)
+
+	// quoted closing brackets and spaces
+	assertNextTokenValue(t, lexerFromString(`a["]) x"] = 1`), tokField, `a["]) x"]`)
+
+	// brackets only follow words
+	_, err := lexerFromString("[0]").NextToken()
+	assert.NotNil(t, err)
+
+	for _, s := range []string{"a[0", `a["]`, `a["\`} {
+		_, err := lexerFromString(s).NextToken()
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestLexerUnterminatedComment(t *testing.T) {
+	l := lexerFromString("a /* b")
+	assertNextTokens(t, l, tokField)
+
+	_, err := l.NextToken()
+	assert.NotNil(t, err)
+}
+
+func TestLexerKeepComments(t *testing.T) {
+	l := lexerFromString("a /* b */ c")
+	l.keepComments = true
+	assertNextTokens(t, l, tokField, tokComment, tokField, tokEnd)
+}
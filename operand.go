@@ -183,3 +183,84 @@ func (rg *rangeTestOperation) Evaluate(record Record) (*Const, error) {
 func (rg *rangeTestOperation) String() string {
 	return fmt.Sprintf("%s BETWEEN %s AND %s", rg.test, rg.min, rg.max)
 }
+
+// rewriteOperand returns a copy of the given operand where each leaf (i.e. a
+// field, a constant or a parameter) is replaced by what the given function
+// returns for it. Composite operands are copied, the original tree is never
+// modified.
+func rewriteOperand(op operand, fn func(operand) (operand, error)) (operand, error) {
+	var err error
+
+	switch o := op.(type) {
+	case *comparison:
+		c := *o
+		if c.left, err = rewriteOperand(o.left, fn); err != nil {
+			return nil, err
+		}
+		if c.right, err = rewriteOperand(o.right, fn); err != nil {
+			return nil, err
+		}
+		return &c, nil
+
+	case *logicalOperation:
+		lo := *o
+		if lo.left, err = rewriteOperand(o.left, fn); err != nil {
+			return nil, err
+		}
+		if o.right != nil {
+			if lo.right, err = rewriteOperand(o.right, fn); err != nil {
+				return nil, err
+			}
+		}
+		return &lo, nil
+
+	case *rangeTestOperation:
+		rg := *o
+		if rg.test, err = rewriteOperand(o.test, fn); err != nil {
+			return nil, err
+		}
+		if rg.min, err = rewriteOperand(o.min, fn); err != nil {
+			return nil, err
+		}
+		if rg.max, err = rewriteOperand(o.max, fn); err != nil {
+			return nil, err
+		}
+		return &rg, nil
+
+	case *groupOperand:
+		g := *o
+		if g.operand, err = rewriteOperand(o.operand, fn); err != nil {
+			return nil, err
+		}
+		return &g, nil
+
+	case *arithmeticOperation:
+		ao := *o
+		if ao.left, err = rewriteOperand(o.left, fn); err != nil {
+			return nil, err
+		}
+		if ao.right, err = rewriteOperand(o.right, fn); err != nil {
+			return nil, err
+		}
+		return &ao, nil
+
+	case *functionCall:
+		args := make([]operand, len(o.args))
+		for i, arg := range o.args {
+			if args[i], err = rewriteOperand(arg, fn); err != nil {
+				return nil, err
+			}
+		}
+		// check the arguments again, they may be known now
+		return newFunctionCall(o.name, args)
+
+	case *quantifiedOperand:
+		q := *o
+		if q.operand, err = rewriteOperand(o.operand, fn); err != nil {
+			return nil, err
+		}
+		return &q, nil
+	}
+
+	return fn(op)
+}
@@ -3,7 +3,7 @@ package charlatan
 // operatorType is the type of an operator
 type operatorType int
 
-// operators can be either logical or comparison-al
+// operators can be either logical, comparison-al or arithmetic
 const (
 	operatorInvalid operatorType = iota
 
@@ -16,6 +16,9 @@ const (
 	operatorLte
 	operatorGt
 	operatorGte
+
+	operatorPlus
+	operatorMinus
 )
 
 // operatorTypeFromTokenType converts a TokenType to an operatorType
@@ -37,6 +40,10 @@ func operatorTypeFromTokenType(ty tokenType) operatorType {
 		return operatorGt
 	case tokGte:
 		return operatorGte
+	case tokPlus:
+		return operatorPlus
+	case tokMinus:
+		return operatorMinus
 	default:
 		return operatorInvalid
 	}
@@ -49,7 +56,12 @@ func (o operatorType) IsLogical() bool {
 
 // isComparison tests if an operator is a comparison
 func (o operatorType) isComparison() bool {
-	return !o.IsLogical() && o != operatorInvalid
+	return o >= operatorEq && o <= operatorGte
+}
+
+// isArithmetic tests if an operator is an arithmetic one
+func (o operatorType) isArithmetic() bool {
+	return o == operatorPlus || o == operatorMinus
 }
 
 func (o operatorType) String() string {
@@ -70,6 +82,10 @@ func (o operatorType) String() string {
 		return ">"
 	case operatorGte:
 		return ">="
+	case operatorPlus:
+		return "+"
+	case operatorMinus:
+		return "-"
 	default:
 		return "<unknown operator>"
 	}
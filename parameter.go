@@ -0,0 +1,30 @@
+package charlatan
+
+import "fmt"
+
+// parameter is a bind parameter, i.e. a placeholder for a constant which is
+// given when the query is bound. Positional parameters (?) have an empty name
+// and are numbered from 0, named ones (:name) have an index of -1.
+type parameter struct {
+	name  string
+	index int
+}
+
+var _ operand = &parameter{}
+
+// isPositional tests if the parameter is a positional one
+func (p *parameter) isPositional() bool {
+	return p.name == ""
+}
+
+// Evaluate always fails, the query must be bound before being evaluated
+func (p *parameter) Evaluate(Record) (*Const, error) {
+	return nil, fmt.Errorf("Unbound parameter %s", p)
+}
+
+func (p *parameter) String() string {
+	if p.isPositional() {
+		return "?"
+	}
+	return ":" + p.name
+}
@@ -3,6 +3,7 @@ package charlatan
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // the automate state
@@ -34,8 +35,15 @@ const (
 	rangeAnd
 	rangeMax
 
+	// a value of the WHERE clause, parsed by the value parser
+	value
+
 	clauseEnd
 	end
+
+	// only used in tolerant mode, when we're skipping tokens until we find
+	// one we can resynchronize on
+	recovering
 )
 
 // parser is the parser itself
@@ -56,6 +64,19 @@ type parser struct {
 	stack []*context
 	// and the current context
 	current *context
+
+	// the value being parsed, in the value state
+	value *valueParser
+
+	// the number of positional parameters found so far
+	positionalParameters int
+
+	// in tolerant mode, the errors are collected instead of aborting the
+	// parsing
+	tolerant bool
+	errors   []*SyntaxError
+	// the state in which the last error occured
+	recoverFrom state
 }
 
 // the context, that contains informations
@@ -105,72 +126,7 @@ func (p *parser) Parse() (*Query, error) {
 			return nil, err
 		}
 
-		switch p.state {
-
-		// the very begining
-		case initial:
-			p.state, err = p.initialState(tok)
-
-		// SELECT
-		case selectInitial:
-			p.state, err = p.selectState(tok)
-		case selectField:
-			p.state, err = p.selectFieldState(tok)
-
-		// FROM
-		case fromInitial:
-			p.state, err = p.fromState(tok)
-
-		// WHERE
-		case operationInitial:
-			p.state, err = p.operationState(tok)
-		case leftOperand:
-			p.state, err = p.operandLeftState(tok)
-		case operator:
-			p.state, err = p.operatorState(tok)
-		case rightOperand:
-			p.state, err = p.operandRightState(tok)
-
-		// range
-		case rangeMin:
-			p.state, err = p.rangeMin(tok)
-		case rangeAnd:
-			p.state, err = p.expect(tokAnd, tok, rangeMax)
-		case rangeMax:
-			p.state, err = p.rangeMax(tok)
-
-		// STARTING
-		case startingInitial:
-			p.state, err = p.expect(tokAt, tok, startingAt)
-		// AT
-		case startingAt:
-			p.state, err = p.startingAt(tok)
-
-		// LIMIT N
-		//       ^
-		case limitInitial:
-			p.state, err = p.limit(tok)
-
-		// LIMIT N, M
-		//        ^
-		case limitSep:
-			p.state, err = p.limitSep(tok)
-
-		// LIMIT N, M
-		//          ^
-		case limitMax:
-			p.state, err = p.limitMax(tok)
-
-		case clauseEnd:
-			p.state, err = p.clauseEnd(tok)
-
-		// unknown
-		default:
-			err = fmt.Errorf("Unknown state %d", p.state)
-		}
-
-		// an error occured during handling the state
-		if err != nil {
+		if err := p.step(tok); err != nil {
 			return nil, err
 		}
 	}
@@ -181,13 +137,169 @@ func (p *parser) Parse() (*Query, error) {
 		return nil, fmt.Errorf("Unbalanced parenthesis")
 	}
 
-	if p.current.first != nil {
+	p.setWhere()
+	p.setComments()
+
+	return p.query, nil
+}
+
+// ParseTolerant parses the query without stopping at the first syntax error.
+// Each error is recorded and the parser resynchronizes on the next clause
+// keyword or parenthesis. It always returns a query, possibly partial, along
+// with all the errors found.
+func (p *parser) ParseTolerant() (*Query, []*SyntaxError) {
+	p.tolerant = true
+
+	for p.state != end {
+		tok, err := p.lexer.NextToken()
+		if err != nil {
+			// the lexer already skipped the invalid input, we just have to
+			// wait for something we can resynchronize on
+			if se, ok := err.(*SyntaxError); ok {
+				p.errors = append(p.errors, se)
+			} else {
+				p.addError(p.lexer.index, err)
+			}
+			if p.state != recovering {
+				p.recoverFrom = p.state
+				p.state = recovering
+			}
+			continue
+		}
+
+		if err := p.step(tok); err != nil {
+			// should never happen in tolerant mode
+			p.addError(tok.Pos, err)
+			break
+		}
+	}
+
+	if len(p.stack) > 0 {
+		p.addError(p.lexer.index, errors.New("Unbalanced parenthesis"))
+		p.closeContexts()
+	}
+
+	p.ensureQuery()
+	p.setWhere()
+	p.setComments()
+
+	return p.query, p.errors
+}
+
+// step moves the automate by one token
+func (p *parser) step(tok *token) error {
+	next, err := p.transition(p.state, tok)
+
+	// an error occured during handling the state
+	if err != nil {
+		if !p.tolerant {
+			return err
+		}
+
+		p.addError(tok.Pos, err)
+		next = p.resync(tok)
+	}
+
+	p.state = next
+
+	return nil
+}
+
+// transition returns the state that follows the given one with the token
+func (p *parser) transition(s state, tok *token) (state, error) {
+	switch s {
+
+	// the very begining
+	case initial:
+		return p.initialState(tok)
+
+	// SELECT
+	case selectInitial:
+		return p.selectState(tok)
+	case selectField:
+		return p.selectFieldState(tok)
+
+	// FROM
+	case fromInitial:
+		return p.fromState(tok)
+
+	// WHERE
+	case operationInitial:
+		return p.operationState(tok)
+	case leftOperand:
+		return p.operandLeftState(tok)
+	case operator:
+		return p.operatorState(tok)
+	case rightOperand:
+		return p.operandRightState(tok)
+
+	// range
+	case rangeMin:
+		return p.rangeMin(tok)
+	case rangeAnd:
+		return p.expect(tokAnd, tok, rangeMax)
+	case rangeMax:
+		return p.rangeMax(tok)
+
+	// STARTING
+	case startingInitial:
+		return p.expect(tokAt, tok, startingAt)
+	// AT
+	case startingAt:
+		return p.startingAt(tok)
+
+	// LIMIT N
+	//       ^
+	case limitInitial:
+		return p.limit(tok)
+
+	// LIMIT N, M
+	//        ^
+	case limitSep:
+		return p.limitSep(tok)
+
+	// LIMIT N, M
+	//          ^
+	case limitMax:
+		return p.limitMax(tok)
+
+	case clauseEnd:
+		return p.clauseEnd(tok)
+
+	// a value, i.e. an operand, an arithmetic operation or a function call
+	case value:
+		return p.valueStep(tok)
+
+	// tolerant mode only: skipping tokens after an error
+	case recovering:
+		return p.resync(tok), nil
+	}
+
+	// unknown
+	return invalidState, fmt.Errorf("Unknown state %d", s)
+}
+
+// setWhere affects the parsed expression to the query
+func (p *parser) setWhere() {
+	if p.query != nil && p.current.first != nil {
 		// affect the logical node as the expression
 		// FIXME should we finish the operation ???
 		p.query.setWhere(p.current.first.simplify())
 	}
+}
+
+// setComments keeps the comments skipped by the lexer in the query
+func (p *parser) setComments() {
+	if p.query == nil {
+		return
+	}
 
-	return p.query, nil
+	for _, tok := range p.lexer.comments {
+		p.query.comments = append(p.query.comments, Comment{
+			Text: tok.Value,
+			Pos:  tok.Pos,
+		})
+	}
 }
 
 // We’re only waiting for the SELECT keyword
@@ -263,10 +375,13 @@ func (p *parser) clauseEnd(tok *token) (state, error) {
 	}
 }
 
-func tok2operand(tok *token) (operand, error) {
+func (p *parser) tok2operand(tok *token) (operand, error) {
 	if tok.isField() {
 		return NewField(tok.Value), nil
 	}
+	if tok.isParameter() {
+		return p.newParameter(tok), nil
+	}
 	if tok.isConst() {
 		return tok.Const()
 	}
@@ -281,14 +396,8 @@ func (p *parser) operationState(tok *token) (state, error) {
 		return operationInitial, nil
 	}
 
-	c, err := tok2operand(tok)
-	if err != nil {
-		return invalidState, err
-	}
-	p.current.left = c
-
-	// the left operand has been setted jump to the left operand state
-	return leftOperand, nil
+	// parse the left operand and jump to the left operand state
+	return p.startValue(&p.current.left, leftOperand, tok)
 }
 
 // We’re waiting for an operator:
@@ -299,20 +408,21 @@ func (p *parser) operationState(tok *token) (state, error) {
 // We can encounter a ), or the end
 func (p *parser) operandLeftState(tok *token) (state, error) {
 
-	if tok.isEnd() {
+	switch tok.Type {
+	case tokEnd, tokStarting, tokLimit:
 		// end the previous operation
 		if err := p.current.endOperation(); err != nil {
 			return invalidState, err
 		}
 
-		return end, nil
-	}
+		switch tok.Type {
+		case tokStarting:
+			return startingInitial, nil
+		case tokLimit:
+			return limitInitial, nil
+		}
 
-	switch tok.Type {
-	case tokStarting:
-		return startingInitial, nil
-	case tokLimit:
-		return limitInitial, nil
+		return end, nil
 	}
 
 	if tok.isLogicalOperator() {
@@ -353,33 +463,19 @@ func (p *parser) operandLeftState(tok *token) (state, error) {
 }
 
 func (p *parser) rangeMin(tok *token) (state, error) {
-	c, err := tok2operand(tok)
-	if err != nil {
-		return invalidState, err
-	}
-
 	if p.current.rangeTest == nil {
 		return invalidState, errors.New("Nil range test")
 	}
 
-	p.current.rangeTest.min = c
-
-	return rangeAnd, nil
+	return p.startValue(&p.current.rangeTest.min, rangeAnd, tok)
 }
 
 func (p *parser) rangeMax(tok *token) (state, error) {
-	c, err := tok2operand(tok)
-	if err != nil {
-		return invalidState, err
-	}
-
 	if p.current.rangeTest == nil {
 		return invalidState, errors.New("Nil range test")
 	}
 
-	p.current.rangeTest.max = c
-
-	return rightOperand, nil
+	return p.startValue(&p.current.rangeTest.max, rightOperand, tok)
 }
 
 // We're waiting for a right operand, nothing else, or a (
@@ -393,20 +489,8 @@ func (p *parser) operatorState(tok *token) (state, error) {
 		return operationInitial, nil
 	}
 
-	if tok.isField() {
-		p.current.right = NewField(tok.Value)
-	} else if tok.isConst() {
-		c, err := tok.Const()
-		if err != nil {
-			return invalidState, err
-		}
-		p.current.right = c
-	} else {
-		return unexpected(tok, tokInvalid)
-	}
-
-	// the right operand has been setted jump to the left operand state
-	return rightOperand, nil
+	// parse the right operand and jump to the right operand state
+	return p.startValue(&p.current.right, rightOperand, tok)
 }
 
 // We're waiting for a logical operator, ), or the end
@@ -506,6 +590,259 @@ func (p *parser) limitMax(tok *token) (state, error) {
 	return unexpected(tok, tokInt)
 }
 
+// Values
+
+// valueState is the state of the value parser
+type valueState int
+
+const (
+	// we're waiting for a term: an operand or a function call
+	valueTerm valueState = iota
+	// we got a word, which is either a field, a function name or the type of
+	// a literal
+	valueWord
+	// we got a term, we're waiting for an arithmetic operator or the end of
+	// the value
+	valueNext
+)
+
+// the types of the literals, e.g. TIMESTAMP '2016-12-28T10:00:00Z'
+const (
+	timestampLiteral = "TIMESTAMP"
+	intervalLiteral  = "INTERVAL"
+)
+
+// valueParser parses the values of the WHERE clause: operands, arithmetic
+// operations and function calls. The parser delegates to it in the value
+// state. Since the end of a value is only known when we get the token after
+// it, this token is then handled by the state that follows the value.
+type valueParser struct {
+	state valueState
+	// the word in the valueWord state
+	word *token
+	// the function calls being parsed, the first frame is the value itself
+	frames []*valueFrame
+	// where the value goes once parsed, and the state to continue from
+	target *operand
+	next   state
+}
+
+// valueFrame is a value or a function call being parsed
+type valueFrame struct {
+	// the function name, empty for the value itself
+	function string
+	// the arguments parsed so far
+	args []operand
+	// the current expression and its pending arithmetic operator
+	expr     operand
+	operator tokenType
+}
+
+// startValue starts parsing a value with the given token
+func (p *parser) startValue(target *operand, next state, tok *token) (state, error) {
+	p.value = &valueParser{
+		state:  valueTerm,
+		frames: []*valueFrame{&valueFrame{operator: tokInvalid}},
+		target: target,
+		next:   next,
+	}
+
+	return p.valueStep(tok)
+}
+
+// valueStep moves the value parser by one token
+func (p *parser) valueStep(tok *token) (state, error) {
+	v := p.value
+	frame := v.frame()
+
+	switch v.state {
+	case valueTerm:
+		// a call without arguments
+		if tok.Type == tokRightParenthesis && frame.function != "" &&
+			len(frame.args) == 0 && frame.expr == nil {
+			return p.closeFunction()
+		}
+
+		if tok.isField() {
+			v.word = tok
+			v.state = valueWord
+			return value, nil
+		}
+
+		if !tok.isParameter() && !tok.isConst() {
+			return unexpected(tok, tokInvalid)
+		}
+
+		op, err := p.tok2operand(tok)
+		if err != nil {
+			return invalidState, err
+		}
+
+		return value, v.addTerm(op)
+
+	case valueWord:
+		word := v.word
+		v.word = nil
+
+		if tok.Type == tokLeftParenthesis {
+			if !isFunction(word.Value) && !isQuantifier(word.Value) {
+				return invalidState, fmt.Errorf(
+					"Unknown function %s at position %d", word.Value, word.Pos)
+			}
+
+			v.frames = append(v.frames, &valueFrame{
+				function: strings.ToUpper(word.Value),
+				operator: tokInvalid,
+			})
+			v.state = valueTerm
+			return value, nil
+		}
+
+		if tok.Type == tokString {
+			switch strings.ToUpper(word.Value) {
+			case timestampLiteral:
+				t, err := ParseTimestamp(tok.Value)
+				if err != nil {
+					return invalidState, fmt.Errorf("%s at position %d", err, tok.Pos)
+				}
+				return value, v.addTerm(TimestampConst(t))
+
+			case intervalLiteral:
+				d, err := ParseDuration(tok.Value)
+				if err != nil {
+					return invalidState, fmt.Errorf("%s at position %d", err, tok.Pos)
+				}
+				return value, v.addTerm(DurationConst(d))
+			}
+		}
+
+		// it's a field, the token comes after it
+		if err := v.addTerm(NewField(word.Value)); err != nil {
+			return invalidState, err
+		}
+
+		return p.valueStep(tok)
+
+	case valueNext:
+		if tok.isArithmeticOperator() {
+			if _, ok := frame.expr.(*quantifiedOperand); ok {
+				return invalidState, fmt.Errorf(
+					"Unexpected %s after %s at position %d", tok.Value, frame.expr, tok.Pos)
+			}
+			frame.operator = tok.Type
+			v.state = valueTerm
+			return value, nil
+		}
+
+		// the end of the value
+		if frame.function == "" {
+			*v.target = frame.expr
+			p.value = nil
+			return p.transition(v.next, tok)
+		}
+
+		switch tok.Type {
+		case tokComma:
+			frame.args = append(frame.args, frame.expr)
+			frame.expr = nil
+			v.state = valueTerm
+			return value, nil
+
+		case tokFrom:
+			// EXTRACT(unit FROM timestamp)
+			if frame.function == "EXTRACT" && len(frame.args) == 0 {
+				frame.args = append(frame.args, frame.expr)
+				frame.expr = nil
+				v.state = valueTerm
+				return value, nil
+			}
+
+		case tokRightParenthesis:
+			return p.closeFunction()
+		}
+
+		return unexpected(tok, tokRightParenthesis)
+	}
+
+	return invalidState, fmt.Errorf("Unknown value state %d", v.state)
+}
+
+// closeFunction ends the current function call, which becomes a term of the
+// enclosing frame
+func (p *parser) closeFunction() (state, error) {
+	v := p.value
+	frame := v.frame()
+
+	if frame.expr != nil {
+		frame.args = append(frame.args, frame.expr)
+	}
+
+	v.frames = v.frames[:len(v.frames)-1]
+
+	if isQuantifier(frame.function) {
+		// ANY(...) and ALL(...) are the whole right operand of a comparison
+		if len(v.frames) > 1 || v.frame().expr != nil || v.target != &p.current.right {
+			return invalidState, fmt.Errorf(
+				"%s can only be the right operand of a comparison", frame.function)
+		}
+
+		q, err := newQuantifiedOperand(frame.function, frame.args)
+		if err != nil {
+			return invalidState, err
+		}
+
+		return value, v.addTerm(q)
+	}
+
+	f, err := newFunctionCall(frame.function, frame.args)
+	if err != nil {
+		return invalidState, err
+	}
+
+	return value, v.addTerm(f)
+}
+
+// frame returns the frame being parsed
+func (v *valueParser) frame() *valueFrame {
+	return v.frames[len(v.frames)-1]
+}
+
+// addTerm adds a term to the current frame, chaining it with the previous
+// one if there's an arithmetic operator
+func (v *valueParser) addTerm(op operand) error {
+	frame := v.frame()
+	v.state = valueNext
+
+	if frame.operator == tokInvalid {
+		frame.expr = op
+		return nil
+	}
+
+	ao, err := newArithmeticOperation(frame.expr,
+		operatorTypeFromTokenType(frame.operator), op)
+	if err != nil {
+		return err
+	}
+
+	frame.expr = ao
+	frame.operator = tokInvalid
+
+	return nil
+}
+
+// Creates a parameter operand, positional ones are numbered in the order they
+// appear in the query
+func (p *parser) newParameter(tok *token) *parameter {
+	param := &parameter{name: tok.Value, index: -1}
+
+	if param.name == "" {
+		param.index = p.positionalParameters
+		p.positionalParameters++
+	}
+
+	return param
+}
+
 func (p *parser) expect(expected tokenType, tok *token, state state) (state, error) {
 	if tok.Type != expected {
 		return unexpected(tok, expected)
@@ -526,11 +863,21 @@ func (p *parser) pushContext(state state) {
 // End the current operation, and continue to the correct state (given on push)
 func (p *parser) popContext() (state, error) {
 
+	if len(p.stack) == 0 {
+		return invalidState, errors.New("Unbalanced parenthesis")
+	}
+
 	// first we end the operation
 	if err := p.current.endOperation(); err != nil {
 		return invalidState, err
 	}
 
+	return p.closeGroup()
+}
+
+// Wrap the current context into a group and pop it
+func (p *parser) closeGroup() (state, error) {
+
 	// creates a group operand
 	g, err := newGroupOperand(p.current.first.simplify())
 	if err != nil {
@@ -642,6 +989,149 @@ func (c *context) endOperation() error {
 	return nil
 }
 
+// Tolerant mode
+
+// SyntaxError is an error found while parsing a query
+type SyntaxError struct {
+	// the position of the token on which the error occured
+	Pos int
+	// the error message
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Msg
+}
+
+// addError records an error in tolerant mode
+func (p *parser) addError(pos int, err error) {
+	p.errors = append(p.errors, &SyntaxError{Pos: pos, Msg: err.Error()})
+}
+
+// resync returns the state the parser should continue from after an error
+// occured on the given token. We only resynchronize on clause keywords and
+// parenthesis, everything else is skipped in the recovering state.
+func (p *parser) resync(tok *token) state {
+
+	if p.state != recovering {
+		p.recoverFrom = p.state
+	}
+
+	inExpression := isExpressionState(p.recoverFrom)
+
+	switch tok.Type {
+	case tokEnd:
+		p.closeContexts()
+		return end
+
+	case tokSelect:
+		if p.query == nil {
+			return selectInitial
+		}
+
+	case tokFrom:
+		if p.query == nil {
+			return fromInitial
+		}
+
+	case tokWhere, tokStarting, tokLimit:
+		p.closeContexts()
+		p.ensureQuery()
+
+		if tok.Type == tokWhere {
+			// keep what we already parsed, the new WHERE overrides it
+			p.setWhere()
+			p.current = newContext()
+		}
+
+		s, _ := p.clauseEnd(tok)
+		return s
+
+	case tokLeftParenthesis:
+		// the new group can only be chained if there's nothing before it,
+		// or if we already have a logical operator
+		if inExpression && (p.current.first == nil || p.current.logicalOperator != tokInvalid) {
+			p.current.discardOperation()
+			p.pushContext(leftOperand)
+			return operationInitial
+		}
+
+	case tokRightParenthesis:
+		if inExpression && len(p.stack) > 0 {
+			return p.closeContext()
+		}
+	}
+
+	return recovering
+}
+
+// closeContext closes the current parenthesis, keeping what has been
+// successfully parsed in it
+func (p *parser) closeContext() state {
+	p.current.discardOperation()
+
+	if p.current.first == nil {
+		// nothing valid in this group, the operation that contains it can't
+		// be used either
+		l := len(p.stack)
+		p.current = p.stack[l-1]
+		p.stack = p.stack[:l-1]
+		p.current.stackState = invalidState
+		p.current.discardOperation()
+
+		return recovering
+	}
+
+	s, err := p.closeGroup()
+	if err != nil {
+		return recovering
+	}
+
+	return s
+}
+
+// closeContexts closes all the opened parenthesis
+func (p *parser) closeContexts() {
+	p.current.discardOperation()
+
+	for len(p.stack) > 0 {
+		if p.closeContext() == recovering {
+			continue
+		}
+
+		if err := p.current.endOperation(); err != nil {
+			p.current.discardOperation()
+		}
+	}
+}
+
+// ensureQuery creates an empty query if we didn't reach the FROM clause
+func (p *parser) ensureQuery() {
+	if p.query == nil {
+		p.query = NewQuery("")
+		p.query.AddFields(p.fields)
+		p.fields = nil
+	}
+}
+
+// isExpressionState tests if the state is one of the WHERE clause
+func isExpressionState(s state) bool {
+	switch s {
+	case operationInitial, leftOperand, operator, rightOperand,
+		rangeMin, rangeAnd, rangeMax, value:
+		return true
+	}
+	return false
+}
+
+// Drop the current operation, if any
+func (c *context) discardOperation() {
+	c.left = nil
+	c.right = nil
+	c.operator = tokInvalid
+	c.rangeTest = nil
+}
+
 // Helper to creates the unexpected error
 func unexpected(tok *token, expected tokenType) (state, error) {
 
@@ -660,9 +1150,9 @@ func unexpected(tok *token, expected tokenType) (state, error) {
 
 	if tok.isEnd() {
 		return invalidState, fmt.Errorf(
-			"Unexpected end of stream at pos %d", tok.Pos)
+			"Unexpected end of stream at position %d", tok.Pos)
 	}
 
 	return invalidState, fmt.Errorf(
-		"Unexpected '%s' at pos %d", tok.Value, tok.Pos)
+		"Unexpected '%s' at position %d", tok.Value, tok.Pos)
 }
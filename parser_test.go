@@ -3,6 +3,7 @@ package charlatan
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,3 +44,175 @@ func TestParserParseLimitWithOffset(t *testing.T) {
 		okQuery(t, s)
 	}
 }
+
+func TestParserParseUnbalancedParenthesis(t *testing.T) {
+	for _, s := range []string{
+		"SELECT x FROM y WHERE (a = 1",
+		"SELECT x FROM y WHERE a = 1)",
+		"SELECT x FROM y WHERE (a = 1))",
+	} {
+		_, err := parserFromString(s).Parse()
+		require.NotNil(t, err, "There should be an error parsing '%s'", s)
+	}
+}
+
+func tolerantQuery(t *testing.T, q string) (*Query, []*SyntaxError) {
+	qry, errs := parserFromString(q).ParseTolerant()
+	require.NotNil(t, qry, "The query '%s' should never be nil", q)
+	return qry, errs
+}
+
+func TestParserParseTolerantValidQuery(t *testing.T) {
+	q, errs := tolerantQuery(t, "SELECT x, y FROM z WHERE (a = 1 || b) && c LIMIT 2, 3")
+	assert.Empty(t, errs)
+	assert.Equal(t, "z", q.From())
+	assert.Equal(t, 2, len(q.Fields()))
+	assert.Equal(t, "(a = 1 OR b) AND c", q.expression.String())
+	assert.Equal(t, int64(2), q.StartingAt())
+	assert.Equal(t, int64(3), q.Limit())
+}
+
+func TestParserParseTolerantResyncOnClauses(t *testing.T) {
+	q, errs := tolerantQuery(t, "SELECT x FROM y WHERE a = LIMIT , STARTING AT 3")
+	require.Equal(t, 2, len(errs))
+	assert.Equal(t, 27, errs[0].Pos)
+	assert.Equal(t, 33, errs[1].Pos)
+
+	assert.Equal(t, "y", q.From())
+	assert.Nil(t, q.expression)
+	assert.Equal(t, int64(3), q.StartingAt())
+	assert.False(t, q.HasLimit())
+}
+
+func TestParserParseTolerantKeepsValidOperations(t *testing.T) {
+	q, errs := tolerantQuery(t, "SELECT x FROM y WHERE a = 1 AND b = LIMIT 4")
+	assert.Equal(t, 1, len(errs))
+	require.NotNil(t, q.expression)
+	assert.Equal(t, "a = 1", q.expression.String())
+	assert.Equal(t, int64(4), q.Limit())
+}
+
+func TestParserParseTolerantResyncOnParenthesis(t *testing.T) {
+	q, errs := tolerantQuery(t, "SELECT x FROM y WHERE (a = = 2) OR (b > 3)")
+	assert.Equal(t, 1, len(errs))
+	require.NotNil(t, q.expression)
+	assert.Equal(t, "(b > 3)", q.expression.String())
+
+	q, errs = tolerantQuery(t, "SELECT x FROM y WHERE (a = 2 b) OR (b > 3)")
+	assert.Equal(t, 1, len(errs))
+	require.NotNil(t, q.expression)
+	assert.Equal(t, "(a = 2) OR (b > 3)", q.expression.String())
+}
+
+func TestParserParseTolerantUnbalancedParenthesis(t *testing.T) {
+	q, errs := tolerantQuery(t, "SELECT x FROM y WHERE (a = 1 AND (b = 2")
+	assert.Equal(t, 1, len(errs))
+	require.NotNil(t, q.expression)
+	assert.Equal(t, "(a = 1 AND (b = 2))", q.expression.String())
+
+	_, errs = tolerantQuery(t, "SELECT x FROM y WHERE a = 1) LIMIT 2")
+	assert.Equal(t, 1, len(errs))
+}
+
+func TestParserParseTolerantErrorPositions(t *testing.T) {
+	_, errs := tolerantQuery(t, `SELECT a FROM b WHERE c = "abc`)
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, 27, errs[0].Pos)
+	assert.Equal(t, `Unterminated " at position 27`, errs[0].Msg)
+
+	_, errs = tolerantQuery(t, "SELECT a FROM b WHERE )")
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, 23, errs[0].Pos)
+	assert.Equal(t, "Unexpected ')' at position 23", errs[0].Msg)
+}
+
+func TestParserParseTolerantIncompleteQueries(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"SELECT",
+		"SELECT a,",
+		"SELECT a FROM",
+		"SELECT a FROM b WHERE",
+		"SELECT a FROM b WHERE (",
+		"SELECT a FROM b WHERE x BETWEEN",
+		"SELECT a FROM b WHERE x BETWEEN 1 AND",
+		"SELECT a FROM b WHERE x = 'foo",
+		"SELECT a FROM b WHERE x [ 2",
+		"SELECT a FROM b LIMIT",
+		"SELECT a FROM b STARTING",
+	} {
+		_, errs := tolerantQuery(t, s)
+		assert.NotEmpty(t, errs, "There should be errors parsing '%s'", s)
+	}
+}
+
+func TestParserParseTolerantMissingFrom(t *testing.T) {
+	q, errs := tolerantQuery(t, "SELECT a, b WHERE c = 2")
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "", q.From())
+	assert.Equal(t, 2, len(q.Fields()))
+	require.NotNil(t, q.expression)
+	assert.Equal(t, "c = 2", q.expression.String())
+}
+
+func TestParserParseValues(t *testing.T) {
+	for s, expected := range map[string]string{
+		"SELECT x FROM y WHERE ts > NOW() - INTERVAL '1h'":                    `ts > NOW() - INTERVAL "1h0m0s"`,
+		"SELECT x FROM y WHERE ts > NOW()-INTERVAL '1h'":                      `ts > NOW() - INTERVAL "1h0m0s"`,
+		"SELECT x FROM y WHERE ts < TIMESTAMP '2016-12-28'+INTERVAL '1d'":     `ts < TIMESTAMP "2016-12-28T00:00:00Z" + INTERVAL "24h0m0s"`,
+		"SELECT x FROM y WHERE ts >= TIMESTAMP '2016-12-28T10:00:00Z'":        `ts >= TIMESTAMP "2016-12-28T10:00:00Z"`,
+		"SELECT x FROM y WHERE a + 1 - b = 2":                                 "a + 1 - b = 2",
+		"SELECT x FROM y WHERE date_trunc('day', ts) = '2016-12-28'":          `DATE_TRUNC("day", ts) = "2016-12-28"`,
+		"SELECT x FROM y WHERE EXTRACT(hour FROM ts) BETWEEN 9 AND 17":        "EXTRACT(hour FROM ts) BETWEEN 9 AND 17",
+		"SELECT x FROM y WHERE STRFTIME('%H', TIMEZONE('+02:00', ts)) = '10'": `STRFTIME("%H", TIMEZONE("+02:00", ts)) = "10"`,
+		"SELECT x FROM y WHERE ts BETWEEN NOW() - INTERVAL '1d' AND NOW()":    `ts BETWEEN NOW() - INTERVAL "24h0m0s" AND NOW()`,
+		"SELECT x FROM y WHERE (a - 1 = 2) AND f(x)":                          "",
+		"SELECT x FROM y WHERE timestamp = 2":                                 "timestamp = 2",
+		"SELECT x FROM y WHERE ts > ? - :d":                                   "ts > ? - :d",
+		"SELECT x FROM y WHERE 'a' = any(tags) AND 2 < ALL(scores)":           `"a" = ANY(tags) AND 2 < ALL(scores)`,
+		"SELECT x FROM y WHERE LENGTH(tags) > 1 AND CONTAINS(tags, 'a')":      `LENGTH(tags) > 1 AND CONTAINS(tags, "a")`,
+	} {
+		q, err := parserFromString(s).Parse()
+
+		if expected == "" {
+			assert.NotNil(t, err, s)
+			continue
+		}
+
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, expected, q.expression.String(), s)
+		}
+	}
+}
+
+func TestParserParseValuesErrors(t *testing.T) {
+	for _, s := range []string{
+		"SELECT x FROM y WHERE ts > NOW(",
+		"SELECT x FROM y WHERE ts > NOW(1)",
+		"SELECT x FROM y WHERE ts > TIMESTAMP 'yesterday'",
+		"SELECT x FROM y WHERE ts > INTERVAL '1 hour'",
+		"SELECT x FROM y WHERE ts > NOW() -",
+		"SELECT x FROM y WHERE a + = 2",
+		"SELECT x FROM y WHERE DATE_TRUNC('day' ts) = 2",
+		"SELECT x FROM y WHERE DATE_TRUNC('day' FROM ts) = 2",
+		"SELECT x FROM y WHERE UNKNOWN(ts) = 2",
+		"SELECT x FROM y WHERE EXTRACT(hour FROM ts FROM ts) = 2",
+		"SELECT x FROM y WHERE ANY(tags) = 'a'",
+		"SELECT x FROM y WHERE ANY(tags)",
+		"SELECT x FROM y WHERE a = ANY(tags) + 1",
+		"SELECT x FROM y WHERE a = 1 + ANY(tags)",
+		"SELECT x FROM y WHERE a = LENGTH(ANY(tags))",
+		"SELECT x FROM y WHERE a = ANY(tags, scores)",
+		"SELECT x FROM y WHERE a BETWEEN ANY(tags) AND 2",
+	} {
+		_, err := parserFromString(s).Parse()
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestParserParseTolerantValues(t *testing.T) {
+	q, errs := tolerantQuery(t, "SELECT x FROM y WHERE NOW( AND a = 1 LIMIT 2")
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, "y", q.From())
+	assert.Equal(t, int64(2), q.Limit())
+}
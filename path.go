@@ -0,0 +1,332 @@
+package charlatan
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegmentType is the type of a segment of a field path
+type pathSegmentType int
+
+const (
+	// a key of an object: a.b or a["b"]
+	keySegment pathSegmentType = iota
+	// an element of an array, negative indexes start from the end: a[0]
+	indexSegment
+	// a part of an array, as in Python: a[1:3], a[:2] or a[-2:]
+	sliceSegment
+	// all the elements of an array or all the members of an object: a[*] or
+	// a.*
+	wildcardSegment
+)
+
+// PathSegment is a segment of a field path
+type PathSegment struct {
+	segmentType pathSegmentType
+	key         string
+	index       int
+	// the bounds of a slice, which are optional
+	start, end       int
+	hasStart, hasEnd bool
+}
+
+// FieldPath is the compiled path of a field, e.g. items[0].price. Paths are
+// made of keys separated by dots, and of brackets which contain either an
+// index, a slice, a wildcard (*) or a quoted key, e.g. a["b.c"].
+type FieldPath []PathSegment
+
+// ParseFieldPath parses the path of a field
+func ParseFieldPath(s string) (FieldPath, error) {
+	var path FieldPath
+
+	runes := []rune(s)
+	i := 0
+
+	if len(runes) == 0 {
+		return nil, fmt.Errorf("Empty field path")
+	}
+
+	for i < len(runes) {
+		switch {
+		case runes[i] == '[':
+			end := closingBracket(runes, i)
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated [ at position %d in %s", i+1, s)
+			}
+
+			seg, err := parseBracket(string(runes[i+1 : end]))
+			if err != nil {
+				return nil, fmt.Errorf("%s at position %d in %s", err, i+1, s)
+			}
+
+			path = append(path, seg)
+			i = end + 1
+
+		case i > 0 && runes[i] != '.':
+			return nil, fmt.Errorf("Unexpected %c at position %d in %s", runes[i], i+1, s)
+
+		default:
+			// a key, after a dot unless it's the first one
+			if i > 0 {
+				i++
+			}
+
+			start := i
+			for i < len(runes) && runes[i] != '.' && runes[i] != '[' {
+				i++
+			}
+
+			key := string(runes[start:i])
+			if key == "" {
+				return nil, fmt.Errorf("Empty key at position %d in %s", start+1, s)
+			}
+
+			if key == "*" {
+				path = append(path, PathSegment{segmentType: wildcardSegment})
+			} else {
+				path = append(path, PathSegment{segmentType: keySegment, key: key})
+			}
+		}
+	}
+
+	return path, nil
+}
+
+// closingBracket returns the index of the bracket which closes the one at the
+// given index, skipping quoted keys, or -1 if there's none
+func closingBracket(runes []rune, open int) int {
+	var quote rune
+
+	for i := open + 1; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case quote != 0 && r == '\\':
+			i++
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ']':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseBracket parses the content of brackets
+func parseBracket(s string) (PathSegment, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "*" {
+		return PathSegment{segmentType: wildcardSegment}, nil
+	}
+
+	// a quoted key
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		key, err := unquoteKey(s[1:len(s)-1], s[0])
+		if err != nil {
+			return PathSegment{}, err
+		}
+		return PathSegment{segmentType: keySegment, key: key}, nil
+	}
+
+	if colon := strings.IndexByte(s, ':'); colon >= 0 {
+		seg := PathSegment{segmentType: sliceSegment}
+		var err error
+
+		if bound := strings.TrimSpace(s[:colon]); bound != "" {
+			seg.hasStart = true
+			if seg.start, err = strconv.Atoi(bound); err != nil {
+				return PathSegment{}, fmt.Errorf("Invalid slice [%s]", s)
+			}
+		}
+
+		if bound := strings.TrimSpace(s[colon+1:]); bound != "" {
+			seg.hasEnd = true
+			if seg.end, err = strconv.Atoi(bound); err != nil {
+				return PathSegment{}, fmt.Errorf("Invalid slice [%s]", s)
+			}
+		}
+
+		return seg, nil
+	}
+
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return PathSegment{}, fmt.Errorf("Invalid index [%s]", s)
+	}
+
+	return PathSegment{segmentType: indexSegment, index: index}, nil
+}
+
+// unquoteKey unquotes a quoted key, in which backslashes escape the next
+// character
+func unquoteKey(s string, quote byte) (string, error) {
+	var buf bytes.Buffer
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", fmt.Errorf("Invalid escape in key %s", s)
+			}
+		case quote:
+			return "", fmt.Errorf("Unescaped %c in key %s", quote, s)
+		}
+		buf.WriteByte(s[i])
+	}
+
+	return buf.String(), nil
+}
+
+// keysPath returns a path made of the keys separated by dots in the string
+func keysPath(s string) FieldPath {
+	var path FieldPath
+
+	for _, key := range strings.Split(s, ".") {
+		path = append(path, PathSegment{segmentType: keySegment, key: key})
+	}
+
+	return path
+}
+
+// Key returns the key of the segment, the boolean is false if it's not a key
+// segment
+func (seg PathSegment) Key() (string, bool) {
+	return seg.key, seg.segmentType == keySegment
+}
+
+// IsMultiple tests if the segment can select several values, i.e. if it's a
+// slice or a wildcard
+func (seg PathSegment) IsMultiple() bool {
+	return seg.segmentType == sliceSegment || seg.segmentType == wildcardSegment
+}
+
+// Indexes returns the indexes of the elements the segment selects in an array
+// of the given length. It's empty if there's none, e.g. if the index is out of
+// range or if the segment is a key.
+func (seg PathSegment) Indexes(length int) []int {
+	var start, end int
+
+	switch seg.segmentType {
+	case indexSegment:
+		index := seg.index
+		if index < 0 {
+			index += length
+		}
+		if index < 0 || index >= length {
+			return nil
+		}
+		return []int{index}
+
+	case sliceSegment:
+		start, end = 0, length
+		if seg.hasStart {
+			start = sliceBound(seg.start, length)
+		}
+		if seg.hasEnd {
+			end = sliceBound(seg.end, length)
+		}
+
+	case wildcardSegment:
+		start, end = 0, length
+
+	default:
+		return nil
+	}
+
+	var indexes []int
+	for i := start; i < end; i++ {
+		indexes = append(indexes, i)
+	}
+
+	return indexes
+}
+
+// sliceBound returns the bound of a slice in an array of the given length:
+// negative bounds start from the end, and bounds are clamped to the array
+func sliceBound(bound, length int) int {
+	if bound < 0 {
+		bound += length
+	}
+	if bound < 0 {
+		return 0
+	}
+	if bound > length {
+		return length
+	}
+	return bound
+}
+
+// Lookup returns the value at the path in the given value. The boolean is
+// false if there's none. Slices and wildcards return arrays, in which the
+// elements without a value at the rest of the path are left out. Wildcards
+// on objects return the members sorted by key.
+func (p FieldPath) Lookup(c *Const) (*Const, bool) {
+	return p.lookup(c, false)
+}
+
+// LookupRepeated is like Lookup, except that index and wildcard segments
+// treat the values which aren't arrays as one-element arrays. It suits the
+// formats where an element which may be repeated isn't an array when it's
+// alone, e.g. XML: "item[0].price" and "item[*].price" then work whether
+// there are one or many items.
+func (p FieldPath) LookupRepeated(c *Const) (*Const, bool) {
+	return p.lookup(c, true)
+}
+
+func (p FieldPath) lookup(c *Const, repeated bool) (*Const, bool) {
+	if len(p) == 0 {
+		return c, true
+	}
+
+	seg, rest := p[0], p[1:]
+
+	if key, ok := seg.Key(); ok {
+		member, ok := c.objectValue[key]
+		if !ok || !c.IsObject() {
+			return nil, false
+		}
+		return rest.lookup(member, repeated)
+	}
+
+	var values []*Const
+
+	switch {
+	case c.IsArray():
+		for _, i := range seg.Indexes(len(c.arrayValue)) {
+			values = append(values, c.arrayValue[i])
+		}
+	case repeated:
+		for range seg.Indexes(1) {
+			values = append(values, c)
+		}
+	case c.IsObject() && seg.segmentType == wildcardSegment:
+		for _, key := range sortedKeys(c.objectValue) {
+			values = append(values, c.objectValue[key])
+		}
+	default:
+		return nil, false
+	}
+
+	if !seg.IsMultiple() {
+		if len(values) == 0 {
+			return nil, false
+		}
+		return rest.lookup(values[0], repeated)
+	}
+
+	results := []*Const{}
+
+	for _, value := range values {
+		if result, ok := rest.lookup(value, repeated); ok {
+			results = append(results, result)
+		}
+	}
+
+	return ArrayConst(results), true
+}
@@ -0,0 +1,163 @@
+package charlatan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	for s, expected := range map[string]FieldPath{
+		"a": {{segmentType: keySegment, key: "a"}},
+		"a.b": {
+			{segmentType: keySegment, key: "a"},
+			{segmentType: keySegment, key: "b"},
+		},
+		"a[0][-1]": {
+			{segmentType: keySegment, key: "a"},
+			{segmentType: indexSegment, index: 0},
+			{segmentType: indexSegment, index: -1},
+		},
+		"a[1:3].b": {
+			{segmentType: keySegment, key: "a"},
+			{segmentType: sliceSegment, start: 1, end: 3, hasStart: true, hasEnd: true},
+			{segmentType: keySegment, key: "b"},
+		},
+		"a[:-1]": {
+			{segmentType: keySegment, key: "a"},
+			{segmentType: sliceSegment, end: -1, hasEnd: true},
+		},
+		"a[ 2: ]": {
+			{segmentType: keySegment, key: "a"},
+			{segmentType: sliceSegment, start: 2, hasStart: true},
+		},
+		"a[*].*": {
+			{segmentType: keySegment, key: "a"},
+			{segmentType: wildcardSegment},
+			{segmentType: wildcardSegment},
+		},
+		`a["b.c"]['d\'e']`: {
+			{segmentType: keySegment, key: "a"},
+			{segmentType: keySegment, key: "b.c"},
+			{segmentType: keySegment, key: "d'e"},
+		},
+		"[0]":         {{segmentType: indexSegment, index: 0}},
+		"$1":          {{segmentType: keySegment, key: "$1"}},
+		"data/f.csv":  {{segmentType: keySegment, key: "data/f"}, {segmentType: keySegment, key: "csv"}},
+		"with space":  {{segmentType: keySegment, key: "with space"}},
+		`a["]"].b[0]`: {{segmentType: keySegment, key: "a"}, {segmentType: keySegment, key: "]"}, {segmentType: keySegment, key: "b"}, {segmentType: indexSegment}},
+	} {
+		path, err := ParseFieldPath(s)
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, expected, path, s)
+		}
+	}
+
+	for _, s := range []string{
+		"", ".a", "a.", "a..b", "a.[0]", "a[0]b", "a[", "a[]", "a[x]", "a[1:x]",
+		`a["b]`, `a["b"c"]`, `a["b\"]`,
+	} {
+		_, err := ParseFieldPath(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestPathSegmentIndexes(t *testing.T) {
+	for s, expected := range map[string][]int{
+		"[0]":    {0},
+		"[3]":    {3},
+		"[-1]":   {3},
+		"[4]":    nil,
+		"[-5]":   nil,
+		"[1:3]":  {1, 2},
+		"[:2]":   {0, 1},
+		"[-2:]":  {2, 3},
+		"[2:1]":  nil,
+		"[-9:9]": {0, 1, 2, 3},
+		"[*]":    {0, 1, 2, 3},
+		"a":      nil,
+	} {
+		path, err := ParseFieldPath(s)
+		require.Nil(t, err, s)
+		assert.Equal(t, expected, path[0].Indexes(4), s)
+	}
+}
+
+func TestFieldPathLookup(t *testing.T) {
+	doc := object(map[string]interface{}{
+		"name": "a",
+		"items": []interface{}{
+			map[string]interface{}{"price": 1, "tags": []interface{}{"x", "y"}},
+			map[string]interface{}{"price": 2},
+			map[string]interface{}{"tags": []interface{}{}},
+		},
+		"a.b": map[string]interface{}{"c": 42},
+	})
+
+	for s, expected := range map[string]string{
+		"name":            "a",
+		"items[0].price":  "1",
+		"items[-2].price": "2",
+		"items[*].price":  "[1,2]",
+		"items[:2].price": "[1,2]",
+		"items[*].tags":   `[["x","y"],[]]`,
+		"items[0].tags.*": `["x","y"]`,
+		`["a.b"].c`:       "42",
+		"items[5:]":       "[]",
+		"*.c":             "[42]",
+	} {
+		path, err := ParseFieldPath(s)
+		require.Nil(t, err, s)
+
+		c, ok := path.Lookup(doc)
+		if assert.True(t, ok, s) {
+			assert.Equal(t, expected, c.AsString(), s)
+		}
+	}
+
+	for _, s := range []string{"nope", "name.a", "name[0]", "items[3]", "items.price", "items[0][0]", "items[2].price"} {
+		path, err := ParseFieldPath(s)
+		require.Nil(t, err, s)
+
+		_, ok := path.Lookup(doc)
+		assert.False(t, ok, s)
+	}
+}
+
+func TestFieldPathLookupRepeated(t *testing.T) {
+	single := object(map[string]interface{}{
+		"item": map[string]interface{}{"price": 1},
+	})
+	repeated := object(map[string]interface{}{
+		"item": []interface{}{
+			map[string]interface{}{"price": 1},
+			map[string]interface{}{"price": 2},
+		},
+	})
+
+	for s, expected := range map[string][2]string{
+		"item[0].price":  {"1", "1"},
+		"item[-1].price": {"1", "2"},
+		"item[*].price":  {"[1]", "[1,2]"},
+		"item[1:].price": {"[]", "[2]"},
+	} {
+		path, err := ParseFieldPath(s)
+		require.Nil(t, err, s)
+
+		for i, doc := range []*Const{single, repeated} {
+			c, ok := path.LookupRepeated(doc)
+			if assert.True(t, ok, s) {
+				assert.Equal(t, expected[i], c.AsString(), s)
+			}
+		}
+	}
+
+	for _, s := range []string{"item[1].price", "item[0].nope"} {
+		path, err := ParseFieldPath(s)
+		require.Nil(t, err, s)
+
+		_, ok := path.LookupRepeated(single)
+		assert.False(t, ok, s)
+	}
+}
@@ -1,6 +1,10 @@
 package charlatan
 
-import "bytes"
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
 
 // Query is a query
 type Query struct {
@@ -15,6 +19,23 @@ type Query struct {
 	startingAt int64
 	// the record index to stop at
 	limit *int64
+	// the comments of the query string
+	comments []Comment
+}
+
+// Comment is a comment of a query string. They're ignored by the parser but
+// kept for the tools which need to write them back, e.g. a formatter.
+type Comment struct {
+	// the comment as written in the query, including its delimiters
+	Text string
+	// the position of the comment in the query string
+	Pos int
+}
+
+// IsBlock tests if the comment is a /* block */ comment, as opposed to a
+// -- line comment
+func (c Comment) IsBlock() bool {
+	return strings.HasPrefix(c.Text, "/*")
 }
 
 // A Record is a record
@@ -27,6 +48,13 @@ func QueryFromString(s string) (*Query, error) {
 	return parserFromString(s).Parse()
 }
 
+// QueryFromStringTolerant creates a query from the given string without
+// stopping at the first syntax error. The returned query is never nil but may
+// be partial if there were errors, e.g. when the string is an incomplete query.
+func QueryFromStringTolerant(s string) (*Query, []*SyntaxError) {
+	return parserFromString(s).ParseTolerant()
+}
+
 // NewQuery creates a new query with the given from part
 func NewQuery(from string) *Query {
 	return &Query{from: from}
@@ -56,6 +84,11 @@ func (q *Query) Limit() int64 {
 	return *q.limit
 }
 
+// Comments returns the comments of the query string, in order
+func (q *Query) Comments() []Comment {
+	return q.comments
+}
+
 // AddField adds one field
 func (q *Query) AddField(field *Field) {
 	if field != nil {
@@ -89,6 +122,178 @@ func (q *Query) setLimit(limit int64) {
 	q.limit = &limit
 }
 
+// parameters returns the bind parameters of the query, in the order they
+// appear in it
+func (q *Query) parameters() []*parameter {
+	params := make([]*parameter, 0)
+
+	if q.expression == nil {
+		return params
+	}
+
+	rewriteOperand(q.expression, func(op operand) (operand, error) {
+		if p, ok := op.(*parameter); ok {
+			params = append(params, p)
+		}
+		return op, nil
+	})
+
+	return params
+}
+
+// NumParameters returns the number of positional parameters (?) of the query
+func (q *Query) NumParameters() int {
+	n := 0
+	for _, p := range q.parameters() {
+		if p.isPositional() {
+			n++
+		}
+	}
+	return n
+}
+
+// ParameterNames returns the names of the named parameters (:name) of the
+// query. Each name is only returned once.
+func (q *Query) ParameterNames() []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, p := range q.parameters() {
+		if !p.isPositional() && !seen[p.name] {
+			seen[p.name] = true
+			names = append(names, p.name)
+		}
+	}
+
+	return names
+}
+
+// Bind returns a copy of the query where the positional parameters (?) are
+// replaced by the given values, in order. Values are converted with NewConst.
+// The query itself is left untouched, so it can be bound again with other
+// values.
+func (q *Query) Bind(args ...interface{}) (*Query, error) {
+	if names := q.ParameterNames(); len(names) > 0 {
+		return nil, fmt.Errorf("The query has named parameters, use BindNamed")
+	}
+
+	if n := q.NumParameters(); n != len(args) {
+		return nil, fmt.Errorf("Expected %d parameters, got %d", n, len(args))
+	}
+
+	return q.bind(func(p *parameter) (*Const, error) {
+		return NewConst(args[p.index])
+	})
+}
+
+// BindNamed returns a copy of the query where the named parameters (:name)
+// are replaced by the given values. Values are converted with NewConst.
+// The query itself is left untouched, so it can be bound again with other
+// values.
+func (q *Query) BindNamed(args map[string]interface{}) (*Query, error) {
+	if q.NumParameters() > 0 {
+		return nil, fmt.Errorf("The query has positional parameters, use Bind")
+	}
+
+	return q.bind(func(p *parameter) (*Const, error) {
+		v, ok := args[p.name]
+		if !ok {
+			return nil, fmt.Errorf("Missing value for parameter %s", p)
+		}
+		return NewConst(v)
+	})
+}
+
+func (q *Query) bind(value func(*parameter) (*Const, error)) (*Query, error) {
+	bound := *q
+
+	if q.expression == nil {
+		return &bound, nil
+	}
+
+	expr, err := rewriteOperand(q.expression, func(op operand) (operand, error) {
+		p, ok := op.(*parameter)
+		if !ok {
+			return op, nil
+		}
+
+		c, err := value(p)
+		if err != nil {
+			return nil, fmt.Errorf("Can't bind parameter %s: %v", p, err)
+		}
+		return c, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bound.expression = expr
+
+	return &bound, nil
+}
+
+// BindFields returns a copy of the query where the fields are bound to the
+// column index returned by the given function, e.g. by a record type which
+// reads columns, so that they don't have to be looked up by name for each
+// record. Fields for which it returns a negative index aren't bound. The
+// query itself is left untouched.
+func (q *Query) BindFields(index func(*Field) int) *Query {
+	bind := func(f *Field) *Field {
+		if i := index(f); i >= 0 {
+			return f.WithIndex(i)
+		}
+		return f
+	}
+
+	bound := *q
+
+	bound.fields = make([]*Field, len(q.fields))
+	for i, field := range q.fields {
+		bound.fields[i] = bind(field)
+	}
+
+	if q.expression != nil {
+		// the function never fails, neither does the rewriting
+		bound.expression, _ = rewriteOperand(q.expression, func(op operand) (operand, error) {
+			if f, ok := op.(*Field); ok {
+				return bind(f), nil
+			}
+			return op, nil
+		})
+	}
+
+	return &bound
+}
+
+// AllFields returns the fields of the query, those of the SELECT part then
+// those of the condition. Each name is only returned once.
+func (q *Query) AllFields() []*Field {
+	fields := make([]*Field, 0, len(q.fields))
+	seen := make(map[string]bool)
+
+	add := func(f *Field) {
+		if !seen[f.name] {
+			seen[f.name] = true
+			fields = append(fields, f)
+		}
+	}
+
+	for _, field := range q.fields {
+		add(field)
+	}
+
+	if q.expression != nil {
+		rewriteOperand(q.expression, func(op operand) (operand, error) {
+			if f, ok := op.(*Field); ok {
+				add(f)
+			}
+			return op, nil
+		})
+	}
+
+	return fields
+}
+
 // FieldsValues extracts the values of each fields into the given record
 // Note that you should evaluate the query first
 func (q *Query) FieldsValues(record Record) ([]*Const, error) {
@@ -133,11 +338,11 @@ func (q *Query) String() string {
 		if i > 0 {
 			buffer.WriteString(", ")
 		}
-		buffer.WriteString(field.Name())
+		buffer.WriteString(field.String())
 	}
 
 	buffer.WriteString(" FROM ")
-	buffer.WriteString(q.from)
+	buffer.WriteString(quoteField(q.from))
 
 	if q.expression != nil {
 		buffer.WriteString(" WHERE ")
@@ -2,8 +2,10 @@ package charlatan
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,10 +32,10 @@ var _ Record = &dummyPerson{}
 func TestQueryInRange(t *testing.T) {
 	q := &Query{
 		fields: []*Field{
-			&Field{"name"},
+			NewField("name"),
 		},
 		expression: &rangeTestOperation{
-			test: Field{"age"},
+			test: NewField("age"),
 			min:  IntConst(10),
 			max:  IntConst(20),
 		},
@@ -110,3 +112,279 @@ func TestQueryFromStringLimitOffset(t *testing.T) {
 	assert.True(t, q.HasLimit())
 	assert.Equal(t, int64(100), q.Limit())
 }
+
+func TestQueryFromStringFieldBeforeLimit(t *testing.T) {
+	q, err := QueryFromString("SELECT a FROM b WHERE a LIMIT 3")
+	require.Nil(t, err)
+	require.NotNil(t, q.expression)
+	assert.Equal(t, "a", q.expression.String())
+}
+
+func TestQueryFromStringTolerant(t *testing.T) {
+	q, errs := QueryFromStringTolerant("SELECT a, FROM b WHERE c > 2")
+	require.NotNil(t, q)
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, 11, errs[0].Pos)
+	assert.Equal(t, "b", q.From())
+	assert.Equal(t, "c > 2", q.expression.String())
+}
+
+func TestQueryBind(t *testing.T) {
+	q, err := QueryFromString("SELECT name FROM b WHERE name = ? AND age BETWEEN ? AND ?")
+	require.Nil(t, err)
+	assert.Equal(t, 3, q.NumParameters())
+	assert.Empty(t, q.ParameterNames())
+
+	_, err = q.Evaluate(&dummyPerson{name: "A", age: 15})
+	assert.NotNil(t, err)
+
+	_, err = q.Bind("A", 10)
+	assert.NotNil(t, err)
+
+	b, err := q.Bind("A", 10, 20)
+	require.Nil(t, err)
+	assert.Equal(t, `SELECT name FROM b WHERE name = "A" AND age BETWEEN 10 AND 20`, b.String())
+
+	m, err := b.Evaluate(&dummyPerson{name: "A", age: 15})
+	assert.Nil(t, err)
+	assert.True(t, m)
+
+	// the original query is untouched and can be bound again
+	assert.Equal(t, `SELECT name FROM b WHERE name = ? AND age BETWEEN ? AND ?`, q.String())
+
+	b, err = q.Bind("O'Brien \" ", 10, 20)
+	require.Nil(t, err)
+
+	m, err = b.Evaluate(&dummyPerson{name: "A", age: 15})
+	assert.Nil(t, err)
+	assert.False(t, m)
+}
+
+func TestQueryBindNamed(t *testing.T) {
+	q, err := QueryFromString("SELECT name FROM b WHERE (age > :min AND age < :max) OR name = :min")
+	require.Nil(t, err)
+	assert.Equal(t, 0, q.NumParameters())
+	assert.Equal(t, []string{"min", "max"}, q.ParameterNames())
+
+	_, err = q.Bind(1, 2)
+	assert.NotNil(t, err)
+
+	_, err = q.BindNamed(map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+
+	b, err := q.BindNamed(map[string]interface{}{"min": 10, "max": IntConst(20)})
+	require.Nil(t, err)
+	assert.Equal(t, `SELECT name FROM b WHERE (age > 10 AND age < 20) OR name = 10`, b.String())
+
+	m, err := b.Evaluate(&dummyPerson{name: "A", age: 15})
+	assert.Nil(t, err)
+	assert.True(t, m)
+}
+
+func TestQueryBindInvalidValue(t *testing.T) {
+	q, err := QueryFromString("SELECT name FROM b WHERE name = ?")
+	require.Nil(t, err)
+
+	_, err = q.Bind([]int{1})
+	assert.NotNil(t, err)
+
+	_, err = q.BindNamed(nil)
+	assert.NotNil(t, err)
+}
+
+func TestQueryBindWithoutParameters(t *testing.T) {
+	q, err := QueryFromString("SELECT name FROM b")
+	require.Nil(t, err)
+
+	b, err := q.Bind()
+	require.Nil(t, err)
+	assert.Equal(t, q.String(), b.String())
+}
+
+func TestQueryComments(t *testing.T) {
+	q, err := QueryFromString(`
+		-- all the adults
+		SELECT name /* , age */
+		FROM people.jsons
+		WHERE age >= 18 -- in France`)
+	require.Nil(t, err)
+
+	assert.Equal(t, "SELECT name FROM people.jsons WHERE age >= 18", q.String())
+
+	comments := q.Comments()
+	require.Equal(t, 3, len(comments))
+
+	assert.Equal(t, "-- all the adults", comments[0].Text)
+	assert.Equal(t, 4, comments[0].Pos)
+	assert.False(t, comments[0].IsBlock())
+
+	assert.Equal(t, "/* , age */", comments[1].Text)
+	assert.True(t, comments[1].IsBlock())
+
+	assert.Equal(t, "-- in France", comments[2].Text)
+}
+
+func TestQueryTimeFiltering(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return mustParseTimestamp("2016-12-28T10:30:00Z") }
+
+	q, err := QueryFromString("SELECT msg FROM logs WHERE ts > NOW() - INTERVAL '1h' AND EXTRACT(hour FROM ts) = 9")
+	require.Nil(t, err)
+
+	for ts, expected := range map[string]bool{
+		"2016-12-28T09:45:00Z":      true,
+		"2016-12-28 09:45:00+00:00": true,
+		"2016-12-28T10:15:00Z":      false,
+		"2016-12-28T09:15:00Z":      false,
+		// the hour is extracted in the timestamp's timezone
+		"2016-12-28T10:45:00+01:00": false,
+		"2016-12-28T08:45:00-01:00": false,
+	} {
+		m, err := q.Evaluate(&constRecord{StringConst(ts)})
+		if assert.Nil(t, err, ts) {
+			assert.Equal(t, expected, m, ts)
+		}
+	}
+}
+
+func TestQueryTimeStringRoundTrip(t *testing.T) {
+	s := `SELECT a FROM b WHERE DATE_TRUNC("day", ts, "Europe/Paris") = TIMESTAMP "2016-12-28T00:00:00+01:00" AND ts - INTERVAL "5m0s" > NOW()`
+
+	q, err := QueryFromString(s)
+	require.Nil(t, err)
+	assert.Equal(t, s, q.String())
+
+	q2, err := QueryFromString(q.String())
+	require.Nil(t, err)
+	assert.Equal(t, s, q2.String())
+}
+
+func TestQueryBindTime(t *testing.T) {
+	q, err := QueryFromString("SELECT a FROM b WHERE ts > ? AND DATE_TRUNC(?, ts) = ts")
+	require.Nil(t, err)
+
+	ts := mustParseTimestamp("2016-12-28T10:00:00Z")
+
+	b, err := q.Bind(ts, "hour")
+	require.Nil(t, err)
+	assert.Equal(t, `SELECT a FROM b WHERE ts > TIMESTAMP "2016-12-28T10:00:00Z" AND DATE_TRUNC("hour", ts) = ts`, b.String())
+
+	// the arguments are checked once bound
+	_, err = q.Bind(ts, "fortnight")
+	assert.NotNil(t, err)
+}
+
+func TestQueryCollections(t *testing.T) {
+	tags := array("a", "b", "c")
+
+	for s, expected := range map[string]bool{
+		"SELECT x FROM y WHERE LENGTH(tags) = 3":             true,
+		"SELECT x FROM y WHERE CONTAINS(tags, 'b')":          true,
+		"SELECT x FROM y WHERE CONTAINS(tags, 'd')":          false,
+		"SELECT x FROM y WHERE 'b' = ANY(tags)":              true,
+		"SELECT x FROM y WHERE 'd' = ANY(tags)":              false,
+		"SELECT x FROM y WHERE 'd' > ALL(tags)":              true,
+		"SELECT x FROM y WHERE 'b' > ALL(tags)":              false,
+		"SELECT x FROM y WHERE tags = '[\"a\",\"b\",\"c\"]'": true,
+	} {
+		q, err := QueryFromString(s)
+		require.Nil(t, err, s)
+
+		m, err := q.Evaluate(&constRecord{tags})
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, expected, m, s)
+		}
+	}
+
+	// empty arrays and nulls
+	for s, expected := range map[string]bool{
+		"SELECT x FROM y WHERE 1 = ANY(tags)": false,
+		"SELECT x FROM y WHERE 1 = ALL(tags)": true,
+	} {
+		q, err := QueryFromString(s)
+		require.Nil(t, err, s)
+
+		m, err := q.Evaluate(&constRecord{ArrayConst(nil)})
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, expected, m, s)
+		}
+
+		m, err = q.Evaluate(&constRecord{NullConst()})
+		if assert.Nil(t, err, s) {
+			assert.False(t, m, s)
+		}
+	}
+}
+
+func TestQueryBindCollection(t *testing.T) {
+	q, err := QueryFromString("SELECT x FROM y WHERE status = ANY(?)")
+	require.Nil(t, err)
+
+	b, err := q.Bind([]interface{}{"open", "pending"})
+	require.Nil(t, err)
+	assert.Equal(t, `SELECT x FROM y WHERE status = ANY(["open","pending"])`, b.String())
+
+	m, err := b.Evaluate(&constRecord{StringConst("pending")})
+	require.Nil(t, err)
+	assert.True(t, m)
+}
+
+// columnsRecord returns the column index of bound fields
+type columnsRecord struct{}
+
+func (columnsRecord) Find(f *Field) (*Const, error) {
+	if i, ok := f.Index(); ok {
+		return IntConst(int64(i)), nil
+	}
+	return nil, fmt.Errorf("Unbound field %s", f)
+}
+
+func TestQueryBindFields(t *testing.T) {
+	columns := map[string]int{"a": 0, "b": 1, "c": 2}
+
+	q, err := QueryFromString("SELECT c, a FROM x WHERE b = 1 AND a + c - 2 = 0 AND c BETWEEN a AND 3")
+	require.Nil(t, err)
+
+	b := q.BindFields(func(f *Field) int {
+		if i, ok := columns[f.Name()]; ok {
+			return i
+		}
+		return -1
+	})
+	assert.Equal(t, q.String(), b.String())
+
+	m, err := b.Evaluate(columnsRecord{})
+	require.Nil(t, err)
+	assert.True(t, m)
+
+	values, err := b.FieldsValues(columnsRecord{})
+	require.Nil(t, err)
+	assert.Equal(t, []*Const{IntConst(2), IntConst(0)}, values)
+
+	// the original query is untouched
+	_, err = q.Evaluate(columnsRecord{})
+	assert.NotNil(t, err)
+
+	_, err = q.FieldsValues(columnsRecord{})
+	assert.NotNil(t, err)
+
+	// fields without index aren't bound
+	b = q.BindFields(func(f *Field) int { return -1 })
+	_, err = b.Evaluate(columnsRecord{})
+	assert.NotNil(t, err)
+}
+
+func TestQueryAllFields(t *testing.T) {
+	q, err := QueryFromString("SELECT c, a FROM x WHERE b = 1 AND (a + d > 2 OR DATE_TRUNC(\"day\", e) = 1)")
+	require.Nil(t, err)
+
+	var names []string
+	for _, f := range q.AllFields() {
+		names = append(names, f.Name())
+	}
+	assert.Equal(t, []string{"c", "a", "b", "d", "e"}, names)
+
+	q, err = QueryFromString("SELECT a FROM x")
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(q.AllFields()))
+}
@@ -0,0 +1,70 @@
+// Package avro provides a source of records for Avro object container files
+package avro
+
+import (
+	"io"
+
+	"github.com/BatchLabs/charlatan/record"
+	"github.com/hamba/avro/v2/ocf"
+)
+
+// Source reads record.DocumentRecords from an Avro object container file, one
+// record at a time. The records are decoded with the writer schema embedded
+// in the file, which must be a record schema.
+//
+// Nested records and maps are objects and arrays are arrays. Unions are
+// decoded as the value of their branch, null included. Strings keep their
+// type, e.g. "01234" isn't an int. Enums are strings, as are bytes and fixed
+// values. Logical types are kept: timestamps and dates are timestamps, times
+// are durations and decimals are decimals.
+//
+// The SoftMatching attribute is set on the records it returns. If the
+// UseDecimals attribute is set to true, floats and doubles are converted to
+// decimals instead of floats.
+type Source struct {
+	dec *ocf.Decoder
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewSource returns a source which reads the Avro records from the reader. It
+// reads the header of the file, along with its schema.
+func NewSource(r io.Reader) (*Source, error) {
+	dec, err := ocf.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source{dec: dec}, nil
+}
+
+// Schema returns the writer schema of the file, as JSON
+func (s *Source) Schema() string {
+	return s.dec.Schema().String()
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *Source) Next() (*record.DocumentRecord, error) {
+	if !s.dec.HasNext() {
+		if err := s.dec.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	var value interface{}
+
+	if err := s.dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	r, err := record.NewDocumentRecordFromValue(value, s.UseDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+
+	return r, nil
+}
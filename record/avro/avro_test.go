@@ -0,0 +1,215 @@
+package avro
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/hamba/avro/v2/ocf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eventSchema = `{
+	"type": "record",
+	"name": "Event",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "score", "type": "float"},
+		{"name": "user", "type": ["null", "string"]},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "counts", "type": {"type": "map", "values": "int"}},
+		{"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["INFO", "ERROR"]}},
+		{"name": "payload", "type": "bytes"},
+		{"name": "code", "type": {"type": "fixed", "name": "Code", "size": 2}},
+		{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 9, "scale": 2}},
+		{"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "source", "type": {
+			"type": "record",
+			"name": "Source",
+			"fields": [
+				{"name": "host", "type": "string"},
+				{"name": "port", "type": ["null", "int"]}
+			]
+		}}
+	]
+}`
+
+// event returns an event with the given id and level
+func event(id int64, level string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"score":   float32(0.1),
+		"user":    nil,
+		"tags":    []interface{}{"a", "b"},
+		"counts":  map[string]interface{}{"x": 1},
+		"level":   level,
+		"payload": []byte("hi"),
+		"code":    [2]byte{'o', 'k'},
+		"amount":  big.NewRat(12345, 100),
+		"at":      time.Date(2016, 12, 28, 23, 30, 0, 0, time.UTC),
+		"source":  map[string]interface{}{"host": "web1", "port": 8080},
+	}
+}
+
+// writeAvro writes the values to an Avro object container file in memory
+func writeAvro(t *testing.T, schema string, values ...interface{}) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	enc, err := ocf.NewEncoder(schema, &buf, ocf.WithCodec(ocf.Deflate))
+	require.Nil(t, err)
+
+	for _, v := range values {
+		require.Nil(t, enc.Encode(v))
+	}
+	require.Nil(t, enc.Close())
+
+	return &buf
+}
+
+func TestSource(t *testing.T) {
+	s, err := NewSource(writeAvro(t, eventSchema, event(1, "INFO"), event(2, "ERROR")))
+	require.Nil(t, err)
+	assert.Contains(t, s.Schema(), `"name":"Event"`)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"id", "1"},
+		{"user", "null"},
+		{"tags[1]", "b"},
+		{"counts.x", "1"},
+		{"level", "INFO"},
+		{"payload", "hi"},
+		{"code", "ok"},
+		{"amount", "123.45"},
+		{"at", "2016-12-28T23:30:00Z"},
+		{"source.host", "web1"},
+		{"source.port", "8080"},
+	} {
+		c, err := r.Find(ch.NewField(tc.field))
+		require.Nil(t, err, tc.field)
+		assert.Equal(t, tc.expected, c.AsString(), tc.field)
+	}
+
+	c, err := r.Find(ch.NewField("score"))
+	require.Nil(t, err)
+	assert.Equal(t, 0.1, c.Value())
+
+	r, err = s.Next()
+	require.Nil(t, err)
+
+	c, err = r.Find(ch.NewField("level"))
+	require.Nil(t, err)
+	assert.Equal(t, "ERROR", c.AsString())
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSourceQuery(t *testing.T) {
+	s, err := NewSource(writeAvro(t, eventSchema,
+		event(1, "INFO"), event(2, "ERROR"), event(3, "ERROR")))
+	require.Nil(t, err)
+
+	query, err := ch.QueryFromString("SELECT id FROM events.avro WHERE level = \"ERROR\" AND source.port > 80")
+	require.Nil(t, err)
+
+	var ids []int64
+
+	for {
+		r, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+
+		ok, err := query.Evaluate(r)
+		require.Nil(t, err)
+		if !ok {
+			continue
+		}
+
+		values, err := query.FieldsValues(r)
+		require.Nil(t, err)
+		ids = append(ids, values[0].Value().(int64))
+	}
+
+	assert.Equal(t, []int64{2, 3}, ids)
+}
+
+func TestSourceUseDecimals(t *testing.T) {
+	s, err := NewSource(writeAvro(t, eventSchema, event(1, "INFO")))
+	require.Nil(t, err)
+	s.UseDecimals = true
+	s.SoftMatching = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("score"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.1", c.AsString())
+
+	c, err = r.Find(ch.NewField("nope"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
+
+func TestSourceStrings(t *testing.T) {
+	schema := `{
+		"type": "record",
+		"name": "Address",
+		"fields": [
+			{"name": "zip", "type": "string"},
+			{"name": "flag", "type": "string"},
+			{"name": "size", "type": "string"}
+		]
+	}`
+
+	s, err := NewSource(writeAvro(t, schema, map[string]interface{}{
+		"zip":  "01234",
+		"flag": "true",
+		"size": "1e3",
+	}))
+	require.Nil(t, err)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	// strings aren't parsed, their type is given by the schema
+	for _, name := range []string{"zip", "flag", "size"} {
+		c, err := r.Find(ch.NewField(name))
+		require.Nil(t, err, name)
+		assert.True(t, c.IsString(), name)
+	}
+
+	query, err := ch.QueryFromString(`SELECT zip FROM a.avro WHERE zip = "01234" AND flag = "true" AND size = "1e3"`)
+	require.Nil(t, err)
+
+	ok, err := query.Evaluate(r)
+	require.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestSourceNotRecords(t *testing.T) {
+	s, err := NewSource(writeAvro(t, `"int"`, 42))
+	require.Nil(t, err)
+
+	_, err = s.Next()
+	assert.NotNil(t, err)
+}
+
+func TestSourceInvalidFile(t *testing.T) {
+	_, err := NewSource(strings.NewReader("not avro"))
+	assert.NotNil(t, err)
+}
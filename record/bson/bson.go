@@ -0,0 +1,184 @@
+// Package bson provides a source of records for BSON documents
+package bson
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/BatchLabs/charlatan/record"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// maxDocumentSize is the maximum size of the BSON documents, the one of
+// MongoDB
+const maxDocumentSize = 16 * 1024 * 1024
+
+// Source reads record.DocumentRecords from a stream of BSON documents, e.g.
+// the .bson files of mongodump. Each document starts with its length.
+//
+// Values are converted like the other documents: embedded documents are
+// objects and arrays are arrays. Dates and timestamps are timestamps,
+// Decimal128 values are decimals, ObjectIDs are hexadecimal strings and
+// UUIDs are formatted as such. Other binary values, regular expressions and
+// JavaScript code are strings, and undefined values are null. Strings keep
+// their type, e.g. "01234" isn't an int.
+//
+// The SoftMatching attribute is set on the records it returns. If the
+// UseDecimals attribute is set to true, doubles are converted to decimals.
+type Source struct {
+	r io.Reader
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewSource returns a source which reads the BSON documents from the reader
+func NewSource(r io.Reader) *Source {
+	return &Source{r: r}
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *Source) Next() (*record.DocumentRecord, error) {
+	var length [4]byte
+
+	if _, err := io.ReadFull(s.r, length[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(length[:])
+	if size < 5 || size > maxDocumentSize {
+		return nil, fmt.Errorf("Invalid BSON document size: %d", size)
+	}
+
+	doc := make([]byte, size)
+	copy(doc, length[:])
+
+	if _, err := io.ReadFull(s.r, doc[4:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	if err := bson.Raw(doc).Validate(); err != nil {
+		return nil, err
+	}
+
+	value, err := documentValue(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := record.NewDocumentRecordFromValue(value, s.UseDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+
+	return r, nil
+}
+
+// documentValue returns the members of a document
+func documentValue(doc bson.Raw) (map[string]interface{}, error) {
+	elements, err := doc.Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]interface{}, len(elements))
+
+	for _, element := range elements {
+		v, err := rawValue(element.Value())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", element.Key(), err)
+		}
+		members[element.Key()] = v
+	}
+
+	return members, nil
+}
+
+// rawValue returns the value of a BSON value which
+// record.DocumentValueToConst converts
+func rawValue(v bson.RawValue) (interface{}, error) {
+	switch v.Type {
+	case bson.TypeDouble:
+		return v.Double(), nil
+	case bson.TypeString:
+		return v.StringValue(), nil
+	case bson.TypeBoolean:
+		return v.Boolean(), nil
+	case bson.TypeInt32:
+		return v.Int32(), nil
+	case bson.TypeInt64:
+		return v.Int64(), nil
+	case bson.TypeNull, bson.TypeUndefined, bson.TypeMinKey, bson.TypeMaxKey:
+		return nil, nil
+
+	case bson.TypeEmbeddedDocument:
+		return documentValue(v.Document())
+
+	case bson.TypeArray:
+		values, err := v.Array().Values()
+		if err != nil {
+			return nil, err
+		}
+
+		elements := make([]interface{}, len(values))
+		for i, value := range values {
+			if elements[i], err = rawValue(value); err != nil {
+				return nil, err
+			}
+		}
+		return elements, nil
+
+	case bson.TypeDateTime:
+		return time.UnixMilli(v.DateTime()).UTC(), nil
+
+	case bson.TypeTimestamp:
+		t, _ := v.Timestamp()
+		return time.Unix(int64(t), 0).UTC(), nil
+
+	case bson.TypeDecimal128:
+		d := v.Decimal128()
+		// NaN and infinities aren't decimals
+		if c, err := ch.DecimalConstFromString(d.String()); err == nil {
+			return c, nil
+		}
+		return d.String(), nil
+
+	case bson.TypeObjectID:
+		return v.ObjectID().Hex(), nil
+
+	case bson.TypeBinary:
+		subtype, data := v.Binary()
+		if (subtype == bson.TypeBinaryUUID || subtype == bson.TypeBinaryUUIDOld) && len(data) == 16 {
+			return record.FormatUUID(data), nil
+		}
+		return data, nil
+
+	case bson.TypeRegex:
+		pattern, options := v.Regex()
+		return "/" + pattern + "/" + options, nil
+
+	case bson.TypeJavaScript:
+		return v.JavaScript(), nil
+
+	case bson.TypeSymbol:
+		return v.Symbol(), nil
+
+	case bson.TypeCodeWithScope:
+		code, _ := v.CodeWithScope()
+		return code, nil
+
+	case bson.TypeDBPointer:
+		ns, id := v.DBPointer()
+		return ns + "/" + id.Hex(), nil
+	}
+
+	return nil, fmt.Errorf("Unsupported BSON type %v", v.Type)
+}
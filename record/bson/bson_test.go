@@ -0,0 +1,221 @@
+package bson
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBSON writes the documents one after the other, as mongodump does
+func writeBSON(t *testing.T, docs ...interface{}) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	for _, doc := range docs {
+		b, err := bson.Marshal(doc)
+		require.Nil(t, err)
+		buf.Write(b)
+	}
+
+	return &buf
+}
+
+func TestSource(t *testing.T) {
+	s := NewSource(writeBSON(t, bson.D{{Key: "name", Value: "api"}}, bson.D{{Key: "name", Value: "worker"}}))
+
+	for _, name := range []string{"api", "worker"} {
+		r, err := s.Next()
+		require.Nil(t, err, name)
+
+		c, err := r.Find(ch.NewField("name"))
+		require.Nil(t, err, name)
+		assert.Equal(t, name, c.AsString())
+	}
+
+	_, err := s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSourceLegacyTypes(t *testing.T) {
+	id, err := bson.ObjectIDFromHex("5849a8fd1c2e7b3f8a6d4e21")
+	require.Nil(t, err)
+	nan, err := bson.ParseDecimal128("NaN")
+	require.Nil(t, err)
+	inf, err := bson.ParseDecimal128("-Infinity")
+	require.Nil(t, err)
+
+	s := NewSource(writeBSON(t, bson.D{
+		{Key: "undefined", Value: bson.Undefined{}},
+		{Key: "min", Value: bson.MinKey{}},
+		{Key: "max", Value: bson.MaxKey{}},
+		{Key: "nan", Value: nan},
+		{Key: "inf", Value: inf},
+		{Key: "js", Value: bson.JavaScript("x + 1")},
+		{Key: "scoped", Value: bson.CodeWithScope{Code: "x + y", Scope: bson.D{{Key: "y", Value: 1}}}},
+		{Key: "symbol", Value: bson.Symbol("sym")},
+		{Key: "ptr", Value: bson.DBPointer{DB: "db.users", Pointer: id}},
+	}))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for _, name := range []string{"undefined", "min", "max"} {
+		c, err := r.Find(ch.NewField(name))
+		require.Nil(t, err, name)
+		assert.True(t, c.IsNull(), name)
+	}
+
+	// NaN and infinite Decimal128 values, code, symbols and DB pointers are
+	// strings
+	for name, expected := range map[string]string{
+		"nan":    "NaN",
+		"inf":    "-Infinity",
+		"js":     "x + 1",
+		"scoped": "x + y",
+		"symbol": "sym",
+		"ptr":    "db.users/5849a8fd1c2e7b3f8a6d4e21",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		require.Nil(t, err, name)
+		assert.True(t, c.IsString(), name)
+		assert.Equal(t, expected, c.AsString(), name)
+	}
+}
+
+func TestSourceNumbers(t *testing.T) {
+	s := NewSource(writeBSON(t, bson.D{
+		{Key: "int32", Value: int32(-7)},
+		{Key: "int64", Value: int64(-1) << 62},
+		{Key: "double", Value: 0.1},
+	}))
+	s.UseDecimals = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for name, expected := range map[string]interface{}{
+		"int32": int64(-7),
+		"int64": int64(-1) << 62,
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	// doubles are converted to decimals from their shortest representation
+	c, err := r.Find(ch.NewField("double"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.1", c.AsString())
+}
+
+func TestSourceValues(t *testing.T) {
+	ts := time.Date(2016, 12, 28, 10, 0, 0, 0, time.UTC)
+	id, err := bson.ObjectIDFromHex("5849a8fd1c2e7b3f8a6d4e21")
+	require.Nil(t, err)
+	amount, err := bson.ParseDecimal128("123.45")
+	require.Nil(t, err)
+
+	s := NewSource(writeBSON(t, bson.D{
+		{Key: "_id", Value: id},
+		{Key: "n", Value: int32(3)},
+		{Key: "big", Value: int64(1) << 40},
+		{Key: "score", Value: 2.5},
+		{Key: "at", Value: bson.NewDateTimeFromTime(ts)},
+		{Key: "amount", Value: amount},
+		{Key: "uuid", Value: bson.Binary{Subtype: bson.TypeBinaryUUID, Data: []byte{
+			0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 8}}},
+		{Key: "data", Value: bson.Binary{Data: []byte("hi")}},
+		{Key: "re", Value: bson.Regex{Pattern: "^a", Options: "i"}},
+		{Key: "tags", Value: bson.A{"a", "b"}},
+		{Key: "user", Value: bson.D{{Key: "name", Value: "joe"}, {Key: "admin", Value: true}}},
+		{Key: "none", Value: nil},
+		{Key: "ts", Value: bson.Timestamp{T: uint32(ts.Unix()), I: 1}},
+		{Key: "zip", Value: "01234"},
+	}))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"_id", "5849a8fd1c2e7b3f8a6d4e21"},
+		{"n", "3"},
+		{"big", "1099511627776"},
+		{"score", "2.50"},
+		{"at", "2016-12-28T10:00:00Z"},
+		{"amount", "123.45"},
+		{"uuid", "12345678-9abc-def0-0102-030405060708"},
+		{"data", "hi"},
+		{"re", "/^a/i"},
+		{"tags[1]", "b"},
+		{"user.name", "joe"},
+		{"user.admin", "true"},
+		{"none", "null"},
+		{"ts", "2016-12-28T10:00:00Z"},
+		{"zip", "01234"},
+	} {
+		c, err := r.Find(ch.NewField(tc.field))
+		require.Nil(t, err, tc.field)
+		assert.Equal(t, tc.expected, c.AsString(), tc.field)
+	}
+
+	c, err := r.Find(ch.NewField("zip"))
+	require.Nil(t, err)
+	assert.True(t, c.IsString())
+}
+
+func TestSourceQuery(t *testing.T) {
+	s := NewSource(writeBSON(t,
+		bson.D{{Key: "name", Value: "api"}, {Key: "replicas", Value: 2}},
+		bson.D{{Key: "name", Value: "worker"}, {Key: "replicas", Value: 5}},
+	))
+
+	query, err := ch.QueryFromString("SELECT name FROM services.bson WHERE replicas > 3")
+	require.Nil(t, err)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+	ok, err := query.Evaluate(r)
+	require.Nil(t, err)
+	assert.False(t, ok)
+
+	r, err = s.Next()
+	require.Nil(t, err)
+	ok, err = query.Evaluate(r)
+	require.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestSourceErrors(t *testing.T) {
+	buf := writeBSON(t, bson.D{{Key: "name", Value: "api"}})
+
+	// truncated
+	s := NewSource(bytes.NewReader(buf.Bytes()[:buf.Len()-2]))
+	_, err := s.Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	s = NewSource(bytes.NewReader(buf.Bytes()[:2]))
+	_, err = s.Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// invalid size
+	s = NewSource(bytes.NewReader([]byte{1, 0, 0, 0, 0}))
+	_, err = s.Next()
+	assert.NotNil(t, err)
+
+	// corrupted
+	b := append([]byte(nil), buf.Bytes()...)
+	b[len(b)-1] = 1
+	s = NewSource(bytes.NewReader(b))
+	_, err = s.Next()
+	assert.NotNil(t, err)
+}
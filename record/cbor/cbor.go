@@ -0,0 +1,64 @@
+// Package cbor provides a source of records for CBOR sequences
+package cbor
+
+import (
+	"io"
+
+	"github.com/BatchLabs/charlatan/record"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// decMode decodes the unknown tags as their content, and the timestamp and
+// bignum tags as time.Time and big.Int values
+var decMode cbor.DecMode
+
+func init() {
+	var err error
+
+	decMode, err = cbor.DecOptions{
+		UnrecognizedTagToAny: cbor.UnrecognizedTagContentToAny,
+	}.DecMode()
+	if err != nil {
+		panic("cbor: invalid decoding options: " + err.Error())
+	}
+}
+
+// Source reads record.DocumentRecords from a CBOR sequence, i.e. CBOR maps
+// one after the other, see RFC 8742.
+//
+// Values are converted like the other documents: arrays are arrays, nested
+// maps are objects whose keys are formatted if they aren't strings, byte
+// strings are strings, and the timestamps and bignums are timestamps and
+// bigints. Text strings keep their type, e.g. "01234" isn't an int.
+//
+// The SoftMatching attribute is set on the records it returns. If the
+// UseDecimals attribute is set to true, floats are converted to decimals.
+type Source struct {
+	dec *cbor.Decoder
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewSource returns a source which reads the CBOR maps from the reader
+func NewSource(r io.Reader) *Source {
+	return &Source{dec: decMode.NewDecoder(r)}
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *Source) Next() (*record.DocumentRecord, error) {
+	var value interface{}
+
+	if err := s.dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	r, err := record.NewDocumentRecordFromValue(value, s.UseDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+
+	return r, nil
+}
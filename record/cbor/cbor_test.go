@@ -0,0 +1,134 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCBOR encodes the values as a CBOR sequence, with tagged timestamps
+func writeCBOR(t *testing.T, values ...interface{}) *bytes.Buffer {
+	em, err := cbor.EncOptions{Time: cbor.TimeRFC3339, TimeTag: cbor.EncTagRequired}.EncMode()
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	enc := em.NewEncoder(&buf)
+	for _, v := range values {
+		require.Nil(t, enc.Encode(v))
+	}
+
+	return &buf
+}
+
+func TestSource(t *testing.T) {
+	s := NewSource(writeCBOR(t,
+		map[string]interface{}{"name": "api"},
+		map[string]interface{}{"name": "worker"},
+	))
+
+	for _, name := range []string{"api", "worker"} {
+		r, err := s.Next()
+		require.Nil(t, err, name)
+
+		c, err := r.Find(ch.NewField("name"))
+		require.Nil(t, err, name)
+		assert.Equal(t, name, c.AsString())
+	}
+
+	_, err := s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSourceTagsAndSimpleValues(t *testing.T) {
+	s := NewSource(bytes.NewReader([]byte{0xa5,
+		// epoch-based timestamp: 1(1482919328)
+		0x62, 'a', 't', 0xc1, 0x1a, 0x58, 0x63, 0x8d, 0xa0,
+		// negative bignum: 3(h'010000000000000000'), i.e. -2^64-1
+		0x63, 'n', 'e', 'g', 0xc3, 0x49, 0x01, 0, 0, 0, 0, 0, 0, 0, 0,
+		// half-precision float
+		0x64, 'h', 'a', 'l', 'f', 0xf9, 0x3e, 0x00,
+		// undefined
+		0x65, 'u', 'n', 'd', 'e', 'f', 0xf7,
+		// an unknown tag around a timestamp: 4242(1(1482919328))
+		0x67, 'w', 'r', 'a', 'p', 'p', 'e', 'd', 0xd9, 0x10, 0x92, 0xc1, 0x1a, 0x58, 0x63, 0x8d, 0xa0,
+	}))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	find := func(name string) *ch.Const {
+		c, err := r.Find(ch.NewField(name))
+		require.Nil(t, err, name)
+		return c
+	}
+
+	assert.Equal(t, "2016-12-28T10:02:08Z", find("at").AsString())
+	assert.Equal(t, "-18446744073709551617", find("neg").AsString())
+	assert.Equal(t, 1.5, find("half").Value())
+	assert.True(t, find("undef").IsNull())
+	assert.Equal(t, "2016-12-28T10:02:08Z", find("wrapped").AsString())
+}
+
+func TestSourceValues(t *testing.T) {
+	ts := time.Date(2016, 12, 28, 10, 0, 0, 0, time.UTC)
+
+	s := NewSource(writeCBOR(t, map[interface{}]interface{}{
+		"id":    -3,
+		"count": uint64(1 << 63),
+		"big":   new(big.Int).Lsh(big.NewInt(1), 70),
+		"at":    ts,
+		"bytes": []byte("hi"),
+		"tags":  []string{"a", "b"},
+		"user":  map[interface{}]interface{}{1: "one", "name": "joe"},
+		"tag":   cbor.Tag{Number: 4242, Content: "content"},
+		"score": 2.5,
+		"zip":   "01234",
+	}))
+	s.UseDecimals = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"id", "-3"},
+		{"count", "9223372036854775808"},
+		{"big", "1180591620717411303424"},
+		{"at", "2016-12-28T10:00:00Z"},
+		{"bytes", "hi"},
+		{"tags[0]", "a"},
+		{"user.1", "one"},
+		{"user.name", "joe"},
+		{"tag", "content"},
+		{"score", "2.5"},
+		{"zip", "01234"},
+	} {
+		c, err := r.Find(ch.NewField(tc.field))
+		require.Nil(t, err, tc.field)
+		assert.Equal(t, tc.expected, c.AsString(), tc.field)
+	}
+
+	c, err := r.Find(ch.NewField("zip"))
+	require.Nil(t, err)
+	assert.True(t, c.IsString())
+
+	_, err = r.Find(ch.NewField("nope"))
+	assert.NotNil(t, err)
+}
+
+func TestSourceNotAMap(t *testing.T) {
+	s := NewSource(writeCBOR(t, "a string"))
+	_, err := s.Next()
+	assert.NotNil(t, err)
+}
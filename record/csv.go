@@ -14,10 +14,21 @@ import (
 // In any case, one can use a "$N" field name, where N is the column index,
 // starting at 0.
 //
-// All values are retrieved as strings, and the special field "*" can be used
-// to get a string representation of the record values.
+// Without a schema, the type of each value is guessed independently and the
+// "NULL" values are null. With a schema, the values of a column all have the
+// column type, and the schema's null tokens are null. The special field "*"
+// can be used to get a string representation of the record values.
+//
+// If the UseDecimals attribute is set to true, non-integer numbers are
+// retrieved as decimals instead of floats, so that they're never rounded.
+//
+// Fields bound to a column index, see CSVHeader.Bind, are retrieved directly
+// from their index.
 type CSVRecord struct {
-	header, record []string
+	header      *CSVHeader
+	record      []string
+	schema      *CSVSchema
+	UseDecimals bool
 }
 
 var _ ch.Record = &CSVRecord{}
@@ -27,14 +38,31 @@ func NewCSVRecord(record []string) *CSVRecord {
 	return &CSVRecord{record: record}
 }
 
-// NewCSVRecordWithHeader returns a new CSVRecord with the given header
+// NewCSVRecordWithHeader returns a new CSVRecord with the given header. Its
+// column names are scanned for each lookup, use NewCSVRecordWithCSVHeader to
+// share an indexed header between the records of a file.
 func NewCSVRecordWithHeader(record []string, header []string) *CSVRecord {
+	return &CSVRecord{header: &CSVHeader{names: header}, record: record}
+}
+
+// NewCSVRecordWithCSVHeader returns a new CSVRecord with the given header
+func NewCSVRecordWithCSVHeader(record []string, header *CSVHeader) *CSVRecord {
 	return &CSVRecord{header: header, record: record}
 }
 
+// NewCSVRecordWithSchema returns a new CSVRecord with the given schema, whose
+// column names are used as the header
+func NewCSVRecordWithSchema(record []string, schema *CSVSchema) *CSVRecord {
+	return &CSVRecord{record: record, schema: schema}
+}
+
 // Find implements the charlatan.Record interface
 func (r *CSVRecord) Find(field *ch.Field) (*ch.Const, error) {
 
+	if index, ok := field.Index(); ok {
+		return r.AtIndex(index)
+	}
+
 	name := field.Name()
 
 	if name == "*" {
@@ -65,29 +93,38 @@ func (r *CSVRecord) Find(field *ch.Field) (*ch.Const, error) {
 // AtIndex gets the value at the given index
 func (r *CSVRecord) AtIndex(index int) (*ch.Const, error) {
 
-	if index < 0 || index > len(r.record) {
+	if index < 0 || index >= len(r.record) {
 		return nil, fmt.Errorf("index out of bounds %d", index)
 	}
 
-	// FIXME should we accept NULL values?
 	value := r.record[index]
+
+	if r.schema != nil {
+		return r.schema.Convert(index, value, r.UseDecimals)
+	}
+
 	if value == "NULL" {
 		return ch.NullConst(), nil
 	}
 
+	if r.UseDecimals {
+		return ch.ConstFromStringDecimal(value), nil
+	}
+
 	return ch.ConstFromString(value), nil
 }
 
 // ColumnNameIndex searches the index of the column name in this record’s
-// header. If it doesn’t have a header or if the column wasn’t found, the
-// method returns -1. The column name match is case-sensitive, the first
-// matching one is used.
+// header, or in its schema if it only has one. If it doesn’t have a header or
+// if the column wasn’t found, the method returns -1. A case-sensitive match
+// is preferred, otherwise the first case-insensitive one is used.
 func (r *CSVRecord) ColumnNameIndex(name string) int {
-	for index, element := range r.header {
-		if element == name {
-			return index
+	if r.header == nil {
+		if r.schema != nil {
+			return r.schema.ColumnIndex(name)
 		}
+		return -1
 	}
 
-	return -1
+	return r.header.Index(name)
 }
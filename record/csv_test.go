@@ -1,6 +1,7 @@
 package record
 
 import (
+	"fmt"
 	"testing"
 
 	ch "github.com/BatchLabs/charlatan"
@@ -62,3 +63,137 @@ func TestFindStar(t *testing.T) {
 	assert.True(t, v.IsString())
 	assert.Equal(t, "[x y z]", v.AsString())
 }
+
+func TestCSVRecordUseDecimals(t *testing.T) {
+	c := NewCSVRecord([]string{"12345678901234567890.12", "0.10", "foo"})
+	require.NotNil(t, c)
+
+	v, err := c.Find(ch.NewField("$0"))
+	require.Nil(t, err)
+	// rounded by the float64 conversion
+	assert.Equal(t, 12345678901234567890.12, v.Value())
+
+	c.UseDecimals = true
+
+	v, err = c.Find(ch.NewField("$0"))
+	require.Nil(t, err)
+	assert.Equal(t, "12345678901234567890.12", v.AsString())
+
+	v, err = c.Find(ch.NewField("$1"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.10", v.AsString())
+
+	v, err = c.Find(ch.NewField("$2"))
+	require.Nil(t, err)
+	assert.Equal(t, "foo", v.AsString())
+}
+
+func TestCSVRecordWithSchema(t *testing.T) {
+	s := InferCSVSchema(
+		[]string{"zip", "count"},
+		[][]string{{"01234", "1"}, {"75001", "NA"}},
+		[]string{"NA"},
+	)
+
+	c := NewCSVRecordWithSchema([]string{"75001", "NA"}, s)
+
+	v, err := c.Find(ch.NewField("zip"))
+	require.Nil(t, err)
+	assert.True(t, v.IsString())
+	assert.Equal(t, "75001", v.AsString())
+
+	v, err = c.Find(ch.NewField("count"))
+	require.Nil(t, err)
+	assert.True(t, v.IsNull())
+
+	v, err = c.Find(ch.NewField("$0"))
+	require.Nil(t, err)
+	assert.True(t, v.IsString())
+
+	_, err = c.Find(ch.NewField("nope"))
+	assert.NotNil(t, err)
+
+	_, err = NewCSVRecordWithSchema([]string{"01234", "x"}, s).Find(ch.NewField("count"))
+	assert.NotNil(t, err)
+}
+
+func TestColumnNameIndexCase(t *testing.T) {
+	c := NewCSVRecordWithHeader([]string{"a", "b", "c"}, []string{"Name", "NAME", "name"})
+
+	assert.Equal(t, 2, c.ColumnNameIndex("name"))
+	assert.Equal(t, 1, c.ColumnNameIndex("NAME"))
+	assert.Equal(t, 0, c.ColumnNameIndex("nAmE"))
+	assert.Equal(t, -1, c.ColumnNameIndex("names"))
+
+	c = NewCSVRecordWithHeader([]string{"a"}, []string{"Population"})
+
+	v, err := c.Find(ch.NewField("population"))
+	require.Nil(t, err)
+	assert.Equal(t, "a", v.AsString())
+}
+
+// wideCSV returns the header and a row of a CSV file with many columns
+func wideCSV() ([]string, []string) {
+	header := make([]string, 200)
+	row := make([]string, 200)
+	for i := range header {
+		header[i] = fmt.Sprintf("column%d", i)
+		row[i] = fmt.Sprintf("%d", i)
+	}
+	return header, row
+}
+
+var benchmarkCSVFields = []*ch.Field{
+	ch.NewField("column10"),
+	ch.NewField("column150"),
+	ch.NewField("column199"),
+}
+
+func BenchmarkCSVRecordFind(b *testing.B) {
+	header, row := wideCSV()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewCSVRecordWithHeader(row, header)
+		for _, f := range benchmarkCSVFields {
+			if _, err := r.Find(f); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCSVRecordFindSharedHeader(b *testing.B) {
+	names, row := wideCSV()
+	header := NewCSVHeader(names)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewCSVRecordWithCSVHeader(row, header)
+		for _, f := range benchmarkCSVFields {
+			if _, err := r.Find(f); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCSVRecordFindBoundFields(b *testing.B) {
+	names, row := wideCSV()
+	header := NewCSVHeader(names)
+
+	fields := make([]*ch.Field, len(benchmarkCSVFields))
+	for i, f := range benchmarkCSVFields {
+		fields[i] = f.WithIndex(header.FieldIndex(f))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewCSVRecordWithCSVHeader(row, header)
+		for _, f := range fields {
+			if _, err := r.Find(f); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
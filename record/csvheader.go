@@ -0,0 +1,109 @@
+package record
+
+import (
+	"strconv"
+	"strings"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// CSVHeader is the header of a CSV file, shared by all its records. It
+// indexes the column names once so that looking a column up doesn't scan the
+// header.
+type CSVHeader struct {
+	names []string
+	// the index of each name, and of each lower-cased name for
+	// case-insensitive matches. They're nil for headers which aren't
+	// indexed, which are scanned instead.
+	indexes, folded map[string]int
+}
+
+// NewCSVHeader returns the header with the given column names
+func NewCSVHeader(names []string) *CSVHeader {
+	h := &CSVHeader{
+		names:   names,
+		indexes: make(map[string]int, len(names)),
+		folded:  make(map[string]int, len(names)),
+	}
+
+	for i, name := range names {
+		// the first matching column is used
+		if _, ok := h.indexes[name]; !ok {
+			h.indexes[name] = i
+		}
+
+		lower := strings.ToLower(name)
+		if _, ok := h.folded[lower]; !ok {
+			h.folded[lower] = i
+		}
+	}
+
+	return h
+}
+
+// Names returns the column names
+func (h *CSVHeader) Names() []string {
+	return h.names
+}
+
+// Index returns the index of the column with the given name, or -1 if
+// there's none. A case-sensitive match is preferred, otherwise the first
+// case-insensitive one is used.
+func (h *CSVHeader) Index(name string) int {
+	if h.indexes == nil {
+		return columnIndex(h.names, name)
+	}
+
+	if i, ok := h.indexes[name]; ok {
+		return i
+	}
+	if i, ok := h.folded[strings.ToLower(name)]; ok {
+		return i
+	}
+
+	return -1
+}
+
+// FieldIndex returns the index of the column a field refers to, either by
+// its name or as "$N", or -1 if there's none
+func (h *CSVHeader) FieldIndex(field *ch.Field) int {
+	name := field.Name()
+
+	if name == "" || name == "*" {
+		return -1
+	}
+
+	if name[0] == '$' {
+		if i, err := strconv.Atoi(name[1:]); err == nil && i >= 0 {
+			return i
+		}
+		return -1
+	}
+
+	return h.Index(name)
+}
+
+// Bind returns a copy of the query where the fields are bound to the index of
+// their column, see charlatan.Query.BindFields. The records of this header
+// then get the values of these fields directly from their index. The bound
+// query must only be used with records with the same columns.
+func (h *CSVHeader) Bind(query *ch.Query) *ch.Query {
+	return query.BindFields(h.FieldIndex)
+}
+
+// columnIndex scans the names for the given name, see CSVHeader.Index
+func columnIndex(names []string, name string) int {
+	for index, element := range names {
+		if element == name {
+			return index
+		}
+	}
+
+	for index, element := range names {
+		if strings.EqualFold(element, name) {
+			return index
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,78 @@
+package record
+
+import (
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVHeaderIndex(t *testing.T) {
+	for _, h := range []*CSVHeader{
+		NewCSVHeader([]string{"id", "Name", "NAME", "name", "id"}),
+		// not indexed
+		{names: []string{"id", "Name", "NAME", "name", "id"}},
+	} {
+		assert.Equal(t, 0, h.Index("id"))
+		assert.Equal(t, 3, h.Index("name"))
+		assert.Equal(t, 2, h.Index("NAME"))
+		assert.Equal(t, 1, h.Index("nAmE"))
+		assert.Equal(t, 0, h.Index("ID"))
+		assert.Equal(t, -1, h.Index("names"))
+	}
+
+	assert.Equal(t, []string{"a"}, NewCSVHeader([]string{"a"}).Names())
+	assert.Equal(t, -1, NewCSVHeader(nil).Index("a"))
+}
+
+func TestCSVHeaderFieldIndex(t *testing.T) {
+	h := NewCSVHeader([]string{"a", "b", "*", "$x"})
+
+	for name, expected := range map[string]int{
+		"a":   0,
+		"B":   1,
+		"c":   -1,
+		"$1":  1,
+		"$42": 42,
+		"$-1": -1,
+		"$x":  -1,
+		"*":   -1,
+	} {
+		assert.Equal(t, expected, h.FieldIndex(ch.NewField(name)), name)
+	}
+}
+
+func TestCSVHeaderBind(t *testing.T) {
+	h := NewCSVHeader([]string{"name", "age"})
+
+	q, err := ch.QueryFromString("SELECT name, $1 FROM x WHERE age > 20 AND nope = 1")
+	require.Nil(t, err)
+
+	b := h.Bind(q)
+
+	r := NewCSVRecordWithCSVHeader([]string{"a", "42"}, h)
+
+	// the unbound field is still looked up by name
+	_, err = b.Evaluate(r)
+	assert.NotNil(t, err)
+
+	q, err = ch.QueryFromString("SELECT name, $1 FROM x WHERE age > 20")
+	require.Nil(t, err)
+
+	b = h.Bind(q)
+
+	m, err := b.Evaluate(r)
+	require.Nil(t, err)
+	assert.True(t, m)
+
+	values, err := b.FieldsValues(r)
+	require.Nil(t, err)
+	assert.Equal(t, []*ch.Const{ch.StringConst("a"), ch.IntConst(42)}, values)
+
+	// records without a header use the bound indexes too
+	values, err = b.FieldsValues(NewCSVRecord([]string{"b", "12"}))
+	require.Nil(t, err)
+	assert.Equal(t, []*ch.Const{ch.StringConst("b"), ch.IntConst(12)}, values)
+}
@@ -0,0 +1,370 @@
+package record
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// CSVType is the type of the values of a CSV column
+type CSVType int
+
+const (
+	// CSVAuto guesses the type of each value independently, as
+	// charlatan.ConstFromString does
+	CSVAuto CSVType = iota
+	// CSVString keeps the values as strings
+	CSVString
+	// CSVInt reads integers, as bigints if they don't fit in 64 bits
+	CSVInt
+	// CSVFloat reads numbers as floats, or as decimals if the record's
+	// UseDecimals attribute is set
+	CSVFloat
+	// CSVDecimal reads numbers as decimals
+	CSVDecimal
+	// CSVBool reads true and false, in any case
+	CSVBool
+	// CSVTimestamp reads timestamps, see charlatan.ParseTimestamp
+	CSVTimestamp
+	// CSVDuration reads durations, see charlatan.ParseDuration
+	CSVDuration
+)
+
+var csvTypeNames = map[CSVType]string{
+	CSVAuto:      "auto",
+	CSVString:    "string",
+	CSVInt:       "int",
+	CSVFloat:     "float",
+	CSVDecimal:   "decimal",
+	CSVBool:      "bool",
+	CSVTimestamp: "timestamp",
+	CSVDuration:  "duration",
+}
+
+// the types inference tries, from the most specific one
+var inferredCSVTypes = []CSVType{CSVInt, CSVFloat, CSVBool, CSVTimestamp}
+
+// DefaultCSVNullTokens are the values read as null when a schema doesn't
+// define its own
+var DefaultCSVNullTokens = []string{"NULL"}
+
+// ParseCSVType returns the type with the given name, e.g. "int"
+func ParseCSVType(name string) (CSVType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	for t, n := range csvTypeNames {
+		if n == name {
+			return t, nil
+		}
+	}
+
+	return CSVAuto, fmt.Errorf("Unknown CSV column type: %s", name)
+}
+
+// String returns the name of the type
+func (t CSVType) String() string {
+	if name, ok := csvTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("CSVType(%d)", int(t))
+}
+
+// CSVColumn is a column of a CSV schema
+type CSVColumn struct {
+	Name string
+	Type CSVType
+}
+
+// CSVSchema gives the types of the columns of a CSV file, so that all the
+// values of a column have the same type. Columns it doesn't list, e.g. extra
+// columns at the end of a row, are read as with CSVAuto.
+type CSVSchema struct {
+	Columns []CSVColumn
+	// NullTokens are the values read as null in any column. If nil,
+	// DefaultCSVNullTokens is used; use an empty slice to have no null
+	// values.
+	NullTokens []string
+}
+
+// ParseCSVSchema parses a schema made of comma-separated columns with their
+// types, e.g. "Year:int,Value:float,Code:string". Columns without a type are
+// strings.
+func ParseCSVSchema(s string) (*CSVSchema, error) {
+	schema := &CSVSchema{}
+	names := make(map[string]bool)
+
+	for _, column := range strings.Split(s, ",") {
+		name, typeName := column, ""
+		if i := strings.LastIndexByte(column, ':'); i >= 0 {
+			name, typeName = column[:i], column[i+1:]
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("Empty column name in CSV schema %s", s)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("Duplicate column %s in CSV schema %s", name, s)
+		}
+		names[name] = true
+
+		t := CSVString
+		if typeName != "" {
+			var err error
+			if t, err = ParseCSVType(typeName); err != nil {
+				return nil, err
+			}
+		}
+
+		schema.Columns = append(schema.Columns, CSVColumn{Name: name, Type: t})
+	}
+
+	return schema, nil
+}
+
+// InferCSVSchema infers the types of the columns from a sample of rows. Each
+// column gets the most specific type all its non-null values have: int,
+// float, bool, timestamp, or string otherwise. Numbers with leading zeros,
+// e.g. ZIP codes, are strings. Columns with only null values are strings.
+func InferCSVSchema(header []string, rows [][]string, nullTokens []string) *CSVSchema {
+	schema := &CSVSchema{NullTokens: nullTokens}
+
+	width := len(header)
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	for i := 0; i < width; i++ {
+		column := CSVColumn{Name: fmt.Sprintf("$%d", i), Type: CSVString}
+		if i < len(header) {
+			column.Name = header[i]
+		}
+
+		var values []string
+		for _, row := range rows {
+			if i < len(row) && !schema.IsNull(row[i]) {
+				values = append(values, row[i])
+			}
+		}
+
+		if len(values) > 0 {
+			column.Type = inferCSVType(values)
+		}
+
+		schema.Columns = append(schema.Columns, column)
+	}
+
+	return schema
+}
+
+// inferCSVType returns the most specific type all the values have
+func inferCSVType(values []string) CSVType {
+	for _, t := range inferredCSVTypes {
+		ok := true
+
+		for _, value := range values {
+			if !inferredAs(value, t) {
+				ok = false
+				break
+			}
+		}
+
+		if ok {
+			return t
+		}
+	}
+
+	return CSVString
+}
+
+// inferredAs tests if a value looks like a value of the given type. It's
+// stricter than the conversion, e.g. it excludes leading zeros and "NaN".
+func inferredAs(value string, t CSVType) bool {
+	switch t {
+	case CSVInt:
+		if hasLeadingZero(value) {
+			return false
+		}
+		_, ok := new(big.Int).SetString(value, 10)
+		return ok
+	case CSVFloat:
+		if hasLeadingZero(value) {
+			return false
+		}
+		_, err := ch.DecimalConstFromString(value)
+		return err == nil
+	case CSVBool:
+		_, err := parseCSVBool(value)
+		return err == nil
+	case CSVTimestamp:
+		_, err := ch.ParseTimestamp(value)
+		return err == nil
+	}
+
+	return false
+}
+
+// hasLeadingZero tests if a number starts with a zero followed by a digit
+func hasLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0' && s[1] >= '0' && s[1] <= '9'
+}
+
+func parseCSVBool(s string) (bool, error) {
+	switch {
+	case strings.EqualFold(s, "true"):
+		return true, nil
+	case strings.EqualFold(s, "false"):
+		return false, nil
+	}
+	return false, fmt.Errorf("Invalid bool: %s", s)
+}
+
+// WithHeader returns a schema with the columns of the header, in the same
+// order. The types are taken from this schema, columns it doesn't have are
+// strings. It fails if a column of this schema isn't in the header.
+func (s *CSVSchema) WithHeader(header []string) (*CSVSchema, error) {
+	types := make(map[string]CSVType, len(s.Columns))
+	for _, column := range s.Columns {
+		types[column.Name] = column.Type
+	}
+
+	aligned := &CSVSchema{NullTokens: s.NullTokens}
+
+	for _, name := range header {
+		t, ok := types[name]
+		if !ok {
+			t = CSVString
+		}
+		delete(types, name)
+
+		aligned.Columns = append(aligned.Columns, CSVColumn{Name: name, Type: t})
+	}
+
+	for _, column := range s.Columns {
+		if _, ok := types[column.Name]; ok {
+			return nil, fmt.Errorf("Column %s of the CSV schema isn't in the header", column.Name)
+		}
+	}
+
+	return aligned, nil
+}
+
+// IsNull tests if a value is one of the null tokens
+func (s *CSVSchema) IsNull(value string) bool {
+	tokens := s.NullTokens
+	if tokens == nil {
+		tokens = DefaultCSVNullTokens
+	}
+
+	for _, token := range tokens {
+		if value == token {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ColumnIndex returns the index of the column with the given name, or -1 if
+// there's none. A case-sensitive match is preferred, otherwise the first
+// case-insensitive one is used.
+func (s *CSVSchema) ColumnIndex(name string) int {
+	names := make([]string, len(s.Columns))
+	for i, column := range s.Columns {
+		names[i] = column.Name
+	}
+
+	return columnIndex(names, name)
+}
+
+// Convert converts a value of the column at the given index to a constant of
+// the column type. It fails if the value doesn't have this type.
+func (s *CSVSchema) Convert(index int, value string, useDecimals bool) (*ch.Const, error) {
+	if s.IsNull(value) {
+		return ch.NullConst(), nil
+	}
+
+	if index < 0 || index >= len(s.Columns) {
+		return convertCSVValue(value, CSVAuto, useDecimals)
+	}
+
+	column := s.Columns[index]
+
+	c, err := convertCSVValue(value, column.Type, useDecimals)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s value in column %s: %s", column.Type, column.Name, value)
+	}
+
+	return c, nil
+}
+
+// String returns the schema in the format ParseCSVSchema reads
+func (s *CSVSchema) String() string {
+	columns := make([]string, len(s.Columns))
+	for i, column := range s.Columns {
+		columns[i] = column.Name + ":" + column.Type.String()
+	}
+	return strings.Join(columns, ",")
+}
+
+// convertCSVValue converts a value to a constant of the given type
+func convertCSVValue(value string, t CSVType, useDecimals bool) (*ch.Const, error) {
+	switch t {
+	case CSVString:
+		return ch.StringConst(value), nil
+
+	case CSVInt:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return ch.IntConst(i), nil
+		}
+		if b, ok := new(big.Int).SetString(value, 10); ok {
+			return ch.BigIntConst(b), nil
+		}
+		return nil, fmt.Errorf("Invalid int: %s", value)
+
+	case CSVFloat:
+		if useDecimals {
+			return ch.DecimalConstFromString(value)
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return ch.FloatConst(f), nil
+
+	case CSVDecimal:
+		return ch.DecimalConstFromString(value)
+
+	case CSVBool:
+		b, err := parseCSVBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return ch.BoolConst(b), nil
+
+	case CSVTimestamp:
+		ts, err := ch.ParseTimestamp(value)
+		if err != nil {
+			return nil, err
+		}
+		return ch.TimestampConst(ts), nil
+
+	case CSVDuration:
+		d, err := ch.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		return ch.DurationConst(d), nil
+	}
+
+	if useDecimals {
+		return ch.ConstFromStringDecimal(value), nil
+	}
+	return ch.ConstFromString(value), nil
+}
@@ -0,0 +1,145 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSVType(t *testing.T) {
+	for name, expected := range map[string]CSVType{
+		"auto":      CSVAuto,
+		"string":    CSVString,
+		"INT":       CSVInt,
+		" float ":   CSVFloat,
+		"decimal":   CSVDecimal,
+		"bool":      CSVBool,
+		"timestamp": CSVTimestamp,
+		"duration":  CSVDuration,
+	} {
+		actual, err := ParseCSVType(name)
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, actual, name)
+		}
+	}
+
+	_, err := ParseCSVType("integer")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "timestamp", CSVTimestamp.String())
+	assert.Equal(t, "CSVType(42)", CSVType(42).String())
+}
+
+func TestParseCSVSchema(t *testing.T) {
+	s, err := ParseCSVSchema("Year:int, Value:float,Code:string,Name,a:b:bool")
+	require.Nil(t, err)
+
+	assert.Equal(t, []CSVColumn{
+		{"Year", CSVInt},
+		{"Value", CSVFloat},
+		{"Code", CSVString},
+		{"Name", CSVString},
+		{"a:b", CSVBool},
+	}, s.Columns)
+	assert.Equal(t, "Year:int,Value:float,Code:string,Name:string,a:b:bool", s.String())
+
+	for _, invalid := range []string{"", "a:int,", ":int", "a:integer", "a,b,a"} {
+		_, err := ParseCSVSchema(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestInferCSVSchema(t *testing.T) {
+	s := InferCSVSchema(
+		[]string{"id", "zip", "price", "ok", "date", "name", "empty"},
+		[][]string{
+			{"1", "01234", "3", "true", "2016-12-28", "a", "NULL"},
+			{"2", "75001", "4.5", "FALSE", "2016-12-28T10:00:00Z", "42", ""},
+			{"-3", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "extra"},
+		},
+		nil,
+	)
+
+	assert.Equal(t, []CSVColumn{
+		{"id", CSVInt},
+		{"zip", CSVString},
+		{"price", CSVFloat},
+		{"ok", CSVBool},
+		{"date", CSVTimestamp},
+		{"name", CSVString},
+		{"empty", CSVString},
+		{"$7", CSVString},
+	}, s.Columns)
+
+	// custom null tokens
+	s = InferCSVSchema([]string{"a"}, [][]string{{"1"}, {"NA"}, {""}}, []string{"NA", ""})
+	assert.Equal(t, CSVInt, s.Columns[0].Type)
+
+	// "NaN" and "Inf" aren't inferred as floats
+	s = InferCSVSchema([]string{"a", "b"}, [][]string{{"1.5", "1"}, {"NaN", "Inf"}}, nil)
+	assert.Equal(t, CSVString, s.Columns[0].Type)
+	assert.Equal(t, CSVString, s.Columns[1].Type)
+}
+
+func TestCSVSchemaWithHeader(t *testing.T) {
+	s, err := ParseCSVSchema("Value:float,Year:int")
+	require.Nil(t, err)
+	s.NullTokens = []string{"NA"}
+
+	aligned, err := s.WithHeader([]string{"Year", "Code", "Value"})
+	require.Nil(t, err)
+	assert.Equal(t, "Year:int,Code:string,Value:float", aligned.String())
+	assert.Equal(t, []string{"NA"}, aligned.NullTokens)
+
+	_, err = s.WithHeader([]string{"Year", "Code"})
+	assert.NotNil(t, err)
+}
+
+func TestCSVSchemaConvert(t *testing.T) {
+	s, err := ParseCSVSchema("a:int,b:float,c:decimal,d:bool,e:timestamp,f:duration,g:string,h:auto")
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		index       int
+		value       string
+		useDecimals bool
+		expected    string
+	}{
+		{0, "42", false, "42"},
+		{0, "99999999999999999999", false, "99999999999999999999"},
+		{1, "0.125", true, "0.125"},
+		{2, "0.125", false, "0.125"},
+		{3, "True", false, "true"},
+		{4, "2016-12-28", false, "2016-12-28T00:00:00Z"},
+		{5, "1h30m", false, "1h30m0s"},
+		{6, "01234", false, "01234"},
+		{7, "01234", false, "1234"},
+		// columns which aren't in the schema
+		{8, "true", false, "true"},
+	} {
+		c, err := s.Convert(tc.index, tc.value, tc.useDecimals)
+		if assert.Nil(t, err, tc.value) {
+			assert.Equal(t, tc.expected, c.AsString(), tc.value)
+		}
+	}
+
+	c, err := s.Convert(1, "0.125", false)
+	require.Nil(t, err)
+	assert.Equal(t, 0.125, c.Value())
+
+	c, err = s.Convert(0, "NULL", false)
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+
+	for index, value := range []string{"4.5", "x", "x", "yes", "x", "x"} {
+		_, err := s.Convert(index, value, false)
+		assert.NotNil(t, err, value)
+	}
+
+	// no null tokens at all
+	s.NullTokens = []string{}
+	c, err = s.Convert(6, "NULL", false)
+	require.Nil(t, err)
+	assert.Equal(t, "NULL", c.AsString())
+}
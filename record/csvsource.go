@@ -0,0 +1,290 @@
+package record
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// CSVSource reads CSVRecords from a CSV file, one row at a time. Its
+// attributes must be set before the first call to Next:
+//   - Delimiter separates the values, ',' by default, e.g. '\t' for TSV
+//   - Comment starts the lines that are skipped, if it's not 0
+//   - LazyQuotes allows quotes in unquoted values and unescaped quotes in
+//     quoted ones
+//   - TrimSpace trims the spaces around the values, quoted values are kept
+//     as written
+//   - NoHeader reads the first row as a record instead of the header, the
+//     columns are then only named "$N"
+//   - Schema gives the types of the columns, see CSVSchema. Its columns are
+//     matched with the header ones by name, unless there's no header.
+//   - InferSchema infers the schema from this number of rows if there's no
+//     Schema, see InferCSVSchema
+//   - NullTokens are the values read as null if there's no Schema, "NULL" by
+//     default
+//   - UseDecimals is set on the records
+//
+// Duplicate column names in the header get a suffix: the second "a" column is
+// named "a_2", the third one "a_3", etc. The header is indexed once and shared
+// by all the records.
+type CSVSource struct {
+	input  io.Reader
+	reader *csv.Reader
+	// the lines read, to tell the quoted values apart, only with TrimSpace
+	lines  *csvLines
+	names  []string
+	header *CSVHeader
+	schema *CSVSchema
+	// rows read to infer the schema, which haven't been returned yet
+	buffer  [][]string
+	started bool
+	// the error which happened while starting, if any
+	err error
+
+	Delimiter   rune
+	Comment     rune
+	LazyQuotes  bool
+	TrimSpace   bool
+	NoHeader    bool
+	Schema      *CSVSchema
+	InferSchema int
+	NullTokens  []string
+	UseDecimals bool
+}
+
+// NewCSVSource returns a source which reads the CSV file from the reader
+func NewCSVSource(r io.Reader) *CSVSource {
+	return &CSVSource{input: r, Delimiter: ','}
+}
+
+// Header returns the column names, nil if there's no header
+func (s *CSVSource) Header() ([]string, error) {
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	return s.names, nil
+}
+
+// Bind returns a copy of the query where the fields are bound to the index of
+// their column, see CSVHeader.Bind, so that the records of this source get
+// their values without looking their columns up
+func (s *CSVSource) Bind(query *ch.Query) (*ch.Query, error) {
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	return s.header.Bind(query), nil
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *CSVSource) Next() (*CSVRecord, error) {
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+
+	var row []string
+
+	if len(s.buffer) > 0 {
+		row, s.buffer = s.buffer[0], s.buffer[1:]
+	} else {
+		var err error
+		if row, err = s.read(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CSVRecord{
+		header:      s.header,
+		record:      row,
+		schema:      s.schema,
+		UseDecimals: s.UseDecimals,
+	}, nil
+}
+
+// start sets the source up on the first call
+func (s *CSVSource) start() error {
+	if !s.started {
+		s.started = true
+		s.err = s.setup()
+	}
+	return s.err
+}
+
+// setup configures the reader, reads the header and sets up the schema
+func (s *CSVSource) setup() error {
+	if s.TrimSpace {
+		s.lines = &csvLines{r: s.input, first: 1}
+		s.reader = csv.NewReader(s.lines)
+	} else {
+		s.reader = csv.NewReader(s.input)
+	}
+
+	s.reader.Comma = s.Delimiter
+	s.reader.Comment = s.Comment
+	s.reader.LazyQuotes = s.LazyQuotes
+	s.reader.TrimLeadingSpace = s.TrimSpace
+
+	if !s.NoHeader {
+		header, err := s.read()
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if len(header) > 0 {
+			// a byte order mark isn't part of the first column name
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+
+		s.names = uniqueColumnNames(header)
+	}
+
+	switch {
+	case s.Schema != nil && s.names != nil:
+		schema, err := s.Schema.WithHeader(s.names)
+		if err != nil {
+			return err
+		}
+		s.schema = schema
+
+	case s.Schema != nil:
+		s.schema = s.Schema
+
+	case s.InferSchema > 0:
+		for len(s.buffer) < s.InferSchema {
+			row, err := s.read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			s.buffer = append(s.buffer, row)
+		}
+
+		s.schema = InferCSVSchema(s.names, s.buffer, s.NullTokens)
+
+	case s.NullTokens != nil:
+		s.schema = &CSVSchema{NullTokens: s.NullTokens}
+	}
+
+	names := s.names
+	if names == nil && s.schema != nil {
+		// without a header, the schema gives the column names
+		for _, column := range s.schema.Columns {
+			names = append(names, column.Name)
+		}
+	}
+
+	s.header = NewCSVHeader(names)
+
+	return nil
+}
+
+// read reads the next row
+func (s *CSVSource) read() ([]string, error) {
+	row, err := s.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.TrimSpace {
+		for i, value := range row {
+			if line, column := s.reader.FieldPos(i); !s.lines.quoted(line, column) {
+				row[i] = strings.TrimRight(value, " \t")
+			}
+		}
+
+		line, _ := s.reader.FieldPos(len(row) - 1)
+		s.lines.release(line)
+	}
+
+	return row, nil
+}
+
+// csvLines records the lines read from a CSV file, so that the quoted values
+// can be told apart from the other ones. It only relies on csv.Reader.FieldPos,
+// which gives the line and the byte column where a value starts, its opening
+// quote included. Lines are split on '\n', as csv.Reader counts them, the '\r'
+// of CRLF line endings is kept at their end.
+type csvLines struct {
+	r io.Reader
+	// the complete lines from the first one which is kept, then the line
+	// being read
+	lines   [][]byte
+	first   int
+	partial []byte
+}
+
+func (l *csvLines) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+
+	data := p[:n]
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		l.lines = append(l.lines, append(l.partial, data[:i]...))
+		l.partial = nil
+		data = data[i+1:]
+	}
+	l.partial = append(l.partial, data...)
+
+	return n, err
+}
+
+// quoted tests if the value at the given position, as returned by
+// csv.Reader.FieldPos, starts with a quote
+func (l *csvLines) quoted(line, column int) bool {
+	b := l.partial
+	if i := line - l.first; i < len(l.lines) {
+		b = l.lines[i]
+	}
+
+	return column-1 < len(b) && b[column-1] == '"'
+}
+
+// release forgets the lines before the given one
+func (l *csvLines) release(line int) {
+	if n := line - l.first; n > 0 && n <= len(l.lines) {
+		l.lines = l.lines[n:]
+		l.first = line
+	}
+}
+
+// uniqueColumnNames adds a suffix to the duplicate column names
+func uniqueColumnNames(header []string) []string {
+	if header == nil {
+		return nil
+	}
+
+	names := make(map[string]bool, len(header))
+	for _, name := range header {
+		names[name] = true
+	}
+
+	seen := make(map[string]bool, len(header))
+	unique := make([]string, len(header))
+
+	for i, name := range header {
+		if !seen[name] {
+			seen[name] = true
+			unique[i] = name
+			continue
+		}
+
+		for n := 2; ; n++ {
+			candidate := fmt.Sprintf("%s_%d", name, n)
+			if !names[candidate] && !seen[candidate] {
+				seen[candidate] = true
+				unique[i] = candidate
+				break
+			}
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,245 @@
+package record
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVSourceDefaults(t *testing.T) {
+	s := NewCSVSource(strings.NewReader("name,age\na,12\nb,NULL\n"))
+
+	header, err := s.Header()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"name", "age"}, header)
+
+	assert.Equal(t, []interface{}{int64(12), nil}, readColumn(t, s.Next, "age"))
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCSVSourceEmpty(t *testing.T) {
+	s := NewCSVSource(strings.NewReader(""))
+
+	header, err := s.Header()
+	require.Nil(t, err)
+	assert.Nil(t, header)
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCSVSourceDialect(t *testing.T) {
+	s := NewCSVSource(strings.NewReader(
+		"\ufeffname\tage\n" +
+			"# a comment\n" +
+			"  a \t 12  \n" +
+			"b\"c\t13\n"))
+	s.Delimiter = '\t'
+	s.Comment = '#'
+	s.LazyQuotes = true
+	s.TrimSpace = true
+
+	header, err := s.Header()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"name", "age"}, header)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a", "12"}, r.record)
+
+	r, err = s.Next()
+	require.Nil(t, err)
+	assert.Equal(t, []string{`b"c`, "13"}, r.record)
+}
+
+func TestCSVSourceTrimSpaceKeepsQuotedValues(t *testing.T) {
+	s := NewCSVSource(strings.NewReader(
+		"name,note\n" +
+			"a  ,  \"b  \"\n" +
+			"\"c\nd  \",e \r\n" +
+			"  \"f \",g"))
+	s.TrimSpace = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a", "b  "}, r.record)
+
+	r, err = s.Next()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"c\nd  ", "e"}, r.record)
+
+	r, err = s.Next()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"f ", "g"}, r.record)
+}
+
+func TestCSVSourceTrimSpaceQuotedEdgeCases(t *testing.T) {
+	for input, expected := range map[string][][]string{
+		// a quoted value which starts on a continuation line
+		"a,b\n\"x\n  y \",\"z\n \"\nc ,d \n": {{"x\n  y ", "z\n "}, {"c", "d"}},
+		// escaped quotes
+		"a,b\n\"say \"\"hi\"\"  \",plain  \n  \"\"\"\",e \n": {{`say "hi"  `, "plain"}, {`"`, "e"}},
+		// CRLF line endings
+		"a,b\r\n\"x \",y  \r\n\"p\r\nq \", r \r\n": {{"x ", "y"}, {"p\nq ", "r"}},
+		// comment lines
+		"a,b\n# \"c\" \n\"x \",y \n": {{"x ", "y"}},
+	} {
+		s := NewCSVSource(strings.NewReader(input))
+		s.TrimSpace = true
+		s.Comment = '#'
+
+		for _, row := range expected {
+			r, err := s.Next()
+			require.Nil(t, err, input)
+			assert.Equal(t, row, r.record, input)
+		}
+
+		_, err := s.Next()
+		assert.Equal(t, io.EOF, err, input)
+	}
+}
+
+// the lines are released as the rows are read, beyond the reader's buffer
+func TestCSVSourceTrimSpaceLongInput(t *testing.T) {
+	var b strings.Builder
+
+	b.WriteString("a,b\n")
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, "\"v\n%d \",w%d  \n", i, i)
+	}
+
+	s := NewCSVSource(strings.NewReader(b.String()))
+	s.TrimSpace = true
+
+	for i := 0; i < 2000; i++ {
+		r, err := s.Next()
+		require.Nil(t, err)
+		require.Equal(t, []string{fmt.Sprintf("v\n%d ", i), fmt.Sprintf("w%d", i)}, r.record)
+	}
+
+	_, err := s.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.True(t, len(s.lines.lines) < 10)
+}
+
+func TestCSVSourceSemicolons(t *testing.T) {
+	s := NewCSVSource(strings.NewReader("a;b\n1;2\n"))
+	s.Delimiter = ';'
+
+	assert.Equal(t, []interface{}{int64(2)}, readColumn(t, s.Next, "b"))
+}
+
+func TestCSVSourceNoHeader(t *testing.T) {
+	s := NewCSVSource(strings.NewReader("a,1\nb,2\n"))
+	s.NoHeader = true
+
+	header, err := s.Header()
+	require.Nil(t, err)
+	assert.Nil(t, header)
+
+	assert.Equal(t, []interface{}{"a", "b"}, readColumn(t, s.Next, "$0"))
+}
+
+func TestCSVSourceDuplicateHeader(t *testing.T) {
+	s := NewCSVSource(strings.NewReader("a,b,a,a_2,a\n1,2,3,4,5\n"))
+
+	header, err := s.Header()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "a_3", "a_2", "a_4"}, header)
+
+	assert.Equal(t, []interface{}{int64(3)}, readColumn(t, s.Next, "a_3"))
+}
+
+func TestCSVSourceSchema(t *testing.T) {
+	schema, err := ParseCSVSchema("zip:string")
+	require.Nil(t, err)
+
+	s := NewCSVSource(strings.NewReader("id,zip\n1,75001\n"))
+	s.Schema = schema
+
+	assert.Equal(t, []interface{}{"75001"}, readColumn(t, s.Next, "zip"))
+
+	// the schema columns must be in the header
+	s = NewCSVSource(strings.NewReader("id,code\n1,75001\n"))
+	s.Schema = schema
+
+	_, err = s.Next()
+	assert.NotNil(t, err)
+	_, err = s.Header()
+	assert.NotNil(t, err)
+
+	// without a header the schema gives the column names
+	s = NewCSVSource(strings.NewReader("75001\n"))
+	s.NoHeader = true
+	s.Schema = schema
+
+	assert.Equal(t, []interface{}{"75001"}, readColumn(t, s.Next, "zip"))
+}
+
+func TestCSVSourceInferSchema(t *testing.T) {
+	s := NewCSVSource(strings.NewReader("zip,n\n01234,1\n75001,NA\n75002,3\n"))
+	s.InferSchema = 2
+	s.NullTokens = []string{"NA"}
+
+	r, err := s.Next()
+	require.Nil(t, err)
+	assert.Equal(t, "zip:string,n:int", r.schema.String())
+
+	assert.Equal(t, []interface{}{"75001", "75002"}, readColumn(t, s.Next, "zip"))
+}
+
+func TestCSVSourceNullTokens(t *testing.T) {
+	s := NewCSVSource(strings.NewReader("a,b\n,NULL\n"))
+	s.NullTokens = []string{""}
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	v, err := r.Find(ch.NewField("a"))
+	require.Nil(t, err)
+	assert.True(t, v.IsNull())
+
+	v, err = r.Find(ch.NewField("b"))
+	require.Nil(t, err)
+	assert.Equal(t, "NULL", v.AsString())
+}
+
+func TestCSVSourceBind(t *testing.T) {
+	s := NewCSVSource(strings.NewReader("Name,Age\na,12\nb,42\n"))
+
+	q, err := ch.QueryFromString("SELECT name FROM x WHERE age > 20")
+	require.Nil(t, err)
+
+	q, err = s.Bind(q)
+	require.Nil(t, err)
+
+	var names []*ch.Const
+
+	for {
+		r, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+
+		if m, err := q.Evaluate(r); assert.Nil(t, err) && m {
+			values, err := q.FieldsValues(r)
+			require.Nil(t, err)
+			names = append(names, values...)
+		}
+	}
+
+	assert.Equal(t, []*ch.Const{ch.StringConst("b")}, names)
+
+	s = NewCSVSource(strings.NewReader("\"a,b\n"))
+	_, err = s.Bind(q)
+	assert.NotNil(t, err)
+}
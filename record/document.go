@@ -0,0 +1,231 @@
+package record
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// DocumentRecord is a record for objects decoded from structured documents,
+// e.g. YAML or TOML ones.
+//
+// Its values keep the type they were decoded with, and arrays and objects are
+// returned as array and object constants. Field paths go into objects and
+// arrays the same way.
+//
+// It supports the special field "*", which returns the object as JSON.
+//
+// If the SoftMatching attribute is set to true, non-existing fields are
+// returned as null constants instead of failing with an error.
+//
+// If the ParseStrings attribute is set to true, the strings a field selects,
+// including the elements of slices and wildcards, are parsed with
+// charlatan.ConstFromString like the JSONRecord ones, e.g. a quoted "8080" is
+// an int. It suits the formats whose strings are often quoted numbers, e.g.
+// YAML. If the UseDecimals attribute is also set to true, the non-integer
+// numbers they hold are returned as decimals instead of floats.
+type DocumentRecord struct {
+	value *ch.Const
+
+	SoftMatching bool
+	ParseStrings bool
+	UseDecimals  bool
+}
+
+var _ ch.Record = &DocumentRecord{}
+
+// NewDocumentRecord returns a new DocumentRecord for the given object
+func NewDocumentRecord(value *ch.Const) (*DocumentRecord, error) {
+	if !value.IsObject() {
+		return nil, fmt.Errorf("A document record must be an object, got %s", value)
+	}
+
+	return &DocumentRecord{value: value}, nil
+}
+
+// NewDocumentRecordFromValue returns a new DocumentRecord for an object
+// decoded by a library, converted with DocumentValueToConst
+func NewDocumentRecordFromValue(value interface{}, useDecimals bool) (*DocumentRecord, error) {
+	c, err := DocumentValueToConst(value, useDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDocumentRecord(c)
+}
+
+// Find implements the charlatan.Record interface
+func (r *DocumentRecord) Find(field *ch.Field) (*ch.Const, error) {
+	return lookupField(r.value, field, lookupOptions{
+		softMatching: r.SoftMatching,
+		parseStrings: r.ParseStrings,
+		useDecimals:  r.UseDecimals,
+	})
+}
+
+// lookupOptions are the options of lookupField
+type lookupOptions struct {
+	softMatching bool
+	// index and wildcard segments treat the values which aren't arrays as
+	// one-element arrays, see charlatan.FieldPath.LookupRepeated
+	repeated bool
+	// strings are parsed, as decimals if useDecimals is true
+	parseStrings, useDecimals bool
+}
+
+// lookupField returns the value of a field in an object, for the records
+// which hold a whole object
+func lookupField(value *ch.Const, field *ch.Field, opts lookupOptions) (*ch.Const, error) {
+	var name string
+
+	if name = field.Name(); len(name) == 0 {
+		return nil, errEmptyField
+	}
+
+	// support for "SELECT *"
+	if name == "*" {
+		return ch.StringConst(value.AsString()), nil
+	}
+
+	lookup := field.Path().Lookup
+	if opts.repeated {
+		lookup = field.Path().LookupRepeated
+	}
+
+	c, ok := lookup(value)
+	if !ok {
+		if opts.softMatching {
+			return ch.NullConst(), nil
+		}
+		return nil, fmt.Errorf("Unknown '%s' field", name)
+	}
+
+	if opts.parseStrings {
+		return parseSelectedStrings(c, field.Path(), opts.useDecimals), nil
+	}
+
+	return c, nil
+}
+
+// DocumentValueToConst converts a value decoded by a library to a constant,
+// whatever the format it was decoded from. Map keys which aren't strings are
+// formatted, unsigned integers too large for an int are bigints, and byte
+// strings are strings. Floats are converted to decimals if useDecimals is
+// true.
+func DocumentValueToConst(value interface{}, useDecimals bool) (*ch.Const, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		members := make(map[string]*ch.Const, len(v))
+		for key, member := range v {
+			c, err := DocumentValueToConst(member, useDecimals)
+			if err != nil {
+				return nil, err
+			}
+			members[key] = c
+		}
+		return ch.ObjectConst(members), nil
+
+	case map[interface{}]interface{}:
+		members := make(map[string]*ch.Const, len(v))
+		for key, member := range v {
+			c, err := DocumentValueToConst(member, useDecimals)
+			if err != nil {
+				return nil, err
+			}
+			members[fmt.Sprint(key)] = c
+		}
+		return ch.ObjectConst(members), nil
+
+	case []map[string]interface{}:
+		elements := make([]*ch.Const, len(v))
+		for i, element := range v {
+			c, err := DocumentValueToConst(element, useDecimals)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = c
+		}
+		return ch.ArrayConst(elements), nil
+
+	case []interface{}:
+		elements := make([]*ch.Const, len(v))
+		for i, element := range v {
+			c, err := DocumentValueToConst(element, useDecimals)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = c
+		}
+		return ch.ArrayConst(elements), nil
+
+	case float64:
+		return FloatToConst(v, useDecimals), nil
+
+	case float32:
+		return Float32ToConst(v, useDecimals), nil
+
+	case time.Time:
+		return ch.TimestampConst(v), nil
+
+	case []byte:
+		return ch.StringConst(string(v)), nil
+
+	case *big.Int:
+		return ch.BigIntConst(new(big.Int).Set(v)), nil
+
+	case big.Int:
+		return ch.BigIntConst(new(big.Int).Set(&v)), nil
+	}
+
+	rv := reflect.ValueOf(value)
+
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u > math.MaxInt64 {
+			return ch.BigIntConst(new(big.Int).SetUint64(u)), nil
+		}
+		return ch.IntConst(int64(rv.Uint())), nil
+
+	case reflect.Array:
+		// fixed-size byte strings, e.g. Avro fixed values
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return ch.StringConst(string(b)), nil
+		}
+	}
+
+	return ch.NewConst(value)
+}
+
+// FloatToConst converts a float to a constant, as a decimal if useDecimals is
+// true and it's a finite number
+func FloatToConst(f float64, useDecimals bool) *ch.Const {
+	if useDecimals && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		if d, err := ch.DecimalConstFromString(strconv.FormatFloat(f, 'g', -1, 64)); err == nil {
+			return d
+		}
+	}
+
+	return ch.FloatConst(f)
+}
+
+// Float32ToConst converts a float32 to a constant like FloatToConst, using its
+// shortest representation rather than the one of its float64 conversion, e.g.
+// 0.1 instead of 0.10000000149011612
+func Float32ToConst(f float32, useDecimals bool) *ch.Const {
+	f64, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	return FloatToConst(f64, useDecimals)
+}
+
+// FormatUUID formats a 16-byte UUID, e.g. "12345678-9abc-def0-0102-030405060708"
+func FormatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
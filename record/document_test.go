@@ -0,0 +1,148 @@
+package record
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentRecordFind(t *testing.T) {
+	c, err := DocumentValueToConst(map[string]interface{}{
+		"name":     "api",
+		"replicas": int64(4),
+		"port":     "8080",
+		"labels":   map[string]interface{}{"team": "core"},
+		"hosts":    []interface{}{"a", "b"},
+	}, false)
+	require.Nil(t, err)
+
+	r, err := NewDocumentRecord(c)
+	require.Nil(t, err)
+
+	for name, expected := range map[string]interface{}{
+		"name":        "api",
+		"replicas":    int64(4),
+		"port":        "8080",
+		"labels.team": "core",
+		"hosts[1]":    "b",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	c, err = r.Find(ch.NewField("*"))
+	require.Nil(t, err)
+	assert.Equal(t, `{"hosts":["a","b"],"labels":{"team":"core"},"name":"api","port":"8080","replicas":4}`, c.AsString())
+
+	for _, name := range []string{"", "nope", "labels.nope", "hosts[2]"} {
+		_, err := r.Find(ch.NewField(name))
+		assert.NotNil(t, err, name)
+	}
+
+	r.SoftMatching = true
+	c, err = r.Find(ch.NewField("nope"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
+
+func TestDocumentRecordParseStrings(t *testing.T) {
+	r, err := NewDocumentRecordFromValue(map[string]interface{}{
+		"port":  "8080",
+		"price": "0.10",
+		"zip":   "01234",
+		"hosts": []interface{}{"a"},
+	}, false)
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("port"))
+	require.Nil(t, err)
+	assert.Equal(t, "8080", c.Value())
+
+	r.ParseStrings = true
+
+	for name, expected := range map[string]interface{}{
+		"port":     int64(8080),
+		"price":    0.1,
+		"zip":      int64(1234),
+		"hosts[0]": "a",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	r.UseDecimals = true
+
+	c, err = r.Find(ch.NewField("price"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.10", c.AsString())
+}
+
+func TestNewDocumentRecordNotAnObject(t *testing.T) {
+	_, err := NewDocumentRecord(ch.IntConst(42))
+	assert.NotNil(t, err)
+}
+
+func TestDocumentValueToConst(t *testing.T) {
+	ts := time.Date(2016, 12, 28, 10, 0, 0, 0, time.UTC)
+
+	c, err := DocumentValueToConst(map[string]interface{}{
+		"tables": []map[string]interface{}{{"a": 1}},
+		"ts":     ts,
+		"f":      0.1,
+		"nan":    math.NaN(),
+	}, true)
+	require.Nil(t, err)
+
+	m := c.Members()
+	assert.Equal(t, `[{"a":1}]`, m["tables"].AsString())
+	assert.Equal(t, ts, m["ts"].Value())
+	assert.Equal(t, "0.1", m["f"].AsString())
+	assert.True(t, m["nan"].IsNumeric())
+
+	_, err = DocumentValueToConst([]interface{}{struct{}{}}, false)
+	assert.NotNil(t, err)
+}
+
+func TestDocumentValueToConstBinaryFormats(t *testing.T) {
+	bigPtr := new(big.Int).Lsh(big.NewInt(1), 65)
+
+	c, err := DocumentValueToConst(map[interface{}]interface{}{
+		1:       "one",
+		"u8":    uint8(7),
+		"u64":   uint64(math.MaxUint64),
+		"f32":   float32(0.1),
+		"bytes": []byte("hi"),
+		"fixed": [2]byte{'o', 'k'},
+		"big":   *new(big.Int).Lsh(big.NewInt(1), 70),
+		"bigp":  bigPtr,
+	}, false)
+	require.Nil(t, err)
+
+	m := c.Members()
+	assert.Equal(t, "one", m["1"].AsString())
+	assert.Equal(t, int64(7), m["u8"].Value())
+	assert.Equal(t, "18446744073709551615", m["u64"].AsString())
+	assert.Equal(t, 0.1, m["f32"].Value())
+	assert.Equal(t, "hi", m["bytes"].AsString())
+	assert.Equal(t, "ok", m["fixed"].AsString())
+	assert.Equal(t, "1180591620717411303424", m["big"].AsString())
+
+	// the bigints are copied
+	bigPtr.SetInt64(1)
+	assert.Equal(t, "36893488147419103232", m["bigp"].AsString())
+}
+
+func TestFormatUUID(t *testing.T) {
+	b := []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 8}
+	assert.Equal(t, "12345678-9abc-def0-0102-030405060708", FormatUUID(b))
+}
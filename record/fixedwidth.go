@@ -0,0 +1,128 @@
+package record
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FixedWidthColumn is a column of a fixed-width file: Width characters
+// starting at Start, the first character of a line being at 0
+type FixedWidthColumn struct {
+	Name         string
+	Start, Width int
+	Type         CSVType
+}
+
+// FixedWidthLayout slices the lines of a fixed-width file into columns. The
+// lines become CSVRecords, so their columns can be retrieved by name or as
+// "$N", and their values are converted as with a CSVSchema.
+type FixedWidthLayout struct {
+	columns []FixedWidthColumn
+	header  *CSVHeader
+	schema  *CSVSchema
+}
+
+// DefaultFixedWidthNullTokens are the values read as null when a layout
+// doesn't define its own: blank columns
+var DefaultFixedWidthNullTokens = []string{""}
+
+// NewFixedWidthLayout returns a layout with the given columns. The values
+// equal to a null token once trimmed are null, blank columns if nullTokens is
+// nil.
+func NewFixedWidthLayout(columns []FixedWidthColumn, nullTokens []string) (*FixedWidthLayout, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("A fixed-width layout needs at least one column")
+	}
+
+	if nullTokens == nil {
+		nullTokens = DefaultFixedWidthNullTokens
+	}
+
+	l := &FixedWidthLayout{
+		columns: columns,
+		schema:  &CSVSchema{NullTokens: nullTokens},
+	}
+
+	names := make([]string, len(columns))
+	seen := make(map[string]bool, len(columns))
+
+	for i, column := range columns {
+		if column.Name == "" {
+			return nil, fmt.Errorf("Empty name for the fixed-width column %d", i)
+		}
+		if seen[column.Name] {
+			return nil, fmt.Errorf("Duplicate fixed-width column %s", column.Name)
+		}
+		if column.Start < 0 || column.Width <= 0 {
+			return nil, fmt.Errorf("Invalid position for the fixed-width column %s", column.Name)
+		}
+
+		seen[column.Name] = true
+		names[i] = column.Name
+		l.schema.Columns = append(l.schema.Columns, CSVColumn{Name: column.Name, Type: column.Type})
+	}
+
+	l.header = NewCSVHeader(names)
+
+	return l, nil
+}
+
+// ParseFixedWidthLayout parses a layout made of comma-separated columns with
+// their start, width and type, e.g. "Year:0:4:int,Code:4:3:string,Name:7:20".
+// Columns without a type are strings.
+func ParseFixedWidthLayout(s string, nullTokens []string) (*FixedWidthLayout, error) {
+	var columns []FixedWidthColumn
+
+	for _, spec := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(spec), ":")
+		if len(parts) != 3 && len(parts) != 4 {
+			return nil, fmt.Errorf("Invalid fixed-width column %s, expected name:start:width[:type]", spec)
+		}
+
+		column := FixedWidthColumn{Name: parts[0], Type: CSVString}
+		var err error
+
+		if column.Start, err = strconv.Atoi(parts[1]); err != nil {
+			return nil, fmt.Errorf("Invalid start for the fixed-width column %s", spec)
+		}
+		if column.Width, err = strconv.Atoi(parts[2]); err != nil {
+			return nil, fmt.Errorf("Invalid width for the fixed-width column %s", spec)
+		}
+		if len(parts) == 4 {
+			if column.Type, err = ParseCSVType(parts[3]); err != nil {
+				return nil, err
+			}
+		}
+
+		columns = append(columns, column)
+	}
+
+	return NewFixedWidthLayout(columns, nullTokens)
+}
+
+// Header returns the header of the records, see CSVHeader.Bind
+func (l *FixedWidthLayout) Header() *CSVHeader {
+	return l.header
+}
+
+// Record slices a line into a record. Lines shorter than the layout have
+// blank columns, and the padding around the values is trimmed.
+func (l *FixedWidthLayout) Record(line string) *CSVRecord {
+	runes := []rune(line)
+	values := make([]string, len(l.columns))
+
+	for i, column := range l.columns {
+		start, end := column.Start, column.Start+column.Width
+		if start > len(runes) {
+			start = len(runes)
+		}
+		if end > len(runes) {
+			end = len(runes)
+		}
+
+		values[i] = strings.TrimSpace(string(runes[start:end]))
+	}
+
+	return &CSVRecord{header: l.header, record: values, schema: l.schema}
+}
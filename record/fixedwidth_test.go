@@ -0,0 +1,94 @@
+package record
+
+import (
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFixedWidthLayout(t *testing.T) {
+	l, err := ParseFixedWidthLayout("Year:0:4:int, Code:4:3,Name:7:10:string", nil)
+	require.Nil(t, err)
+
+	assert.Equal(t, []FixedWidthColumn{
+		{"Year", 0, 4, CSVInt},
+		{"Code", 4, 3, CSVString},
+		{"Name", 7, 10, CSVString},
+	}, l.columns)
+	assert.Equal(t, []string{"Year", "Code", "Name"}, l.Header().Names())
+
+	for _, invalid := range []string{
+		"",
+		"a:0",
+		"a:0:1:int:x",
+		"a:x:1",
+		"a:0:x",
+		"a:0:1:integer",
+		"a:-1:1",
+		"a:0:0",
+		":0:1",
+		"a:0:1,a:1:1",
+	} {
+		_, err := ParseFixedWidthLayout(invalid, nil)
+		assert.NotNil(t, err, invalid)
+	}
+
+	_, err = NewFixedWidthLayout(nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestFixedWidthLayoutRecord(t *testing.T) {
+	l, err := ParseFixedWidthLayout("Year:0:4:int,Code:4:5,Name:9:10,Amount:19:8:decimal", nil)
+	require.Nil(t, err)
+
+	r := l.Record("2016 0123 Café        12.50")
+
+	for name, expected := range map[string]string{
+		"Year":   "2016",
+		"Code":   "0123",
+		"$1":     "0123",
+		"Name":   "Café",
+		"name":   "Café",
+		"Amount": "12.50",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.AsString(), name)
+		}
+	}
+
+	// short lines have blank, null, columns
+	r = l.Record("2016 01")
+
+	c, err := r.Find(ch.NewField("Code"))
+	require.Nil(t, err)
+	assert.Equal(t, "01", c.AsString())
+
+	c, err = r.Find(ch.NewField("Amount"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+
+	_, err = l.Record("20X6").Find(ch.NewField("Year"))
+	assert.NotNil(t, err)
+
+	_, err = r.Find(ch.NewField("nope"))
+	assert.NotNil(t, err)
+}
+
+func TestFixedWidthLayoutNullTokens(t *testing.T) {
+	l, err := ParseFixedWidthLayout("a:0:3:int,b:3:3", []string{"N/A"})
+	require.Nil(t, err)
+
+	r := l.Record("N/A   ")
+
+	c, err := r.Find(ch.NewField("a"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+
+	c, err = r.Find(ch.NewField("b"))
+	require.Nil(t, err)
+	assert.Equal(t, "", c.AsString())
+}
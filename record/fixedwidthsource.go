@@ -0,0 +1,56 @@
+package record
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// FixedWidthSource reads the lines of a fixed-width file as CSVRecords, see
+// FixedWidthLayout. Blank lines are skipped.
+//
+// The UseDecimals attribute is set on the records it returns.
+type FixedWidthSource struct {
+	scanner *bufio.Scanner
+	layout  *FixedWidthLayout
+
+	UseDecimals bool
+}
+
+// NewFixedWidthSource returns a source which reads the lines of the reader
+// with the given layout
+func NewFixedWidthSource(r io.Reader, layout *FixedWidthLayout) *FixedWidthSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineLength)
+
+	return &FixedWidthSource{scanner: scanner, layout: layout}
+}
+
+// Bind returns a copy of the query where the fields are bound to the index of
+// their column, see CSVHeader.Bind
+func (s *FixedWidthSource) Bind(query *ch.Query) *ch.Query {
+	return s.layout.Header().Bind(query)
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *FixedWidthSource) Next() (*CSVRecord, error) {
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		r := s.layout.Record(line)
+		r.UseDecimals = s.UseDecimals
+
+		return r, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
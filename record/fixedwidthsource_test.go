@@ -0,0 +1,54 @@
+package record
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fixedWidthLines = `FR 2015    66.6
+FR 2016    66.9
+
+DE 2016    82.3
+`
+
+func TestFixedWidthSource(t *testing.T) {
+	l, err := ParseFixedWidthLayout("Code:0:2,Year:3:4:int,Population:7:8:float", nil)
+	require.Nil(t, err)
+
+	q, err := ch.QueryFromString("SELECT Code, $2 FROM pop.txt WHERE Year = 2016")
+	require.Nil(t, err)
+
+	s := NewFixedWidthSource(strings.NewReader(fixedWidthLines), l)
+	s.UseDecimals = true
+	q = s.Bind(q)
+
+	var values [][]*ch.Const
+
+	for {
+		r, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+
+		if m, err := q.Evaluate(r); assert.Nil(t, err) && m {
+			v, err := q.FieldsValues(r)
+			require.Nil(t, err)
+			values = append(values, v)
+		}
+	}
+
+	require.Equal(t, 2, len(values))
+	assert.Equal(t, "FR", values[0][0].AsString())
+	assert.Equal(t, "66.9", values[0][1].AsString())
+	assert.Equal(t, "DE", values[1][0].AsString())
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
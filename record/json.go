@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"sync"
 
 	ch "github.com/BatchLabs/charlatan"
 )
@@ -15,31 +15,68 @@ import (
 // returns the JSON as-is, except that the keys order is not garanteed.
 //
 // If the SoftMatching attribute is set to true, non-existing fields are
-// returned as null contants instead of failing with an error.
+// returned as null constants instead of failing with an error.
+//
+// If the UseDecimals attribute is set to true, non-integer numbers, including
+// the ones in strings, are returned as decimals instead of floats, so that
+// they're never rounded.
+//
+// Arrays and objects are returned as array and object constants, which are
+// serialized back to JSON when converted to strings. The strings selected by
+// a field are parsed, e.g. "42" is an int, whether it's selected with a[0] or
+// as an element of a[*].
+//
+// Top-level values are decoded the first time they're needed and memoized, so
+// a query using "stats.walking" and "stats.biking" decodes "stats" once.
 type JSONRecord struct {
-	attrs        map[string]*json.RawMessage
+	attrs map[string]*json.RawMessage
+
+	// mu guards the decoded top-level values, and the UseDecimals attribute
+	// they were decoded with
+	mu       sync.Mutex
+	values   map[string]*ch.Const
+	decimals bool
+
 	SoftMatching bool
+	UseDecimals  bool
 }
 
 var _ ch.Record = &JSONRecord{}
 
 var errEmptyField = errors.New("Empty field name")
 
-// NewJSONRecordFromDecoder creates a new JSONRecord from a JSON decoder
+// NewJSONRecordFromDecoder creates a new JSONRecord from a JSON decoder. See
+// JSONSource to read the elements of a JSON array.
 func NewJSONRecordFromDecoder(dec *json.Decoder) (*JSONRecord, error) {
-	attrs := make(map[string]*json.RawMessage)
+	var raw json.RawMessage
 
-	if err := dec.Decode(&attrs); err != nil {
+	// the decoder only checks the value is valid, its members are split
+	// without being decoded
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	return newJSONRecord(raw)
+}
+
+// newJSONRecord creates a new JSONRecord from a valid JSON object
+func newJSONRecord(raw json.RawMessage) (*JSONRecord, error) {
+	if string(raw) == "null" {
+		return &JSONRecord{attrs: make(map[string]*json.RawMessage)}, nil
+	}
+
+	attrs, err := splitJSONObject(raw)
+	if err != nil {
 		return nil, err
 	}
 
 	return &JSONRecord{attrs: attrs}, nil
 }
 
-// Find implements the charlatan.Record interface
+// Find implements the charlatan.Record interface. The field's path can go
+// into objects (a.b or a["b.c"]) and arrays (a[0], a[-1], a[1:3] or a[*]).
+// Slices and wildcards return arrays.
 func (r *JSONRecord) Find(field *ch.Field) (*ch.Const, error) {
-	var ok bool
-	var partial *json.RawMessage
 	var name string
 
 	if name = field.Name(); len(name) == 0 {
@@ -55,68 +92,111 @@ func (r *JSONRecord) Find(field *ch.Field) (*ch.Const, error) {
 		return ch.StringConst(string(b)), nil
 	}
 
-	attrs := r.attrs
-	parts := strings.Split(name, ".")
-
-	for i, k := range parts {
-		partial, ok = attrs[k]
+	path := field.Path()
 
-		if !ok {
-			if r.SoftMatching {
-				return ch.NullConst(), nil
+	key, ok := path[0].Key()
+	if !ok {
+		// the path starts with a wildcard, e.g. *.price
+		attrs := make(map[string]*ch.Const, len(r.attrs))
+		for k := range r.attrs {
+			c, _, err := r.value(k)
+			if err != nil {
+				return nil, err
 			}
-
-			return nil, fmt.Errorf("Unknown '%s' field (in '%s')", k, field.Name())
+			attrs[k] = c
 		}
+		return r.lookup(ch.ObjectConst(attrs), path, field)
+	}
 
-		// update the attrs if we need to go deeper
-		if i < len(parts)-1 {
-			attrs = make(map[string]*json.RawMessage)
-			if err := json.Unmarshal(*partial, &attrs); err != nil {
-				if r.SoftMatching {
-					return ch.NullConst(), nil
-				}
-				return nil, err
-			}
+	c, ok, err := r.value(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		if r.SoftMatching {
+			return ch.NullConst(), nil
 		}
+
+		return nil, fmt.Errorf("Unknown '%s' field (in '%s')", key, name)
 	}
 
-	return jsonToConst(partial)
+	return r.lookup(c, path[1:], field)
 }
 
-func jsonToConst(partial *json.RawMessage) (*ch.Const, error) {
-	var value string
+// value returns the decoded top-level value with the given key. The boolean
+// is false if there's no such value.
+func (r *JSONRecord) value(key string) (*ch.Const, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if partial == nil {
-		return ch.NullConst(), nil
+	if r.values == nil || r.decimals != r.UseDecimals {
+		r.values = make(map[string]*ch.Const, len(r.attrs))
+		r.decimals = r.UseDecimals
 	}
 
-	asString := string(*partial)
+	if c, ok := r.values[key]; ok {
+		return c, true, nil
+	}
 
-	if asString == "null" {
-		return ch.NullConst(), nil
+	partial, ok := r.attrs[key]
+	if !ok {
+		return nil, false, nil
 	}
 
-	if err := json.Unmarshal(*partial, &value); err != nil {
-		if err, ok := err.(*json.UnmarshalTypeError); ok {
-			// we failed to unmarshal into a string, let's try the other types
-			switch err.Value {
-			case "number":
-				var n json.Number
-				if err := json.Unmarshal(*partial, &n); err != nil {
-					return nil, err
-				}
+	c, err := parseJSONValue(*partial, r.UseDecimals)
+	if err != nil {
+		return nil, true, err
+	}
 
-				value = n.String()
+	r.values[key] = c
 
-			case "bool", "object", "array":
-				value = asString
+	return c, true, nil
+}
 
-			default:
-				return nil, err
-			}
+// lookup returns the value at the path in the given value. The selected
+// strings are parsed, like top-level ones.
+func (r *JSONRecord) lookup(c *ch.Const, path ch.FieldPath, field *ch.Field) (*ch.Const, error) {
+	c, ok := path.Lookup(c)
+	if !ok {
+		if r.SoftMatching {
+			return ch.NullConst(), nil
+		}
+		return nil, fmt.Errorf("Unknown '%s' field", field.Name())
+	}
+
+	return parseSelectedStrings(c, path, r.UseDecimals), nil
+}
+
+// parseSelectedStrings parses the strings a path selected in a value, so that
+// a[0] and the elements of a[*] or a[1:3] are converted in the same way. Each
+// slice or wildcard of the path adds one level of arrays around them.
+func parseSelectedStrings(c *ch.Const, path ch.FieldPath, useDecimals bool) *ch.Const {
+	depth := 0
+	for _, seg := range path {
+		if seg.IsMultiple() {
+			depth++
+		}
+	}
+
+	return parseStrings(c, depth, useDecimals)
+}
+
+// parseStrings parses the strings at the given depth of nested arrays
+func parseStrings(c *ch.Const, depth int, useDecimals bool) *ch.Const {
+	switch {
+	case depth > 0 && c.IsArray():
+		elements := make([]*ch.Const, len(c.Elements()))
+		for i, element := range c.Elements() {
+			elements[i] = parseStrings(element, depth-1, useDecimals)
+		}
+		return ch.ArrayConst(elements)
+	case depth == 0 && c.IsString():
+		if useDecimals {
+			return ch.ConstFromStringDecimal(c.AsString())
 		}
+		return ch.ConstFromString(c.AsString())
 	}
 
-	return ch.ConstFromString(value), nil
+	return c
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 	"strings"
+	"sync"
 	"testing"
 
 	ch "github.com/BatchLabs/charlatan"
@@ -43,7 +44,7 @@ func TestFindArrayField(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, c)
 
-	require.True(t, c.IsString())
+	require.True(t, c.IsArray())
 	require.Equal(t, `[]`, c.AsString())
 }
 
@@ -56,8 +57,32 @@ func TestFindObjectField(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, c)
 
-	require.True(t, c.IsString())
-	require.Equal(t, `{"need": {"to": {"go": {"deeper": 1, "a": "d"}}}}`, c.AsString())
+	require.True(t, c.IsObject())
+	require.Equal(t, `{"need":{"to":{"go":{"a":"d","deeper":1}}}}`, c.AsString())
+}
+
+func TestFindNestedValues(t *testing.T) {
+	r, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`
+		{"tags": ["a", "42", 42, 1.5, true, null, {"k": [1]}]}
+	`)))
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("tags"))
+	require.Nil(t, err)
+	require.True(t, c.IsArray())
+
+	elements := c.Elements()
+	require.Equal(t, 7, len(elements))
+
+	// nested strings aren't parsed
+	assert.True(t, elements[1].IsString())
+	assert.Equal(t, int64(42), elements[2].Value())
+	assert.Equal(t, 1.5, elements[3].Value())
+	assert.Equal(t, true, elements[4].Value())
+	assert.True(t, elements[5].IsNull())
+	assert.True(t, elements[6].IsObject())
+
+	assert.Equal(t, `["a","42",42,1.5,true,null,{"k":[1]}]`, c.AsString())
 }
 
 func TestFindTopLevelStringField(t *testing.T) {
@@ -215,3 +240,258 @@ func TestJSONRecordSoftFind(t *testing.T) {
 	require.NotNil(t, v)
 	assert.True(t, v.IsNull())
 }
+
+func TestJSONRecordUseDecimals(t *testing.T) {
+	rec, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`
+		{"amount": 12345678901234567890.12, "count": 3, "big": 99999999999999999999,
+		 "prices": [0.10, 2.50]}
+	`)))
+	require.Nil(t, err)
+	require.NotNil(t, rec)
+
+	v, err := rec.Find(ch.NewField("amount"))
+	require.Nil(t, err)
+	// rounded by the float64 conversion
+	assert.Equal(t, 12345678901234567890.12, v.Value())
+
+	rec.UseDecimals = true
+
+	v, err = rec.Find(ch.NewField("amount"))
+	require.Nil(t, err)
+	assert.Equal(t, "12345678901234567890.12", v.AsString())
+
+	v, err = rec.Find(ch.NewField("count"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), v.Value())
+
+	v, err = rec.Find(ch.NewField("big"))
+	require.Nil(t, err)
+	assert.Equal(t, "99999999999999999999", v.AsString())
+
+	v, err = rec.Find(ch.NewField("prices"))
+	require.Nil(t, err)
+	assert.Equal(t, "[0.10,2.50]", v.AsString())
+}
+
+func TestJSONRecordFindPaths(t *testing.T) {
+	rec, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`
+		{"items": [{"price": 10, "sku": "42"}, {"price": 12.5}, {"sku": "x"}],
+		 "a.b": {"c": true}, "n": {"k": "v"}}
+	`)))
+	require.Nil(t, err)
+
+	for name, expected := range map[string]string{
+		"items[0].price":    "10",
+		"items[-2].price":   "12.50",
+		"items[*].price":    "[10,12.5]",
+		"items[1:].sku":     `["x"]`,
+		"items[*].sku":      `[42,"x"]`,
+		`["a.b"].c`:         "true",
+		`["a.b"]["c"]`:      "true",
+		"n.*":               `["v"]`,
+		"*.c":               "[true]",
+		"items[0]":          `{"price":10,"sku":"42"}`,
+		"items[10:]":        "[]",
+		"items[0].sku":      "42",
+		"items[0][\"sku\"]": "42",
+	} {
+		c, err := rec.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.AsString(), name)
+		}
+	}
+
+	// strings found with a path are parsed, like top-level ones
+	c, err := rec.Find(ch.NewField("items[0].sku"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(42), c.Value())
+
+	// including the elements of slices and wildcards
+	c, err = rec.Find(ch.NewField("items[*].sku"))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(c.Elements()))
+	assert.Equal(t, int64(42), c.Elements()[0].Value())
+	assert.Equal(t, "x", c.Elements()[1].Value())
+
+	for _, name := range []string{"items[3]", "items[1].sku", "items.price", "n[0]", "nope[0]"} {
+		_, err := rec.Find(ch.NewField(name))
+		assert.NotNil(t, err, name)
+	}
+
+	rec.SoftMatching = true
+
+	c, err = rec.Find(ch.NewField("items[3].price"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
+
+func TestJSONRecordQueryPaths(t *testing.T) {
+	rec, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`
+		{"items": [{"price": 10}, {"price": 30}]}
+	`)))
+	require.Nil(t, err)
+
+	for s, expected := range map[string]bool{
+		"SELECT x FROM y WHERE items[-1].price > 20":         true,
+		"SELECT x FROM y WHERE 10 = ANY(items[*].price)":     true,
+		"SELECT x FROM y WHERE 10 < ALL(items[*].price)":     false,
+		"SELECT x FROM y WHERE LENGTH(items[*].price) = 2":   true,
+		"SELECT x FROM y WHERE CONTAINS(items[*].price, 30)": true,
+	} {
+		q, err := ch.QueryFromString(s)
+		require.Nil(t, err, s)
+
+		m, err := q.Evaluate(rec)
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, expected, m, s)
+		}
+	}
+}
+
+// people.jsons-shaped records
+const benchmarkPeople = `{"name": "Nico", "age": 54, "stats": {"walking": 42, "running": 104, "biking": 13}}
+{"name": "Vincent", "age": 32, "stats": {"walking": 100, "running": 2, "biking": 3}}
+{"name": "Cedric", "age": 52, "stats": {"walking": 2, "running": 2, "biking": 1000}}
+{"name": "Michel", "age": 83, "stats": {"walking": 0, "running": 0, "biking": 0}}
+`
+
+func benchmarkJSONQuery(b *testing.B, s string) {
+	q, err := ch.QueryFromString(s)
+	require.Nil(b, err)
+
+	input := strings.Repeat(benchmarkPeople, 256)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		dec := json.NewDecoder(strings.NewReader(input))
+
+		for {
+			r, err := NewJSONRecordFromDecoder(dec)
+			if err == io.EOF {
+				break
+			}
+			require.Nil(b, err)
+
+			if m, _ := q.Evaluate(r); m {
+				_, err = q.FieldsValues(r)
+				require.Nil(b, err)
+			}
+		}
+	}
+}
+
+func BenchmarkJSONRecordTopLevelFields(b *testing.B) {
+	benchmarkJSONQuery(b, "SELECT name, age FROM people.jsons WHERE age > 40")
+}
+
+func BenchmarkJSONRecordNestedFields(b *testing.B) {
+	benchmarkJSONQuery(b, "SELECT name, stats.running FROM people.jsons WHERE stats.walking > 30 AND stats.biking < 300")
+}
+
+func BenchmarkJSONRecordFind(b *testing.B) {
+	r, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(benchmarkPeople)))
+	require.Nil(b, err)
+
+	fields := []*ch.Field{
+		ch.NewField("name"), ch.NewField("stats.walking"), ch.NewField("stats.biking"),
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, f := range fields {
+			if _, err := r.Find(f); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestNewJSONRecordFromDecoderNotAnObject(t *testing.T) {
+	_, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`[1, 2]`)))
+	assert.NotNil(t, err)
+
+	r, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`null`)))
+	require.Nil(t, err)
+
+	_, err = r.Find(ch.NewField("a"))
+	assert.NotNil(t, err)
+}
+
+func TestJSONRecordMemoizesValues(t *testing.T) {
+	r, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`
+		{"stats": {"walking": 42, "biking": 1.5}}
+	`)))
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("stats.walking"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(42), c.Value())
+
+	require.Equal(t, 1, len(r.values))
+	stats := r.values["stats"]
+
+	c, err = r.Find(ch.NewField("stats.biking"))
+	require.Nil(t, err)
+	assert.Equal(t, 1.5, c.Value())
+	assert.True(t, stats == r.values["stats"])
+
+	// changing UseDecimals decodes the values again
+	r.UseDecimals = true
+
+	c, err = r.Find(ch.NewField("stats.biking"))
+	require.Nil(t, err)
+	assert.Equal(t, "1.5", c.AsString())
+	assert.False(t, stats == r.values["stats"])
+}
+
+func TestJSONRecordParsesNestedSelections(t *testing.T) {
+	rec, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`
+		{"m": [["1", "2.5"], ["true"]], "raw": ["3", ["4"]], "p": ["0.10"]}
+	`)))
+	require.Nil(t, err)
+
+	c, err := rec.Find(ch.NewField("m[*][*]"))
+	require.Nil(t, err)
+	assert.Equal(t, "[[1,2.5],[true]]", c.AsString())
+
+	c, err = rec.Find(ch.NewField("m[0][1:]"))
+	require.Nil(t, err)
+	assert.Equal(t, "[2.5]", c.AsString())
+
+	// only the selected elements are parsed, not the arrays inside them
+	c, err = rec.Find(ch.NewField("raw[*]"))
+	require.Nil(t, err)
+	assert.Equal(t, `[3,["4"]]`, c.AsString())
+
+	rec.UseDecimals = true
+
+	for _, name := range []string{"p[0]", "p[*]"} {
+		c, err = rec.Find(ch.NewField(name))
+		require.Nil(t, err, name)
+		assert.Contains(t, c.AsString(), "0.10", name)
+	}
+}
+
+func TestJSONRecordConcurrentFind(t *testing.T) {
+	rec, err := NewJSONRecordFromDecoder(json.NewDecoder(strings.NewReader(`
+		{"stats": {"walking": 42, "biking": 1.5}, "n": 3}
+	`)))
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, name := range []string{"stats.walking", "stats.biking", "n"} {
+				_, err := rec.Find(ch.NewField(name))
+				assert.Nil(t, err, name)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,199 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// jsonSourceState is the state of a JSON source
+type jsonSourceState int
+
+const (
+	// nothing has been read yet
+	jsonSourceStart jsonSourceState = iota
+	// the records are concatenated objects
+	jsonSourceObjects
+	// the records are the elements of an array
+	jsonSourceArray
+	// the end of the array has been read
+	jsonSourceEnd
+)
+
+// JSONSource reads JSONRecords from a stream, one at a time, without loading
+// the whole document. The records are either:
+//   - concatenated objects, as in .jsons files
+//   - the elements of a top-level array: [{...}, {...}]
+//   - the elements of an array at a path in the document, e.g. "data" for
+//     {"data": [{...}, {...}]}
+//
+// The SoftMatching and UseDecimals attributes are set on the records it
+// returns.
+type JSONSource struct {
+	dec   *json.Decoder
+	r     *bufio.Reader
+	path  []string
+	state jsonSourceState
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewJSONSource returns a source which reads either concatenated objects or
+// the elements of a top-level array from the reader
+func NewJSONSource(r io.Reader) *JSONSource {
+	br := bufio.NewReader(r)
+	return &JSONSource{dec: json.NewDecoder(br), r: br}
+}
+
+// NewJSONSourceAt returns a source which reads the elements of the array at
+// the given path in the document, made of keys separated by dots, e.g.
+// "data" or "response.items"
+func NewJSONSourceAt(r io.Reader, path string) (*JSONSource, error) {
+	var keys []string
+
+	p, err := ch.ParseFieldPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, seg := range p {
+		key, ok := seg.Key()
+		if !ok {
+			return nil, fmt.Errorf("Invalid array path %s: only keys are supported", path)
+		}
+		keys = append(keys, key)
+	}
+
+	return &JSONSource{dec: json.NewDecoder(r), path: keys}, nil
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *JSONSource) Next() (*JSONRecord, error) {
+	if s.state == jsonSourceStart {
+		if err := s.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	var raw json.RawMessage
+
+	switch s.state {
+	case jsonSourceArray:
+		if !s.dec.More() {
+			// the closing bracket
+			if err := s.expectDelim(']'); err != nil {
+				return nil, err
+			}
+			s.state = jsonSourceEnd
+			return nil, io.EOF
+		}
+
+	case jsonSourceEnd:
+		return nil, io.EOF
+	}
+
+	if err := s.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	r, err := newJSONRecord(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+	r.UseDecimals = s.UseDecimals
+
+	return r, nil
+}
+
+// start finds out where the records are
+func (s *JSONSource) start() error {
+	if s.path != nil {
+		if err := s.seek(); err != nil {
+			return err
+		}
+		s.state = jsonSourceArray
+		return nil
+	}
+
+	// skip the leading whitespaces to peek at the first character
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			if err := s.r.UnreadByte(); err != nil {
+				return err
+			}
+
+			if b != '[' {
+				s.state = jsonSourceObjects
+				return nil
+			}
+
+			break
+		}
+	}
+
+	if err := s.expectDelim('['); err != nil {
+		return err
+	}
+
+	s.state = jsonSourceArray
+	return nil
+}
+
+// seek reads the document up to the opening bracket of the array at the path,
+// skipping the values of the other keys
+func (s *JSONSource) seek() error {
+	for _, key := range s.path {
+		if err := s.expectDelim('{'); err != nil {
+			return err
+		}
+
+		for {
+			if !s.dec.More() {
+				return fmt.Errorf("Can't find the '%s' key of %s",
+					key, strings.Join(s.path, "."))
+			}
+
+			tok, err := s.dec.Token()
+			if err != nil {
+				return err
+			}
+
+			if tok == key {
+				break
+			}
+
+			var skipped json.RawMessage
+			if err := s.dec.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.expectDelim('[')
+}
+
+// expectDelim reads the next token, which must be the given delimiter
+func (s *JSONSource) expectDelim(delim json.Delim) error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != delim {
+		return fmt.Errorf("Expected '%s' in the JSON document, got %v", delim, tok)
+	}
+
+	return nil
+}
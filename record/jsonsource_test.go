@@ -0,0 +1,133 @@
+package record
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSourceConcatenatedObjects(t *testing.T) {
+	s := NewJSONSource(strings.NewReader(`
+		{"name": "a"}
+		{"name": "b"}{"name": "c"}
+	`))
+
+	assert.Equal(t, []interface{}{"a", "b", "c"}, readColumn(t, s.Next, "name"))
+
+	// it stays at the end
+	_, err := s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestJSONSourceTopLevelArray(t *testing.T) {
+	s := NewJSONSource(strings.NewReader(`
+		[{"name": "a"}, {"name": "b"},
+		 {"name": "c"}]
+	`))
+
+	assert.Equal(t, []interface{}{"a", "b", "c"}, readColumn(t, s.Next, "name"))
+
+	_, err := s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestJSONSourceEmpty(t *testing.T) {
+	for _, doc := range []string{"", "  \n", "[]", " [ ] "} {
+		_, err := NewJSONSource(strings.NewReader(doc)).Next()
+		assert.Equal(t, io.EOF, err, doc)
+	}
+}
+
+func TestJSONSourceAt(t *testing.T) {
+	s, err := NewJSONSourceAt(strings.NewReader(`{
+		"meta": {"count": 2, "data": "not this one"},
+		"response": {
+			"page": [1, 2],
+			"data": [{"name": "a"}, {"name": "b"}],
+			"after": {"data": []}
+		}
+	}`), "response.data")
+	require.Nil(t, err)
+
+	assert.Equal(t, []interface{}{"a", "b"}, readColumn(t, s.Next, "name"))
+}
+
+func TestJSONSourceAtErrors(t *testing.T) {
+	for _, path := range []string{"", "data[0]", "data.*"} {
+		_, err := NewJSONSourceAt(strings.NewReader(`{}`), path)
+		assert.NotNil(t, err, path)
+	}
+
+	for path, doc := range map[string]string{
+		"data":   `{"items": []}`,
+		"a.data": `{"a": [1]}`,
+		"items":  `{"items": {"a": 1}}`,
+	} {
+		s, err := NewJSONSourceAt(strings.NewReader(doc), path)
+		require.Nil(t, err)
+
+		_, err = s.Next()
+		assert.NotNil(t, err, path)
+		assert.NotEqual(t, io.EOF, err, path)
+	}
+}
+
+func TestJSONSourceNotObjects(t *testing.T) {
+	s := NewJSONSource(strings.NewReader(`[{"name": "a"}, 42]`))
+
+	_, err := s.Next()
+	require.Nil(t, err)
+
+	_, err = s.Next()
+	assert.NotNil(t, err)
+}
+
+func TestJSONSourceRecordsOptions(t *testing.T) {
+	s := NewJSONSource(strings.NewReader(`[{"price": 0.10}]`))
+	s.SoftMatching = true
+	s.UseDecimals = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("price"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.10", c.AsString())
+
+	c, err = r.Find(ch.NewField("nope"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("failing reader")
+}
+
+func TestJSONSourceStreams(t *testing.T) {
+	// the first records can be read before the rest of the document is
+	s := NewJSONSource(io.MultiReader(
+		strings.NewReader(`[{"name": "a"}, {"name": "b"}, `),
+		failingReader{},
+	))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("name"))
+	require.Nil(t, err)
+	assert.Equal(t, "a", c.AsString())
+
+	_, err = s.Next()
+	require.Nil(t, err)
+
+	_, err = s.Next()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,322 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// jsonParser converts JSON values to constants in a single pass, without
+// going through interface{} values. It expects valid JSON, e.g. values
+// returned by a json.Decoder, but reports errors anyway.
+type jsonParser struct {
+	data        []byte
+	pos         int
+	useDecimals bool
+}
+
+// parseJSONValue converts a JSON value to a constant. Strings are kept as is.
+func parseJSONValue(data []byte, useDecimals bool) (*ch.Const, error) {
+	p := &jsonParser{data: data, useDecimals: useDecimals}
+
+	c, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// splitJSONObject splits a JSON object into its members, which aren't
+// decoded
+func splitJSONObject(data []byte) (map[string]*json.RawMessage, error) {
+	p := &jsonParser{data: data}
+	attrs := make(map[string]*json.RawMessage)
+
+	err := p.object(func(key string) error {
+		p.skipSpaces()
+
+		start := p.pos
+		if err := p.skipValue(); err != nil {
+			return err
+		}
+
+		raw := json.RawMessage(p.data[start:p.pos])
+		attrs[key] = &raw
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	return attrs, nil
+}
+
+func (p *jsonParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Invalid JSON at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonParser) skipSpaces() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next byte which isn't a whitespace, or 0 at the end
+func (p *jsonParser) peek() byte {
+	p.skipSpaces()
+	if p.pos == len(p.data) {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// expect consumes the given byte
+func (p *jsonParser) expect(b byte) error {
+	if p.peek() != b {
+		return p.errorf("expected '%c'", b)
+	}
+	p.pos++
+	return nil
+}
+
+// end checks there's nothing but whitespaces left
+func (p *jsonParser) end() error {
+	if p.peek() != 0 {
+		return p.errorf("unexpected data after the value")
+	}
+	return nil
+}
+
+// value parses the next value
+func (p *jsonParser) value() (*ch.Const, error) {
+	switch p.peek() {
+	case '{':
+		members := make(map[string]*ch.Const)
+		err := p.object(func(key string) error {
+			c, err := p.value()
+			members[key] = c
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ch.ObjectConst(members), nil
+
+	case '[':
+		elements := []*ch.Const{}
+		err := p.array(func() error {
+			c, err := p.value()
+			elements = append(elements, c)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ch.ArrayConst(elements), nil
+
+	case '"':
+		s, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		return ch.StringConst(s), nil
+
+	case 't':
+		return ch.BoolConst(true), p.literal("true")
+	case 'f':
+		return ch.BoolConst(false), p.literal("false")
+	case 'n':
+		return ch.NullConst(), p.literal("null")
+	}
+
+	n, err := p.number()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.useDecimals {
+		return ch.ConstFromStringDecimal(n), nil
+	}
+	return ch.ConstFromString(n), nil
+}
+
+// skipValue skips the next value
+func (p *jsonParser) skipValue() error {
+	switch p.peek() {
+	case '{':
+		return p.object(func(string) error { return p.skipValue() })
+	case '[':
+		return p.array(p.skipValue)
+	case '"':
+		_, err := p.rawStr()
+		return err
+	case 't':
+		return p.literal("true")
+	case 'f':
+		return p.literal("false")
+	case 'n':
+		return p.literal("null")
+	}
+
+	_, err := p.number()
+	return err
+}
+
+// object parses an object, calling member on each key with the parser
+// positioned on its value, which member must consume
+func (p *jsonParser) object(member func(key string) error) error {
+	if err := p.expect('{'); err != nil {
+		return err
+	}
+
+	if p.peek() == '}' {
+		p.pos++
+		return nil
+	}
+
+	for {
+		if p.peek() != '"' {
+			return p.errorf("expected a key")
+		}
+
+		key, err := p.str()
+		if err != nil {
+			return err
+		}
+
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+
+		if err := member(key); err != nil {
+			return err
+		}
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return nil
+		default:
+			return p.errorf("expected ',' or '}'")
+		}
+	}
+}
+
+// array parses an array, calling element with the parser positioned on each
+// element, which element must consume
+func (p *jsonParser) array(element func() error) error {
+	if err := p.expect('['); err != nil {
+		return err
+	}
+
+	if p.peek() == ']' {
+		p.pos++
+		return nil
+	}
+
+	for {
+		if err := element(); err != nil {
+			return err
+		}
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return nil
+		default:
+			return p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+// rawStr skips a string and returns whether it has escapes
+func (p *jsonParser) rawStr() (bool, error) {
+	escaped := false
+
+	// the opening quote
+	p.pos++
+
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case '\\':
+			escaped = true
+			p.pos += 2
+			continue
+		case '"':
+			p.pos++
+			return escaped, nil
+		}
+		p.pos++
+	}
+
+	return false, p.errorf("unterminated string")
+}
+
+// str parses a string
+func (p *jsonParser) str() (string, error) {
+	start := p.pos
+
+	escaped, err := p.rawStr()
+	if err != nil {
+		return "", err
+	}
+
+	if !escaped {
+		return string(p.data[start+1 : p.pos-1]), nil
+	}
+
+	// escapes are rare enough to be left to the standard library
+	var s string
+	if err := json.Unmarshal(p.data[start:p.pos], &s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// literal consumes the given literal
+func (p *jsonParser) literal(lit string) error {
+	end := p.pos + len(lit)
+	if end > len(p.data) || string(p.data[p.pos:end]) != lit {
+		return p.errorf("expected %s", lit)
+	}
+	p.pos = end
+	return nil
+}
+
+// number consumes a number and returns it as it's written
+func (p *jsonParser) number() (string, error) {
+	start := p.pos
+
+	for p.pos < len(p.data) && isNumberByte(p.data[p.pos]) {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return "", p.errorf("unexpected character")
+	}
+
+	return string(p.data[start:p.pos]), nil
+}
+
+func isNumberByte(b byte) bool {
+	return (b >= '0' && b <= '9') || b == '-' || b == '+' || b == '.' || b == 'e' || b == 'E'
+}
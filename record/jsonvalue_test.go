@@ -0,0 +1,60 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONValue(t *testing.T) {
+	for s, expected := range map[string]string{
+		`42`:                         "42",
+		` -1.5e3 `:                   "-1500.00",
+		`"a"`:                        "a",
+		`"say \"hi\" é"`:             `say "hi" é`,
+		`true`:                       "true",
+		`null`:                       "null",
+		`[]`:                         "[]",
+		`{}`:                         "{}",
+		`[1, "2", [true], {}]`:       `[1,"2",[true],{}]`,
+		`{"b": {"c": null}, "a": 1}`: `{"a":1,"b":{"c":null}}`,
+	} {
+		c, err := parseJSONValue([]byte(s), false)
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, expected, c.AsString(), s)
+		}
+	}
+
+	// strings aren't parsed
+	c, err := parseJSONValue([]byte(`"42"`), false)
+	require.Nil(t, err)
+	assert.True(t, c.IsString())
+
+	c, err = parseJSONValue([]byte(`[0.10]`), true)
+	require.Nil(t, err)
+	assert.Equal(t, "[0.10]", c.AsString())
+
+	for _, s := range []string{
+		``, `{`, `[1,]`, `[1 2]`, `{"a" 1}`, `{a: 1}`, `{"a": }`, `"a`, `tru`,
+		`nul`, `1 2`, `{"a": 1,}`,
+	} {
+		_, err := parseJSONValue([]byte(s), false)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestSplitJSONObject(t *testing.T) {
+	attrs, err := splitJSONObject([]byte(`{"a": 1, "b": {"c": [1, "]"]}, "d\"e": "x"}`))
+	require.Nil(t, err)
+	require.Equal(t, 3, len(attrs))
+
+	assert.Equal(t, `1`, string(*attrs["a"]))
+	assert.Equal(t, `{"c": [1, "]"]}`, string(*attrs["b"]))
+	assert.Equal(t, `"x"`, string(*attrs[`d"e`]))
+
+	for _, s := range []string{`[]`, `42`, `{"a": 1} 2`, `{"a"}`} {
+		_, err := splitJSONObject([]byte(s))
+		assert.NotNil(t, err, s)
+	}
+}
@@ -0,0 +1,160 @@
+package record
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// LogfmtRecord is a record for logfmt lines, made of key=value pairs
+// separated by spaces, e.g. level=info msg="GET /" latency=12ms.
+//
+// Values can be quoted, with the Go escapes. Keys without a value are true,
+// and when a key is repeated the last value is used. Field names are the keys
+// as-is, dots included. Values are parsed with charlatan.ConstFromString.
+//
+// It supports the special field "*", which returns the line.
+//
+// If the SoftMatching attribute is set to true, non-existing fields are
+// returned as null constants instead of failing with an error.
+//
+// If the UseDecimals attribute is set to true, non-integer numbers are
+// returned as decimals instead of floats, so that they're never rounded.
+type LogfmtRecord struct {
+	line string
+	// the values, nil for keys without a value
+	values map[string]*string
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+var _ ch.Record = &LogfmtRecord{}
+
+// NewLogfmtRecord parses a logfmt line
+func NewLogfmtRecord(line string) (*LogfmtRecord, error) {
+	values, err := parseLogfmt(line)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogfmtRecord{line: line, values: values}, nil
+}
+
+// Find implements the charlatan.Record interface
+func (r *LogfmtRecord) Find(field *ch.Field) (*ch.Const, error) {
+	var name string
+
+	if name = field.Name(); len(name) == 0 {
+		return nil, errEmptyField
+	}
+
+	// support for "SELECT *"
+	if name == "*" {
+		return ch.StringConst(r.line), nil
+	}
+
+	value, ok := r.values[name]
+	if !ok {
+		if r.SoftMatching {
+			return ch.NullConst(), nil
+		}
+		return nil, fmt.Errorf("Unknown '%s' field", name)
+	}
+
+	if value == nil {
+		return ch.BoolConst(true), nil
+	}
+
+	if r.UseDecimals {
+		return ch.ConstFromStringDecimal(*value), nil
+	}
+
+	return ch.ConstFromString(*value), nil
+}
+
+// parseLogfmt parses the key=value pairs of a line
+func parseLogfmt(line string) (map[string]*string, error) {
+	values := make(map[string]*string)
+	i := 0
+
+	for {
+		for i < len(line) && isLogfmtSpace(line[i]) {
+			i++
+		}
+
+		if i == len(line) {
+			return values, nil
+		}
+
+		start := i
+		for i < len(line) && !isLogfmtSpace(line[i]) && line[i] != '=' {
+			if line[i] == '"' {
+				return nil, fmt.Errorf("Unexpected quote in key at position %d", i+1)
+			}
+			i++
+		}
+
+		key := line[start:i]
+		if key == "" {
+			return nil, fmt.Errorf("Missing key at position %d", i+1)
+		}
+
+		// a bare key
+		if i == len(line) || line[i] != '=' {
+			values[key] = nil
+			continue
+		}
+
+		// the equal sign
+		i++
+
+		value, end, err := logfmtValue(line, i)
+		if err != nil {
+			return nil, err
+		}
+
+		values[key] = &value
+		i = end
+	}
+}
+
+// logfmtValue reads the value which starts at the given index, and returns it
+// with the index of its end
+func logfmtValue(line string, start int) (string, int, error) {
+	if start == len(line) || line[start] != '"' {
+		end := start
+		for end < len(line) && !isLogfmtSpace(line[end]) {
+			end++
+		}
+		return line[start:end], end, nil
+	}
+
+	escaped := false
+
+	for end := start + 1; end < len(line); end++ {
+		switch line[end] {
+		case '\\':
+			escaped = true
+			end++
+		case '"':
+			if !escaped {
+				return line[start+1 : end], end + 1, nil
+			}
+
+			value, err := strconv.Unquote(line[start : end+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("Invalid quoted value at position %d: %v", start+1, err)
+			}
+			return value, end + 1, nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("Unterminated quoted value at position %d", start+1)
+}
+
+func isLogfmtSpace(b byte) bool {
+	return strings.IndexByte(" \t\r\n", b) >= 0
+}
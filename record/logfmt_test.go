@@ -0,0 +1,83 @@
+package record
+
+import (
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogfmtRecordFind(t *testing.T) {
+	line := `level=info msg="GET /a \"b\"" latency=12ms status=200 ratio=0.10 ` +
+		`http.path=/a debug empty= quoted="" level=error unicode="café"`
+
+	r, err := NewLogfmtRecord(line)
+	require.Nil(t, err)
+
+	for name, expected := range map[string]interface{}{
+		"level":     "error",
+		"msg":       `GET /a "b"`,
+		"latency":   "12ms",
+		"status":    int64(200),
+		"ratio":     0.1,
+		"http.path": "/a",
+		"debug":     true,
+		"empty":     "",
+		"quoted":    "",
+		"unicode":   "café",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	c, err := r.Find(ch.NewField("*"))
+	require.Nil(t, err)
+	assert.Equal(t, line, c.AsString())
+
+	for _, name := range []string{"", "nope", "http"} {
+		_, err := r.Find(ch.NewField(name))
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestLogfmtRecordOptions(t *testing.T) {
+	r, err := NewLogfmtRecord("ratio=0.10")
+	require.Nil(t, err)
+	r.SoftMatching = true
+	r.UseDecimals = true
+
+	c, err := r.Find(ch.NewField("ratio"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.10", c.AsString())
+
+	c, err = r.Find(ch.NewField("nope"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
+
+func TestLogfmtRecordSpaces(t *testing.T) {
+	r, err := NewLogfmtRecord("  a=1\tb=2  ")
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(r.values))
+
+	r, err = NewLogfmtRecord("")
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(r.values))
+}
+
+func TestLogfmtRecordInvalid(t *testing.T) {
+	for _, line := range []string{
+		`=a`,
+		`a=1 =b`,
+		`a"b=1`,
+		`msg="unterminated`,
+		`msg="bad \q escape"`,
+	} {
+		_, err := NewLogfmtRecord(line)
+		assert.NotNil(t, err, line)
+	}
+}
@@ -0,0 +1,61 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxLineLength is the maximum length of the lines of line-based sources
+const maxLineLength = 1024 * 1024
+
+// LogfmtSource reads LogfmtRecords from a stream, one line at a time. Empty
+// lines are skipped.
+//
+// The SoftMatching and UseDecimals attributes are set on the records it
+// returns.
+type LogfmtSource struct {
+	scanner *bufio.Scanner
+	line    int
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewLogfmtSource returns a source which reads the logfmt lines from the
+// reader
+func NewLogfmtSource(r io.Reader) *LogfmtSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineLength)
+
+	return &LogfmtSource{scanner: scanner}
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *LogfmtSource) Next() (*LogfmtRecord, error) {
+	for s.scanner.Scan() {
+		s.line++
+
+		line := s.scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		r, err := NewLogfmtRecord(line)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", s.line, err)
+		}
+
+		r.SoftMatching = s.SoftMatching
+		r.UseDecimals = s.UseDecimals
+
+		return r, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
@@ -0,0 +1,71 @@
+package record
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const logfmtLines = `level=info msg="started" port=8080
+
+level=error msg="connection refused" latency=12ms
+level=info msg="request" latency=3ms
+`
+
+func TestLogfmtSource(t *testing.T) {
+	q, err := ch.QueryFromString(`SELECT msg FROM app.log WHERE level = "error"`)
+	require.Nil(t, err)
+
+	s := NewLogfmtSource(strings.NewReader(logfmtLines))
+
+	var count int
+	var messages []string
+
+	for {
+		r, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		count++
+
+		if m, err := q.Evaluate(r); assert.Nil(t, err) && m {
+			values, err := q.FieldsValues(r)
+			require.Nil(t, err)
+			messages = append(messages, values[0].AsString())
+		}
+	}
+
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []string{"connection refused"}, messages)
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLogfmtSourceOptions(t *testing.T) {
+	s := NewLogfmtSource(strings.NewReader("a=0.10\n"))
+	s.SoftMatching = true
+	s.UseDecimals = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+	assert.True(t, r.SoftMatching)
+	assert.True(t, r.UseDecimals)
+}
+
+func TestLogfmtSourceInvalidLine(t *testing.T) {
+	s := NewLogfmtSource(strings.NewReader("a=1\n\nmsg=\"oops\n"))
+
+	_, err := s.Next()
+	require.Nil(t, err)
+
+	_, err = s.Next()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Line 3")
+}
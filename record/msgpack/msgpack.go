@@ -0,0 +1,143 @@
+// Package msgpack provides a source of records for MessagePack streams
+package msgpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/BatchLabs/charlatan/record"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Framing is the way the maps of a stream are delimited
+type Framing int
+
+const (
+	// SelfDelimited maps follow each other without any delimiter, since
+	// MessagePack values carry their own length
+	SelfDelimited Framing = iota
+	// Uint32Prefixed maps are each prefixed by their size in bytes, as a
+	// big-endian uint32
+	Uint32Prefixed
+	// VarintPrefixed maps are each prefixed by their size in bytes, as an
+	// unsigned varint, like length-delimited Protocol Buffers messages
+	VarintPrefixed
+)
+
+// Source reads record.DocumentRecords from a stream of MessagePack maps. The
+// maps follow each other, self-delimited by default; set the Framing
+// attribute before the first call to Next to read length-prefixed ones.
+//
+// Values are converted like the other documents: arrays are arrays, nested
+// maps are objects whose keys are formatted if they aren't strings, binary
+// values are strings and timestamps are timestamps. The other extension types
+// can't be decoded and fail with an error. Strings keep their type, e.g.
+// "01234" isn't an int.
+//
+// The SoftMatching attribute is set on the records it returns. If the
+// UseDecimals attribute is set to true, floats are converted to decimals.
+type Source struct {
+	r   *bufio.Reader
+	dec *msgpack.Decoder
+	// the current map, with the length-prefixed framings
+	frame bytes.Buffer
+
+	Framing      Framing
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewSource returns a source which reads the MessagePack maps from the reader
+func NewSource(r io.Reader) *Source {
+	br := bufio.NewReader(r)
+	return &Source{r: br, dec: newDecoder(br)}
+}
+
+// newDecoder returns a decoder which keeps the maps whose keys aren't strings
+func newDecoder(r io.Reader) *msgpack.Decoder {
+	dec := msgpack.NewDecoder(r)
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+	return dec
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *Source) Next() (*record.DocumentRecord, error) {
+	var value interface{}
+
+	if s.Framing == SelfDelimited {
+		if err := s.dec.Decode(&value); err != nil {
+			return nil, err
+		}
+	} else {
+		frame, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := newDecoder(frame).Decode(&value); err != nil {
+			// the frame is too short
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if frame.Len() > 0 {
+			return nil, fmt.Errorf("Invalid MessagePack frame: %d bytes after the map", frame.Len())
+		}
+	}
+
+	r, err := record.NewDocumentRecordFromValue(value, s.UseDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+
+	return r, nil
+}
+
+// readFrame reads the next length-prefixed map
+func (s *Source) readFrame() (*bytes.Reader, error) {
+	var size uint64
+
+	switch s.Framing {
+	case Uint32Prefixed:
+		var b [4]byte
+		if _, err := io.ReadFull(s.r, b[:]); err != nil {
+			return nil, err
+		}
+		size = uint64(binary.BigEndian.Uint32(b[:]))
+
+	case VarintPrefixed:
+		n, err := binary.ReadUvarint(s.r)
+		if err != nil {
+			return nil, err
+		}
+		size = n
+
+	default:
+		return nil, fmt.Errorf("Unknown MessagePack framing %d", s.Framing)
+	}
+
+	if size > math.MaxInt32 {
+		return nil, fmt.Errorf("Invalid MessagePack frame size: %d", size)
+	}
+
+	// the buffer only grows with what's actually read, whatever the size
+	s.frame.Reset()
+	if _, err := io.CopyN(&s.frame, s.r, int64(size)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return bytes.NewReader(s.frame.Bytes()), nil
+}
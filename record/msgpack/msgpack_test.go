@@ -0,0 +1,241 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMessagePack encodes the values one after the other
+func writeMessagePack(t *testing.T, values ...interface{}) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf)
+	for _, v := range values {
+		require.Nil(t, enc.Encode(v))
+	}
+
+	return &buf
+}
+
+func TestSource(t *testing.T) {
+	s := NewSource(writeMessagePack(t,
+		map[string]interface{}{"name": "api"},
+		map[int]interface{}{1: "worker"},
+	))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("name"))
+	require.Nil(t, err)
+	assert.Equal(t, "api", c.AsString())
+
+	// the keys which aren't strings are formatted
+	r, err = s.Next()
+	require.Nil(t, err)
+
+	c, err = r.Find(ch.NewField("1"))
+	require.Nil(t, err)
+	assert.Equal(t, "worker", c.AsString())
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSourceIntegers(t *testing.T) {
+	s := NewSource(writeMessagePack(t, map[string]interface{}{
+		"negfix": int8(-3),
+		"uint8":  uint8(200),
+		"min":    int64(math.MinInt64),
+		"maxint": uint64(math.MaxInt64),
+		"max":    uint64(math.MaxUint64),
+	}))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	// signed and unsigned integers are ints, unless they don't fit in one
+	for name, expected := range map[string]interface{}{
+		"negfix": int64(-3),
+		"uint8":  int64(200),
+		"min":    int64(math.MinInt64),
+		"maxint": int64(math.MaxInt64),
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	c, err := r.Find(ch.NewField("max"))
+	require.Nil(t, err)
+	assert.Equal(t, "18446744073709551615", c.AsString())
+	assert.True(t, c.IsNumeric())
+}
+
+func TestSourceExtensions(t *testing.T) {
+	// {"at": timestamp ext -1 with 32-bit seconds}
+	s := NewSource(bytes.NewReader([]byte{0x81, 0xa2, 'a', 't', 0xd6, 0xff, 0x58, 0x63, 0x8d, 0xa0}))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("at"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(0x58638da0), c.Value().(time.Time).Unix())
+
+	// {"ext": fixext1 of the unregistered type 5}
+	s = NewSource(bytes.NewReader([]byte{0x81, 0xa3, 'e', 'x', 't', 0xd4, 0x05, 0x01}))
+
+	_, err = s.Next()
+	assert.NotNil(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
+
+func TestSourceValues(t *testing.T) {
+	ts := time.Date(2016, 12, 28, 10, 0, 0, 0, time.UTC)
+
+	s := NewSource(writeMessagePack(t, map[string]interface{}{
+		"id":      uint64(42),
+		"score":   float32(0.1),
+		"at":      ts,
+		"payload": []byte("hi"),
+		"tags":    []string{"a", "b"},
+		"codes":   map[int]string{404: "not found"},
+		"user":    map[string]interface{}{"name": "joe", "admin": true},
+		"none":    nil,
+		"zip":     "01234",
+		"flag":    "true",
+	}))
+	s.SoftMatching = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"id", "42"},
+		{"payload", "hi"},
+		{"tags[1]", "b"},
+		{"codes.404", "not found"},
+		{"user.name", "joe"},
+		{"user.admin", "true"},
+		{"none", "null"},
+		{"nope", "null"},
+	} {
+		c, err := r.Find(ch.NewField(tc.field))
+		require.Nil(t, err, tc.field)
+		assert.Equal(t, tc.expected, c.AsString(), tc.field)
+	}
+
+	c, err := r.Find(ch.NewField("at"))
+	require.Nil(t, err)
+	assert.True(t, ts.Equal(c.Value().(time.Time)))
+
+	c, err = r.Find(ch.NewField("score"))
+	require.Nil(t, err)
+	assert.Equal(t, 0.1, c.Value())
+
+	// strings aren't parsed
+	for name, expected := range map[string]string{"zip": "01234", "flag": "true"} {
+		c, err := r.Find(ch.NewField(name))
+		require.Nil(t, err, name)
+		assert.True(t, c.IsString(), name)
+		assert.Equal(t, expected, c.AsString(), name)
+	}
+}
+
+// writeFramed encodes the values, each one prefixed by its size
+func writeFramed(t *testing.T, framing Framing, values ...interface{}) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	for _, v := range values {
+		b, err := msgpack.Marshal(v)
+		require.Nil(t, err)
+
+		if framing == Uint32Prefixed {
+			buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(b))))
+		} else {
+			buf.Write(binary.AppendUvarint(nil, uint64(len(b))))
+		}
+		buf.Write(b)
+	}
+
+	return &buf
+}
+
+func TestSourceLengthPrefixed(t *testing.T) {
+	for _, framing := range []Framing{Uint32Prefixed, VarintPrefixed} {
+		s := NewSource(writeFramed(t, framing,
+			map[string]interface{}{"name": "api"},
+			map[string]interface{}{"name": "worker"},
+		))
+		s.Framing = framing
+
+		for _, name := range []string{"api", "worker"} {
+			r, err := s.Next()
+			require.Nil(t, err, framing)
+
+			c, err := r.Find(ch.NewField("name"))
+			require.Nil(t, err, framing)
+			assert.Equal(t, name, c.AsString(), framing)
+		}
+
+		_, err := s.Next()
+		assert.Equal(t, io.EOF, err, framing)
+	}
+
+	// read as self-delimited, the size prefix isn't a map
+	s := NewSource(writeFramed(t, Uint32Prefixed, map[string]interface{}{"name": "api"}))
+	_, err := s.Next()
+	assert.NotNil(t, err)
+}
+
+func TestSourceLengthPrefixedErrors(t *testing.T) {
+	buf := writeFramed(t, Uint32Prefixed, map[string]interface{}{"name": "api"})
+
+	// truncated
+	s := NewSource(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	s.Framing = Uint32Prefixed
+	_, err := s.Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// a frame longer than its map
+	b := append([]byte{0, 0, 0, byte(buf.Len() - 3)}, buf.Bytes()[4:]...)
+	b = append(b, 0xc0)
+	s = NewSource(bytes.NewReader(b))
+	s.Framing = Uint32Prefixed
+	_, err = s.Next()
+	assert.NotNil(t, err)
+
+	// an empty frame
+	s = NewSource(bytes.NewReader([]byte{0}))
+	s.Framing = VarintPrefixed
+	_, err = s.Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestSourceErrors(t *testing.T) {
+	s := NewSource(writeMessagePack(t, []int{1, 2}))
+	_, err := s.Next()
+	assert.NotNil(t, err)
+
+	// a truncated map
+	buf := writeMessagePack(t, map[string]interface{}{"name": "api"})
+	s = NewSource(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	_, err = s.Next()
+	assert.NotNil(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
@@ -0,0 +1,207 @@
+// Package parquet provides a source of records for Parquet files
+package parquet
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/BatchLabs/charlatan/record"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// julianUnixEpoch is the julian day of the unix epoch, used by the INT96
+// timestamps
+const julianUnixEpoch = 2440588
+
+// leafColumn is a leaf column of a Parquet file, with the place of its
+// values in the records
+type leafColumn struct {
+	// the index of the leaf column
+	index int
+	// the path of the column in the file, e.g. "tags.list.element"
+	name string
+	typ  parquet.Type
+	// the keys of the value in the records, e.g. "tags"
+	keys []string
+	// the number of keys of the array the values are elements of, or -1 if
+	// the column isn't repeated
+	arrayAt int
+	// the definition levels from which the array and its elements are defined
+	arrayDef, elementDef int
+	maxDef, maxRep       int
+}
+
+// leafColumns returns the leaf columns of a Parquet schema
+func leafColumns(root *parquet.Column) []*leafColumn {
+	var columns []*leafColumn
+
+	for _, c := range root.Columns() {
+		columns = walkColumn(c, leafColumn{arrayAt: -1}, false, false, columns)
+	}
+
+	return columns
+}
+
+// walkColumn adds the leaf columns of a column of the schema. The
+// state holds the keys and levels of its parent. wrapped is true if the parent
+// is a LIST or a MAP, and element if the column is the element of a LIST,
+// whose name isn't a key.
+func walkColumn(c *parquet.Column, state leafColumn, wrapped, element bool, columns []*leafColumn) []*leafColumn {
+	state.keys = append(state.keys[:len(state.keys):len(state.keys)], c.Name())
+	state.name = strings.Join(c.Path(), ".")
+
+	switch {
+	case c.Optional():
+		state.maxDef++
+
+	case c.Repeated():
+		state.arrayDef = state.maxDef
+		state.maxDef++
+		state.elementDef = state.maxDef
+		// the repeated group of a LIST or a MAP is the array of its parent
+		if wrapped {
+			state.keys = state.keys[:len(state.keys)-1]
+		}
+		state.arrayAt = len(state.keys)
+	}
+
+	if element {
+		state.keys = state.keys[:len(state.keys)-1]
+	}
+
+	if c.Leaf() {
+		state.index = c.Index()
+		state.typ = c.Type()
+		state.maxRep = c.MaxRepetitionLevel()
+		return append(columns, &state)
+	}
+
+	wrapper := false
+	if lt := c.Type().LogicalType(); lt != nil {
+		switch lt.Value.(type) {
+		case *format.ListType, *format.MapType:
+			wrapper = true
+		}
+	}
+
+	// the element of a 3-level list, unless it's a legacy 2-level list of
+	// groups, see the backward-compatibility rules of the Parquet format
+	isElement := wrapped && c.Repeated() && len(c.Columns()) == 1 &&
+		c.Name() != "array" && !strings.HasSuffix(c.Name(), "_tuple")
+
+	for _, child := range c.Columns() {
+		columns = walkColumn(child, state, wrapper, isElement, columns)
+	}
+
+	return columns
+}
+
+// valueToConst converts a value of a column of the given type to a
+// constant. Floats are converted to decimals if useDecimals is true.
+func valueToConst(v parquet.Value, typ parquet.Type, useDecimals bool) *ch.Const {
+	if v.IsNull() {
+		return ch.NullConst()
+	}
+
+	var logical interface{}
+	if lt := typ.LogicalType(); lt != nil {
+		logical = lt.Value
+	}
+
+	switch v.Kind() {
+	case parquet.Boolean:
+		return ch.BoolConst(v.Boolean())
+
+	case parquet.Int32:
+		if t, ok := logical.(*format.IntType); ok && !t.IsSigned {
+			return intToConst(int64(v.Uint32()), logical)
+		}
+		return intToConst(int64(v.Int32()), logical)
+
+	case parquet.Int64:
+		if t, ok := logical.(*format.IntType); ok && !t.IsSigned {
+			if u := v.Uint64(); u > math.MaxInt64 {
+				return ch.BigIntConst(new(big.Int).SetUint64(u))
+			}
+		}
+		return intToConst(v.Int64(), logical)
+
+	case parquet.Int96:
+		i := v.Int96()
+		nanos := int64(i[1])<<32 | int64(i[0])
+		days := int64(i[2]) - julianUnixEpoch
+		return ch.TimestampConst(time.Unix(days*86400, nanos).UTC())
+
+	case parquet.Float:
+		return record.Float32ToConst(v.Float(), useDecimals)
+
+	case parquet.Double:
+		return record.FloatToConst(v.Double(), useDecimals)
+	}
+
+	b := v.ByteArray()
+
+	switch t := logical.(type) {
+	case *format.DecimalType:
+		unscaled := new(big.Int).SetBytes(b)
+		// two's complement
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+		}
+		return decimalConst(unscaled, int(t.Scale))
+
+	case *format.UUIDType:
+		if len(b) == 16 {
+			return ch.StringConst(record.FormatUUID(b))
+		}
+	}
+
+	return ch.StringConst(string(b))
+}
+
+// intToConst converts an integer to a constant according to its
+// logical type
+func intToConst(n int64, logical interface{}) *ch.Const {
+	switch t := logical.(type) {
+	case *format.DateType:
+		return ch.TimestampConst(time.Unix(n*86400, 0).UTC())
+
+	case *format.TimestampType:
+		switch t.Unit.Value.(type) {
+		case *format.MilliSeconds:
+			return ch.TimestampConst(time.UnixMilli(n).UTC())
+		case *format.MicroSeconds:
+			return ch.TimestampConst(time.UnixMicro(n).UTC())
+		}
+		return ch.TimestampConst(time.Unix(0, n).UTC())
+
+	case *format.TimeType:
+		return ch.DurationConst(unitDuration(n, t.Unit))
+
+	case *format.DecimalType:
+		return decimalConst(big.NewInt(n), int(t.Scale))
+	}
+
+	return ch.IntConst(n)
+}
+
+// unitDuration returns the duration of n time units
+func unitDuration(n int64, unit format.TimeUnit) time.Duration {
+	switch unit.Value.(type) {
+	case *format.MilliSeconds:
+		return time.Duration(n) * time.Millisecond
+	case *format.MicroSeconds:
+		return time.Duration(n) * time.Microsecond
+	}
+	return time.Duration(n)
+}
+
+// decimalConst returns the decimal of an unscaled value
+func decimalConst(unscaled *big.Int, scale int) *ch.Const {
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return ch.DecimalConst(new(big.Rat).SetFrac(unscaled, denom), scale)
+}
@@ -0,0 +1,187 @@
+package parquet
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/BatchLabs/charlatan/record"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeParquet writes the rows to a Parquet file in memory
+func writeParquet[T any](t *testing.T, rows []T, options ...parquet.WriterOption) *bytes.Reader {
+	var buf bytes.Buffer
+
+	w := parquet.NewGenericWriter[T](&buf, options...)
+	_, err := w.Write(rows)
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+// readParquet returns the first record of a Parquet file
+func readParquet(t *testing.T, r *bytes.Reader) *record.DocumentRecord {
+	s, err := NewSource(r, r.Size(), nil)
+	require.Nil(t, err)
+
+	rec, err := s.Next()
+	require.Nil(t, err)
+
+	return rec
+}
+
+type typesRow struct {
+	Bool     bool      `parquet:"bool"`
+	Int8     int8      `parquet:"int8"`
+	Uint64   uint64    `parquet:"uint64"`
+	Float    float32   `parquet:"float"`
+	Double   float64   `parquet:"double"`
+	String   string    `parquet:"string"`
+	Date     int32     `parquet:"date,date"`
+	Millis   time.Time `parquet:"millis,timestamp(millisecond)"`
+	Time     int64     `parquet:"time,time(microsecond)"`
+	Decimal  int64     `parquet:"decimal,decimal(2:18)"`
+	Fixed    [8]byte   `parquet:"fixed,decimal(3:18)"`
+	UUID     [16]byte  `parquet:"uuid,uuid"`
+	Optional *int64    `parquet:"optional,optional"`
+}
+
+func TestValueTypes(t *testing.T) {
+	ts := time.Date(2016, 12, 28, 23, 30, 15, 123000000, time.UTC)
+
+	r := readParquet(t, writeParquet(t, []typesRow{{
+		Bool:    true,
+		Int8:    -3,
+		Uint64:  math.MaxUint64,
+		Float:   0.1,
+		Double:  2.5,
+		String:  "42",
+		Date:    17163,
+		Millis:  ts,
+		Time:    90000000,
+		Decimal: -12345,
+		Fixed:   [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe},
+		UUID:    [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 8},
+	}}))
+
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"bool", "true"},
+		{"int8", "-3"},
+		{"uint64", "18446744073709551615"},
+		{"double", "2.50"},
+		{"string", "42"},
+		{"date", "2016-12-28T00:00:00Z"},
+		{"millis", "2016-12-28T23:30:15.123Z"},
+		{"time", "1m30s"},
+		{"decimal", "-123.45"},
+		{"fixed", "-0.002"},
+		{"uuid", "12345678-9abc-def0-0102-030405060708"},
+		{"optional", "null"},
+	} {
+		c, err := r.Find(ch.NewField(tc.field))
+		require.Nil(t, err, tc.field)
+		assert.Equal(t, tc.expected, c.AsString(), tc.field)
+	}
+
+	c, err := r.Find(ch.NewField("string"))
+	require.Nil(t, err)
+	assert.True(t, c.IsString())
+
+	c, err = r.Find(ch.NewField("float"))
+	require.Nil(t, err)
+	assert.Equal(t, 0.1, c.Value())
+}
+
+type nestedRow struct {
+	Name  string            `parquet:"name"`
+	Tags  []string          `parquet:"tags,list"`
+	Nums  []int32           `parquet:"nums"`
+	Attrs map[string]string `parquet:"attrs"`
+	Items []struct {
+		Name  string  `parquet:"name"`
+		Price float64 `parquet:"price"`
+	} `parquet:"items,list"`
+	Address *struct {
+		City string `parquet:"city"`
+	} `parquet:"address,optional"`
+}
+
+func TestNestedValues(t *testing.T) {
+	row := nestedRow{
+		Name:  "joe",
+		Tags:  []string{"a", "b"},
+		Nums:  []int32{1, 2, 3},
+		Attrs: map[string]string{"k": "v"},
+	}
+	row.Items = append(row.Items, struct {
+		Name  string  `parquet:"name"`
+		Price float64 `parquet:"price"`
+	}{"pen", 1.5})
+	row.Address = &struct {
+		City string `parquet:"city"`
+	}{"Paris"}
+
+	r := readParquet(t, writeParquet(t, []nestedRow{row}))
+
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"tags[1]", "b"},
+		{"nums[-1]", "3"},
+		{"attrs[0].key", "k"},
+		{"attrs[0].value", "v"},
+		{"items[0].name", "pen"},
+		{"address.city", "Paris"},
+	} {
+		c, err := r.Find(ch.NewField(tc.field))
+		require.Nil(t, err, tc.field)
+		assert.Equal(t, tc.expected, c.AsString(), tc.field)
+	}
+
+	c, err := r.Find(ch.NewField("tags"))
+	require.Nil(t, err)
+	assert.True(t, c.IsArray())
+
+	c, err = r.Find(ch.NewField("*"))
+	require.Nil(t, err)
+	assert.Contains(t, c.AsString(), `"name":"joe"`)
+}
+
+func TestEmptyLists(t *testing.T) {
+	r := readParquet(t, writeParquet(t, []nestedRow{{Name: "joe"}}))
+
+	c, err := r.Find(ch.NewField("nums"))
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(c.Elements()))
+
+	c, err = r.Find(ch.NewField("address.city"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
+
+func TestUnknownField(t *testing.T) {
+	r := readParquet(t, writeParquet(t, []nestedRow{{Name: "joe"}}))
+
+	_, err := r.Find(ch.NewField("nope"))
+	assert.NotNil(t, err)
+
+	_, err = r.Find(ch.NewField(""))
+	assert.NotNil(t, err)
+
+	r.SoftMatching = true
+
+	c, err := r.Find(ch.NewField("nope"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
@@ -0,0 +1,408 @@
+package parquet
+
+import (
+	"fmt"
+	"io"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/BatchLabs/charlatan/record"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Source reads the rows of a Parquet file as record.DocumentRecords, one row
+// group at a time.
+//
+// Groups are objects and repeated fields, lists included, are arrays, so that
+// field paths go into them as into JSON records. Maps are arrays of objects
+// with a "key" and a "value". Values are converted according to their logical
+// type: dates and timestamps are timestamps, times are durations, decimals
+// are decimals and large unsigned integers are bigints. The special field "*"
+// returns the columns which were read as JSON.
+//
+// When it's given a query, only the columns of its fields are read, and the
+// row groups whose statistics show that none of their rows can match its
+// condition are skipped, see charlatan.Query.MayMatch. Columns repeated within
+// repeated fields, e.g. lists of lists, aren't supported.
+//
+// The SoftMatching attribute is set on the records it returns. If the
+// UseDecimals attribute is set to true, floats and doubles are converted to
+// decimals.
+type Source struct {
+	file  *parquet.File
+	query *ch.Query
+	// the columns which are read
+	columns []*leafColumn
+	// the index of the next row group
+	rowGroup int
+	// the values of each column in the current row group, and the next row
+	values  []columnValues
+	numRows int
+	row     int
+	// the number of row groups skipped thanks to their statistics
+	skipped int
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// columnValues are the values of a column in a row group
+type columnValues struct {
+	cells []leafValue
+	// the index of the first cell of each row
+	starts []int
+}
+
+// leafValue is a value of a column with its definition level
+type leafValue struct {
+	value *ch.Const
+	def   int
+}
+
+// NewSource returns a source which reads the Parquet file of the given
+// size. The query may be nil, in which case all the columns and row groups are
+// read.
+func NewSource(r io.ReaderAt, size int64, query *ch.Query) (*Source, error) {
+	file, err := parquet.OpenFile(r, size, parquet.SkipPageIndex(true), parquet.SkipBloomFilters(true))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Source{
+		file:    file,
+		query:   query,
+		columns: projectColumns(leafColumns(file.Root()), query),
+	}
+
+	for _, c := range s.columns {
+		if c.maxRep > 1 {
+			return nil, fmt.Errorf("Unsupported nested repeated column %s", c.name)
+		}
+	}
+
+	return s, nil
+}
+
+// Columns returns the path of the columns which are read, e.g.
+// "tags.list.element"
+func (s *Source) Columns() []string {
+	names := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *Source) Next() (*record.DocumentRecord, error) {
+	for s.row >= s.numRows {
+		if err := s.nextRowGroup(); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := record.NewDocumentRecord(s.rowValue(s.row))
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+	s.row++
+
+	return r, nil
+}
+
+// nextRowGroup reads the next row group which may have matching rows, or
+// returns io.EOF if there's none left
+func (s *Source) nextRowGroup() error {
+	groups := s.file.RowGroups()
+
+	for s.rowGroup < len(groups) {
+		i := s.rowGroup
+		s.rowGroup++
+
+		if s.query != nil && !s.query.MayMatch(s.fieldRanges(i)) {
+			s.skipped++
+			continue
+		}
+
+		return s.readRowGroup(groups[i])
+	}
+
+	return io.EOF
+}
+
+// readRowGroup reads the values of the columns in a row group
+func (s *Source) readRowGroup(rg parquet.RowGroup) error {
+	chunks := rg.ColumnChunks()
+
+	s.values = make([]columnValues, len(s.columns))
+	for i, c := range s.columns {
+		values, err := readColumn(chunks[c.index], c, s.UseDecimals)
+		if err != nil {
+			return err
+		}
+		s.values[i] = values
+	}
+
+	s.numRows = int(rg.NumRows())
+	s.row = 0
+
+	return nil
+}
+
+// readColumn reads the values of a column chunk
+func readColumn(chunk parquet.ColumnChunk, c *leafColumn, useDecimals bool) (columnValues, error) {
+	var values columnValues
+
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	buffer := make([]parquet.Value, 1024)
+
+	for {
+		page, err := pages.ReadPage()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return values, fmt.Errorf("Can't read the %s column: %v", c.name, err)
+		}
+
+		reader := page.Values()
+
+		for {
+			n, err := reader.ReadValues(buffer)
+
+			for _, v := range buffer[:n] {
+				if v.RepetitionLevel() == 0 {
+					values.starts = append(values.starts, len(values.cells))
+				}
+				values.cells = append(values.cells, leafValue{
+					value: valueToConst(v, c.typ, useDecimals),
+					def:   v.DefinitionLevel(),
+				})
+			}
+
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				parquet.Release(page)
+				return values, fmt.Errorf("Can't read the %s column: %v", c.name, err)
+			}
+		}
+
+		parquet.Release(page)
+	}
+}
+
+// rowValue returns the object of a row of the current row group
+func (s *Source) rowValue(row int) *ch.Const {
+	object := make(map[string]interface{})
+
+	for i, c := range s.columns {
+		values := s.values[i]
+
+		end := len(values.cells)
+		if row+1 < len(values.starts) {
+			end = values.starts[row+1]
+		}
+		cells := values.cells[values.starts[row]:end]
+
+		if c.arrayAt < 0 {
+			setValue(object, c.keys, cells[0].value)
+			continue
+		}
+
+		arrayKeys, elementKeys := c.keys[:c.arrayAt], c.keys[c.arrayAt:]
+
+		// a null list
+		if cells[0].def < c.arrayDef {
+			setValue(object, arrayKeys, ch.NullConst())
+			continue
+		}
+
+		array := objectArray(object, arrayKeys)
+
+		// an empty list
+		if cells[0].def < c.elementDef {
+			continue
+		}
+
+		for j, cell := range cells {
+			if j == len(*array) {
+				*array = append(*array, nil)
+			}
+
+			if len(elementKeys) == 0 {
+				(*array)[j] = cell.value
+				continue
+			}
+
+			element, ok := (*array)[j].(map[string]interface{})
+			if !ok {
+				element = make(map[string]interface{})
+				(*array)[j] = element
+			}
+			setValue(element, elementKeys, cell.value)
+		}
+	}
+
+	return treeToConst(object)
+}
+
+// fieldRanges returns the ranges of the fields in a row group, from the
+// statistics of their column. Only the fields of non-repeated columns have
+// one.
+func (s *Source) fieldRanges(rowGroup int) func(*ch.Field) (ch.FieldRange, bool) {
+	chunks := s.file.Metadata().RowGroups[rowGroup].Columns
+
+	return func(f *ch.Field) (ch.FieldRange, bool) {
+		keys, exact := fieldKeys(f)
+		if !exact {
+			return ch.FieldRange{}, false
+		}
+
+		for _, c := range s.columns {
+			if c.arrayAt >= 0 || !equalKeys(c.keys, keys) {
+				continue
+			}
+
+			stats := chunks[c.index].MetaData.Statistics
+			kind := c.typ.Kind()
+
+			// INT96 values aren't ordered as the timestamps they hold
+			if stats.MinValue == nil || stats.MaxValue == nil || kind == parquet.Int96 {
+				return ch.FieldRange{}, false
+			}
+
+			return ch.FieldRange{
+				Min:     valueToConst(kind.Value(stats.MinValue), c.typ, false),
+				Max:     valueToConst(kind.Value(stats.MaxValue), c.typ, false),
+				HasNull: stats.NullCount > 0,
+			}, true
+		}
+
+		return ch.FieldRange{}, false
+	}
+}
+
+// projectColumns returns the columns needed by the fields of the query,
+// all of them if it's nil or selects "*"
+func projectColumns(columns []*leafColumn, query *ch.Query) []*leafColumn {
+	if query == nil {
+		return columns
+	}
+
+	fields := query.AllFields()
+
+	var projected []*leafColumn
+
+	for _, c := range columns {
+		for _, f := range fields {
+			keys, _ := fieldKeys(f)
+			if f.Name() == "*" || isKeysPrefix(c.keys, keys) || isKeysPrefix(keys, c.keys) {
+				projected = append(projected, c)
+				break
+			}
+		}
+	}
+
+	return projected
+}
+
+// fieldKeys returns the keys at the start of the path of a field, and
+// whether the path is only made of keys
+func fieldKeys(f *ch.Field) ([]string, bool) {
+	path := f.Path()
+	keys := make([]string, 0, len(path))
+
+	for _, seg := range path {
+		key, ok := seg.Key()
+		if !ok {
+			return keys, false
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, true
+}
+
+// isKeysPrefix tests if the keys start with the given prefix
+func isKeysPrefix(keys, prefix []string) bool {
+	return len(prefix) <= len(keys) && equalKeys(keys[:len(prefix)], prefix)
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// setValue sets a value in an object, creating the objects along the
+// keys
+func setValue(object map[string]interface{}, keys []string, value *ch.Const) {
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			object[key] = child
+		}
+		object = child
+	}
+
+	object[keys[len(keys)-1]] = value
+}
+
+// objectArray returns the array at the given keys of an object, creating it
+// if needed
+func objectArray(object map[string]interface{}, keys []string) *[]interface{} {
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			object[key] = child
+		}
+		object = child
+	}
+
+	key := keys[len(keys)-1]
+
+	array, ok := object[key].(*[]interface{})
+	if !ok {
+		array = &[]interface{}{}
+		object[key] = array
+	}
+
+	return array
+}
+
+// treeToConst converts the objects and arrays of a row to a constant
+func treeToConst(node interface{}) *ch.Const {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		members := make(map[string]*ch.Const, len(n))
+		for key, member := range n {
+			members[key] = treeToConst(member)
+		}
+		return ch.ObjectConst(members)
+
+	case *[]interface{}:
+		elements := make([]*ch.Const, len(*n))
+		for i, element := range *n {
+			elements[i] = treeToConst(element)
+		}
+		return ch.ArrayConst(elements)
+
+	case *ch.Const:
+		return n
+	}
+
+	return ch.NullConst()
+}
@@ -0,0 +1,176 @@
+package parquet
+
+import (
+	"io"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type person struct {
+	Name  string   `parquet:"name"`
+	Age   int64    `parquet:"age"`
+	Score *float64 `parquet:"score,optional"`
+	City  string   `parquet:"city"`
+}
+
+var annaScore = 5.0
+
+// people are sorted by age, in row groups of 2 rows
+var people = []person{
+	{Name: "anna", Age: 10, Score: &annaScore, City: "Paris"},
+	{Name: "bob", Age: 20, City: "Lyon"},
+	{Name: "carl", Age: 30, City: "Paris"},
+	{Name: "dana", Age: 40, City: "Nice"},
+	{Name: "eve", Age: 50, City: "Lyon"},
+}
+
+// queryNames returns the names of the people matching the condition,
+// and the source which read them
+func queryNames(t *testing.T, where string) ([]string, *Source) {
+	r := writeParquet(t, people, parquet.MaxRowsPerRowGroup(2))
+
+	query, err := ch.QueryFromString("SELECT name FROM people WHERE " + where)
+	require.Nil(t, err)
+
+	s, err := NewSource(r, r.Size(), query)
+	require.Nil(t, err)
+
+	var names []string
+
+	for {
+		record, err := s.Next()
+		if err == io.EOF {
+			return names, s
+		}
+		require.Nil(t, err)
+
+		ok, err := query.Evaluate(record)
+		require.Nil(t, err)
+		if !ok {
+			continue
+		}
+
+		values, err := query.FieldsValues(record)
+		require.Nil(t, err)
+		names = append(names, values[0].AsString())
+	}
+}
+
+func TestSourceSkipsRowGroups(t *testing.T) {
+	names, s := queryNames(t, "age > 35")
+	assert.Equal(t, []string{"dana", "eve"}, names)
+	assert.Equal(t, 1, s.skipped)
+
+	names, s = queryNames(t, "age BETWEEN 20 AND 30")
+	assert.Equal(t, []string{"bob", "carl"}, names)
+	assert.Equal(t, 1, s.skipped)
+
+	names, s = queryNames(t, "age = 20 OR name = \"eve\"")
+	assert.Equal(t, []string{"bob", "eve"}, names)
+	assert.Equal(t, 1, s.skipped)
+
+	names, s = queryNames(t, "city = \"Lyon\"")
+	assert.Equal(t, []string{"bob", "eve"}, names)
+	assert.Equal(t, 1, s.skipped)
+
+	names, s = queryNames(t, "age > 100")
+	assert.Nil(t, names)
+	assert.Equal(t, 3, s.skipped)
+}
+
+func TestSourceNullStatistics(t *testing.T) {
+	// the first row group has a score of 5 but also a null one, which is
+	// lower than any number
+	names, s := queryNames(t, "score < 1")
+	assert.Equal(t, []string{"bob", "carl", "dana", "eve"}, names)
+	assert.Equal(t, 0, s.skipped)
+
+	names, s = queryNames(t, "score > 6")
+	assert.Nil(t, names)
+	assert.Equal(t, 1, s.skipped)
+}
+
+func TestSourceProjection(t *testing.T) {
+	r := writeParquet(t, people)
+
+	query, err := ch.QueryFromString("SELECT name FROM people WHERE age > 25")
+	require.Nil(t, err)
+
+	s, err := NewSource(r, r.Size(), query)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"name", "age"}, s.Columns())
+
+	record, err := s.Next()
+	require.Nil(t, err)
+
+	_, err = record.Find(ch.NewField("city"))
+	assert.NotNil(t, err)
+
+	query, err = ch.QueryFromString("SELECT * FROM people")
+	require.Nil(t, err)
+
+	s, err = NewSource(r, r.Size(), query)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"name", "age", "score", "city"}, s.Columns())
+
+	s, err = NewSource(r, r.Size(), nil)
+	require.Nil(t, err)
+	assert.Equal(t, 4, len(s.Columns()))
+}
+
+func TestSourceAllRows(t *testing.T) {
+	r := writeParquet(t, people, parquet.MaxRowsPerRowGroup(2))
+
+	s, err := NewSource(r, r.Size(), nil)
+	require.Nil(t, err)
+	s.UseDecimals = true
+
+	n := 0
+	for {
+		record, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+
+		c, err := record.Find(ch.NewField("age"))
+		require.Nil(t, err)
+		assert.Equal(t, int64(10*(n+1)), c.Value())
+		n++
+	}
+
+	assert.Equal(t, 5, n)
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSourceNestedLists(t *testing.T) {
+	type nested struct {
+		Matrix [][]int32 `parquet:"matrix,list"`
+		Name   string    `parquet:"name"`
+	}
+
+	r := writeParquet(t, []nested{{Name: "m"}})
+
+	query, err := ch.QueryFromString("SELECT name FROM x")
+	require.Nil(t, err)
+
+	_, err = NewSource(r, r.Size(), query)
+	assert.Nil(t, err)
+
+	_, err = NewSource(r, r.Size(), nil)
+	assert.NotNil(t, err)
+}
+
+func TestSourceInvalidFile(t *testing.T) {
+	r := writeParquet(t, people)
+
+	_, err := NewSource(io.NewSectionReader(r, 0, 10), 10, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,207 @@
+// Package protobuf provides a source of records for Protocol Buffers messages
+package protobuf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/BatchLabs/charlatan/record"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Source reads record.DocumentRecords from a stream of length-delimited
+// Protocol Buffers messages, each one prefixed by its size as a varint. The
+// messages are decoded dynamically from their descriptor, without generated
+// code.
+//
+// The fields of the messages are the fields of the records, with their names
+// in the .proto file. Nested messages and maps are objects, repeated fields
+// are arrays and enums are the names of their values, or their numbers if
+// they're unknown. Unknown fields are skipped. Unset fields have their
+// default value, except the ones with presence, e.g. messages and proto3
+// optional fields, which are null. The Timestamp and Duration messages are
+// timestamps and durations, and the wrappers, e.g. Int64Value, their value.
+// Strings keep their type, e.g. "01234" isn't an int.
+//
+// The SoftMatching attribute is set on the records it returns. If the
+// UseDecimals attribute is set to true, floats and doubles are converted to
+// decimals.
+type Source struct {
+	r          *bufio.Reader
+	descriptor protoreflect.MessageDescriptor
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// ReadDescriptorSet reads a serialized FileDescriptorSet, e.g. one
+// written by protoc --descriptor_set_out --include_imports
+func ReadDescriptorSet(r io.Reader) (*descriptorpb.FileDescriptorSet, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// NewSource returns a source which reads the messages of the given
+// full name, e.g. "events.v1.Event", from the reader. The descriptor set must
+// contain the message's file and all its dependencies.
+func NewSource(r io.Reader, set *descriptorpb.FileDescriptorSet, message string) (*Source, error) {
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(message))
+	if err != nil {
+		return nil, fmt.Errorf("Unknown message %s: %v", message, err)
+	}
+
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s isn't a message", message)
+	}
+
+	return &Source{r: bufio.NewReader(r), descriptor: md}, nil
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *Source) Next() (*record.DocumentRecord, error) {
+	m := dynamicpb.NewMessage(s.descriptor)
+
+	if err := protodelim.UnmarshalFrom(s.r, m); err != nil {
+		return nil, err
+	}
+
+	r, err := record.NewDocumentRecordFromValue(messageValue(m), s.UseDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+
+	return r, nil
+}
+
+// messageValue returns the members of a message, or the value of the
+// well-known types, which record.DocumentValueToConst converts
+func messageValue(m protoreflect.Message) interface{} {
+	md := m.Descriptor()
+
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		seconds, nanos := secondsAndNanos(m)
+		return time.Unix(seconds, nanos).UTC()
+
+	case "google.protobuf.Duration":
+		seconds, nanos := secondsAndNanos(m)
+		return time.Duration(seconds)*time.Second + time.Duration(nanos)
+
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue",
+		"google.protobuf.BytesValue":
+		fd := md.Fields().ByName("value")
+		return singleValue(fd, m.Get(fd))
+	}
+
+	fields := md.Fields()
+	members := make(map[string]interface{}, fields.Len())
+
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+
+		if fd.HasPresence() && !m.Has(fd) {
+			members[string(fd.Name())] = nil
+			continue
+		}
+
+		members[string(fd.Name())] = fieldValue(fd, m.Get(fd))
+	}
+
+	return members
+}
+
+// secondsAndNanos returns the fields of a Timestamp or a Duration
+func secondsAndNanos(m protoreflect.Message) (int64, int64) {
+	fields := m.Descriptor().Fields()
+	return m.Get(fields.ByName("seconds")).Int(), m.Get(fields.ByName("nanos")).Int()
+}
+
+// fieldValue returns the value of a field, repeated or not
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		elements := make([]interface{}, list.Len())
+		for i := range elements {
+			elements[i] = singleValue(fd, list.Get(i))
+		}
+		return elements
+
+	case fd.IsMap():
+		members := make(map[string]interface{}, v.Map().Len())
+		v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			members[key.String()] = singleValue(fd.MapValue(), value)
+			return true
+		})
+		return members
+	}
+
+	return singleValue(fd, v)
+}
+
+// singleValue returns a single value of a field
+func singleValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int()
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return v.Uint()
+
+	case protoreflect.FloatKind:
+		return float32(v.Float())
+
+	case protoreflect.DoubleKind:
+		return v.Float()
+
+	case protoreflect.StringKind:
+		return v.String()
+
+	case protoreflect.BytesKind:
+		return v.Bytes()
+
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		// an unknown value
+		return int64(v.Enum())
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageValue(v.Message())
+	}
+
+	return nil
+}
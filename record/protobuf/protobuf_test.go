@@ -0,0 +1,361 @@
+package protobuf
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// field returns the descriptor of a field
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   typ.Enum(),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+// repeated marks a field as repeated
+func repeated(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+// eventSet returns the descriptors of the events.v1.Event message and
+// of its dependencies
+func eventSet() *descriptorpb.FileDescriptorSet {
+	const (
+		typeString  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		typeMessage = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+
+	user := field("user", 8, typeString, "")
+	user.Proto3Optional = proto.Bool(true)
+	user.OneofIndex = proto.Int32(0)
+
+	event := &descriptorpb.DescriptorProto{
+		Name: proto.String("Event"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+			field("name", 2, typeString, ""),
+			field("level", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".events.v1.Level"),
+			repeated(field("tags", 4, typeString, "")),
+			repeated(field("counts", 5, typeMessage, ".events.v1.Event.CountsEntry")),
+			field("source", 6, typeMessage, ".events.v1.Source"),
+			field("at", 7, typeMessage, ".google.protobuf.Timestamp"),
+			user,
+			field("payload", 9, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+			field("big", 10, descriptorpb.FieldDescriptorProto_TYPE_UINT64, ""),
+			field("score", 11, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, ""),
+			repeated(field("sources", 12, typeMessage, ".events.v1.Source")),
+			field("retries", 13, typeMessage, ".google.protobuf.Int32Value"),
+		},
+		NestedType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("CountsEntry"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("key", 1, typeString, ""),
+				field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+			},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}},
+		OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_user")}},
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("events.proto"),
+		Package:    proto.String("events.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto", "google/protobuf/wrappers.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			event,
+			{
+				Name: proto.String("Source"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("host", 1, typeString, ""),
+					field("port", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				},
+			},
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Level"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("INFO"), Number: proto.Int32(0)},
+				{Name: proto.String("ERROR"), Number: proto.Int32(1)},
+			},
+		}},
+	}
+
+	return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto),
+		protodesc.ToFileDescriptorProto(wrapperspb.File_google_protobuf_wrappers_proto),
+		file,
+	}}
+}
+
+// writeEvents writes length-delimited events from their JSON
+// representation
+func writeEvents(t *testing.T, events ...string) *bytes.Buffer {
+	files, err := protodesc.NewFiles(eventSet())
+	require.Nil(t, err)
+
+	d, err := files.FindDescriptorByName("events.v1.Event")
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	for _, event := range events {
+		m := dynamicpb.NewMessage(d.(protoreflect.MessageDescriptor))
+		require.Nil(t, protojson.Unmarshal([]byte(event), m))
+
+		_, err := protodelim.MarshalTo(&buf, m)
+		require.Nil(t, err)
+	}
+
+	return &buf
+}
+
+func TestSource(t *testing.T) {
+	s, err := NewSource(writeEvents(t,
+		`{"name": "api"}`,
+		`{"name": "worker"}`,
+	), eventSet(), "events.v1.Event")
+	require.Nil(t, err)
+
+	for _, name := range []string{"api", "worker"} {
+		r, err := s.Next()
+		require.Nil(t, err, name)
+
+		c, err := r.Find(ch.NewField("name"))
+		require.Nil(t, err, name)
+		assert.Equal(t, name, c.AsString())
+	}
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSourceIntegers(t *testing.T) {
+	s, err := NewSource(writeEvents(t,
+		`{"id": "-9223372036854775808", "big": "9223372036854775807"}`,
+		`{"id": "-1", "big": "9223372036854775808"}`,
+	), eventSet(), "events.v1.Event")
+	require.Nil(t, err)
+
+	// int64 fields are ints, uint64 fields are bigints beyond the ints
+	for _, expected := range [][2]string{
+		{"-9223372036854775808", "9223372036854775807"},
+		{"-1", "9223372036854775808"},
+	} {
+		r, err := s.Next()
+		require.Nil(t, err)
+
+		c, err := r.Find(ch.NewField("id"))
+		require.Nil(t, err)
+		assert.Equal(t, expected[0], c.AsString())
+
+		c, err = r.Find(ch.NewField("big"))
+		require.Nil(t, err)
+		assert.Equal(t, expected[1], c.AsString())
+		assert.True(t, c.IsNumeric())
+	}
+}
+
+func TestSourceUnknownValues(t *testing.T) {
+	// level = 7, which isn't a Level value, and the unknown field 99 = 1
+	msg := []byte{0x18, 0x07, 0x98, 0x06, 0x01}
+
+	s, err := NewSource(bytes.NewReader(append([]byte{byte(len(msg))}, msg...)), eventSet(), "events.v1.Event")
+	require.Nil(t, err)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	// unknown enum values are their number, unknown fields are skipped
+	c, err := r.Find(ch.NewField("level"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(7), c.Value())
+
+	c, err = r.Find(ch.NewField("*"))
+	require.Nil(t, err)
+	assert.NotContains(t, c.AsString(), "99")
+}
+
+func TestSourceValues(t *testing.T) {
+	s, err := NewSource(writeEvents(t, `{
+		"id": "42",
+		"name": "api",
+		"level": "ERROR",
+		"tags": ["a", "b"],
+		"counts": {"x": 3},
+		"source": {"host": "web1", "port": 8080},
+		"at": "2016-12-28T10:00:00Z",
+		"payload": "aGk=",
+		"big": "18446744073709551615",
+		"score": 0.1,
+		"sources": [{"host": "a"}, {"host": "b", "port": 80}],
+		"retries": 2
+	}`), eventSet(), "events.v1.Event")
+	require.Nil(t, err)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"id", "42"},
+		{"level", "ERROR"},
+		{"tags[1]", "b"},
+		{"counts.x", "3"},
+		{"source.host", "web1"},
+		{"source.port", "8080"},
+		{"at", "2016-12-28T10:00:00Z"},
+		{"user", "null"},
+		{"payload", "hi"},
+		{"big", "18446744073709551615"},
+		{"sources[0].port", "0"},
+		{"sources[1].host", "b"},
+		{"retries", "2"},
+	} {
+		c, err := r.Find(ch.NewField(tc.field))
+		require.Nil(t, err, tc.field)
+		assert.Equal(t, tc.expected, c.AsString(), tc.field)
+	}
+
+	c, err := r.Find(ch.NewField("score"))
+	require.Nil(t, err)
+	assert.Equal(t, 0.1, c.Value())
+}
+
+func TestSourceDefaults(t *testing.T) {
+	s, err := NewSource(writeEvents(t, `{"user": ""}`), eventSet(), "events.v1.Event")
+	require.Nil(t, err)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"id", "0"},
+		{"level", "INFO"},
+		{"tags", "[]"},
+		{"source", "null"},
+		{"at", "null"},
+		{"user", ""},
+		{"retries", "null"},
+	} {
+		c, err := r.Find(ch.NewField(tc.field))
+		require.Nil(t, err, tc.field)
+		assert.Equal(t, tc.expected, c.AsString(), tc.field)
+	}
+}
+
+func TestSourceStrings(t *testing.T) {
+	s, err := NewSource(writeEvents(t,
+		`{"name": "01234"}`,
+		`{"name": "true"}`,
+	), eventSet(), "events.v1.Event")
+	require.Nil(t, err)
+
+	query, err := ch.QueryFromString(`SELECT name FROM events.pb WHERE name = "01234"`)
+	require.Nil(t, err)
+
+	for _, expected := range []bool{true, false} {
+		r, err := s.Next()
+		require.Nil(t, err)
+
+		// strings aren't parsed
+		c, err := r.Find(ch.NewField("name"))
+		require.Nil(t, err)
+		assert.True(t, c.IsString())
+
+		ok, err := query.Evaluate(r)
+		require.Nil(t, err)
+		assert.Equal(t, expected, ok)
+	}
+}
+
+func TestSourceQuery(t *testing.T) {
+	s, err := NewSource(writeEvents(t,
+		`{"name": "api", "level": "INFO"}`,
+		`{"name": "worker", "level": "ERROR"}`,
+	), eventSet(), "events.v1.Event")
+	require.Nil(t, err)
+
+	query, err := ch.QueryFromString("SELECT name FROM events.pb WHERE level = \"ERROR\"")
+	require.Nil(t, err)
+
+	var names []string
+
+	for {
+		r, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+
+		if ok, _ := query.Evaluate(r); ok {
+			values, err := query.FieldsValues(r)
+			require.Nil(t, err)
+			names = append(names, values[0].AsString())
+		}
+	}
+
+	assert.Equal(t, []string{"worker"}, names)
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := NewSource(&bytes.Buffer{}, eventSet(), "events.v1.Nope")
+	assert.NotNil(t, err)
+
+	_, err = NewSource(&bytes.Buffer{}, eventSet(), "events.v1.Level")
+	assert.NotNil(t, err)
+
+	// the dependencies are missing
+	set := eventSet()
+	set.File = set.File[2:]
+	_, err = NewSource(&bytes.Buffer{}, set, "events.v1.Event")
+	assert.NotNil(t, err)
+
+	// a truncated message
+	buf := writeEvents(t, `{"name": "api"}`)
+	s, err := NewSource(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), eventSet(), "events.v1.Event")
+	require.Nil(t, err)
+	_, err = s.Next()
+	assert.NotNil(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
+
+func TestReadDescriptorSet(t *testing.T) {
+	b, err := proto.Marshal(eventSet())
+	require.Nil(t, err)
+
+	set, err := ReadDescriptorSet(bytes.NewReader(b))
+	require.Nil(t, err)
+	assert.Equal(t, 3, len(set.File))
+
+	_, err = ReadDescriptorSet(bytes.NewReader([]byte{0xff}))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,214 @@
+package record
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+const (
+	// lineField is the pseudo-field of the whole line
+	lineField = "_line"
+	// lineNumberField is the pseudo-field of the line number
+	lineNumberField = "_lineno"
+)
+
+// the request part of the access logs, which can be anything if the request
+// is malformed
+const accessLogRequest = `"(?P<request>(?P<method>[A-Z]+) (?P<path>\S+) (?P<protocol>[^"]+)|[^"]*)"`
+
+const commonLogFormat = `^(?P<remote_addr>\S+) (?P<ident>\S+) (?P<remote_user>\S+) ` +
+	`\[(?P<time>[^\]]+)\] ` + accessLogRequest + ` (?P<status>\d{3}) (?P<bytes>\d+|-)`
+
+// regexpPresets are the formats of some common logs
+var regexpPresets = map[string]struct {
+	expr       string
+	timestamps []string
+}{
+	// the Common Log Format, used by Apache and nginx
+	"common": {
+		expr:       commonLogFormat + `$`,
+		timestamps: []string{"time"},
+	},
+	// the Combined Log Format, the default of nginx
+	"combined": {
+		expr:       commonLogFormat + ` "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)"$`,
+		timestamps: []string{"time"},
+	},
+	// syslog messages, as defined by RFC 5424
+	"syslog": {
+		expr: `^<(?P<priority>\d{1,3})>(?P<version>\d{1,2}) (?P<timestamp>\S+) ` +
+			`(?P<hostname>\S+) (?P<app_name>\S+) (?P<procid>\S+) (?P<msgid>\S+) ` +
+			`(?P<structured_data>-|(?:\[(?:[^\]\\]|\\.)*\])+)(?: (?P<message>.*))?$`,
+		timestamps: []string{"timestamp"},
+	},
+}
+
+// RegexpFormat is a regular expression whose named capture groups are the
+// fields of the lines it matches
+type RegexpFormat struct {
+	re *regexp.Regexp
+	// the index of each named group
+	groups map[string]int
+	// the groups whose values are timestamps
+	timestamps map[string]bool
+	// the value of the groups which is read as null, if any
+	null string
+}
+
+// NewRegexpFormat compiles a regular expression with named capture groups,
+// e.g. `^(?P<level>\w+): (?P<message>.*)$`. The "_line" and "_lineno" names
+// are reserved.
+func NewRegexpFormat(expr string) (*RegexpFormat, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &RegexpFormat{
+		re:         re,
+		groups:     make(map[string]int),
+		timestamps: make(map[string]bool),
+	}
+
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if name == lineField || name == lineNumberField {
+			return nil, fmt.Errorf("The %s group name is reserved", name)
+		}
+		f.groups[name] = i
+	}
+
+	if len(f.groups) == 0 {
+		return nil, fmt.Errorf("The regular expression has no named group: %s", expr)
+	}
+
+	return f, nil
+}
+
+// RegexpFormatPreset returns the format of some common logs:
+//   - "common": the Common Log Format of Apache and nginx
+//   - "combined": the Combined Log Format, also named "nginx" and "apache"
+//   - "syslog": syslog messages, as defined by RFC 5424
+//
+// Their "-" values are null and their times are timestamps.
+func RegexpFormatPreset(name string) (*RegexpFormat, error) {
+	switch name {
+	case "nginx", "apache":
+		name = "combined"
+	}
+
+	preset, ok := regexpPresets[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown regexp format preset: %s", name)
+	}
+
+	f, err := NewRegexpFormat(preset.expr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range preset.timestamps {
+		f.timestamps[group] = true
+	}
+	f.null = "-"
+
+	return f, nil
+}
+
+// Fields returns the names of the fields of the format, sorted
+func (f *RegexpFormat) Fields() []string {
+	names := make([]string, 0, len(f.groups))
+	for name := range f.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Match returns the record of the line with the given number, or nil if the
+// line doesn't match
+func (f *RegexpFormat) Match(line string, lineno int) *RegexpRecord {
+	indexes := f.re.FindStringSubmatchIndex(line)
+	if indexes == nil {
+		return nil
+	}
+
+	return &RegexpRecord{format: f, line: line, lineno: lineno, indexes: indexes}
+}
+
+// RegexpRecord is a record for a line of text matched by a RegexpFormat.
+//
+// Its fields are the named capture groups, whose values are parsed with
+// charlatan.ConstFromString. Groups which didn't match anything are null. The
+// "_line" pseudo-field is the whole line and "_lineno" its number, starting
+// at 1. The special field "*" also returns the line.
+//
+// If the SoftMatching attribute is set to true, non-existing fields are
+// returned as null constants instead of failing with an error.
+//
+// If the UseDecimals attribute is set to true, non-integer numbers are
+// returned as decimals instead of floats, so that they're never rounded.
+type RegexpRecord struct {
+	format *RegexpFormat
+	line   string
+	lineno int
+	// the submatches indexes
+	indexes []int
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+var _ ch.Record = &RegexpRecord{}
+
+// Find implements the charlatan.Record interface
+func (r *RegexpRecord) Find(field *ch.Field) (*ch.Const, error) {
+	var name string
+
+	if name = field.Name(); len(name) == 0 {
+		return nil, errEmptyField
+	}
+
+	switch name {
+	case "*", lineField:
+		return ch.StringConst(r.line), nil
+	case lineNumberField:
+		return ch.IntConst(int64(r.lineno)), nil
+	}
+
+	group, ok := r.format.groups[name]
+	if !ok {
+		if r.SoftMatching {
+			return ch.NullConst(), nil
+		}
+		return nil, fmt.Errorf("Unknown '%s' field", name)
+	}
+
+	start, end := r.indexes[2*group], r.indexes[2*group+1]
+	if start < 0 {
+		return ch.NullConst(), nil
+	}
+
+	value := r.line[start:end]
+
+	if r.format.null != "" && value == r.format.null {
+		return ch.NullConst(), nil
+	}
+
+	if r.format.timestamps[name] {
+		if t, err := ch.ParseTimestamp(value); err == nil {
+			return ch.TimestampConst(t), nil
+		}
+	}
+
+	if r.UseDecimals {
+		return ch.ConstFromStringDecimal(value), nil
+	}
+
+	return ch.ConstFromString(value), nil
+}
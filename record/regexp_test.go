@@ -0,0 +1,140 @@
+package record
+
+import (
+	"testing"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findAll returns the values of the given fields of a record
+func findAll(t *testing.T, r ch.Record, expected map[string]interface{}) {
+	for name, value := range expected {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, value, c.Value(), name)
+		}
+	}
+}
+
+func TestNewRegexpFormat(t *testing.T) {
+	f, err := NewRegexpFormat(`^(?P<level>\w+): (?P<message>.*)$`)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"level", "message"}, f.Fields())
+
+	for _, expr := range []string{
+		`(`,
+		`^(\w+): (.*)$`,
+		`^(?P<_line>.*)$`,
+		`^(?P<_lineno>.*)$`,
+	} {
+		_, err := NewRegexpFormat(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestRegexpRecordFind(t *testing.T) {
+	f, err := NewRegexpFormat(`^(?P<level>\w+): (?P<message>.*?)(?: in (?P<latency>[\d.]+)ms)?$`)
+	require.Nil(t, err)
+
+	assert.Nil(t, f.Match("nope", 1))
+
+	r := f.Match("error: connection refused", 42)
+	require.NotNil(t, r)
+
+	findAll(t, r, map[string]interface{}{
+		"level":   "error",
+		"message": "connection refused",
+		"latency": nil,
+		"_line":   "error: connection refused",
+		"*":       "error: connection refused",
+		"_lineno": int64(42),
+	})
+
+	for _, name := range []string{"", "nope"} {
+		_, err := r.Find(ch.NewField(name))
+		assert.NotNil(t, err, name)
+	}
+
+	r = f.Match("info: done in 0.10ms", 1)
+	require.NotNil(t, r)
+	findAll(t, r, map[string]interface{}{"latency": 0.1})
+
+	r.UseDecimals = true
+	r.SoftMatching = true
+
+	c, err := r.Find(ch.NewField("latency"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.10", c.AsString())
+
+	c, err = r.Find(ch.NewField("nope"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
+
+func TestRegexpFormatPresets(t *testing.T) {
+	_, err := RegexpFormatPreset("nope")
+	assert.NotNil(t, err)
+
+	f, err := RegexpFormatPreset("common")
+	require.Nil(t, err)
+
+	r := f.Match(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`, 1)
+	require.NotNil(t, r)
+	findAll(t, r, map[string]interface{}{
+		"remote_addr": "127.0.0.1",
+		"ident":       nil,
+		"remote_user": "frank",
+		"time":        time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		"method":      "GET",
+		"path":        "/apache_pb.gif",
+		"protocol":    "HTTP/1.0",
+		"status":      int64(200),
+		"bytes":       int64(2326),
+	})
+
+	for _, name := range []string{"nginx", "apache", "combined"} {
+		f, err := RegexpFormatPreset(name)
+		require.Nil(t, err)
+
+		r := f.Match(`::1 - - [28/Dec/2016:10:00:00 +0000] "\x16\x03" 400 - "-" "curl/7.50"`, 1)
+		require.NotNil(t, r, name)
+		findAll(t, r, map[string]interface{}{
+			"request":    `\x16\x03`,
+			"method":     nil,
+			"status":     int64(400),
+			"bytes":      nil,
+			"referer":    nil,
+			"user_agent": "curl/7.50",
+		})
+	}
+
+	f, err = RegexpFormatPreset("syslog")
+	require.Nil(t, err)
+
+	r = f.Match(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 `+
+		`[exampleSDID@32473 iut="3" eventSource="Application"] An application event`, 1)
+	require.NotNil(t, r)
+	findAll(t, r, map[string]interface{}{
+		"priority":        int64(165),
+		"version":         int64(1),
+		"timestamp":       time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+		"hostname":        "mymachine.example.com",
+		"app_name":        "evntslog",
+		"procid":          nil,
+		"msgid":           "ID47",
+		"structured_data": `[exampleSDID@32473 iut="3" eventSource="Application"]`,
+		"message":         "An application event",
+	})
+
+	r = f.Match(`<34>1 - host su - - -`, 1)
+	require.NotNil(t, r)
+	findAll(t, r, map[string]interface{}{
+		"timestamp":       nil,
+		"structured_data": nil,
+		"message":         nil,
+	})
+}
@@ -0,0 +1,58 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// RegexpSource reads RegexpRecords from a stream, one line at a time. Lines
+// which don't match the format are errors, unless the SkipUnmatched attribute
+// is set to true.
+//
+// The SoftMatching and UseDecimals attributes are set on the records it
+// returns.
+type RegexpSource struct {
+	scanner *bufio.Scanner
+	format  *RegexpFormat
+	line    int
+
+	SkipUnmatched bool
+	SoftMatching  bool
+	UseDecimals   bool
+}
+
+// NewRegexpSource returns a source which reads the lines of the reader with
+// the given format
+func NewRegexpSource(r io.Reader, format *RegexpFormat) *RegexpSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineLength)
+
+	return &RegexpSource{scanner: scanner, format: format}
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *RegexpSource) Next() (*RegexpRecord, error) {
+	for s.scanner.Scan() {
+		s.line++
+
+		r := s.format.Match(s.scanner.Text(), s.line)
+		if r == nil {
+			if s.SkipUnmatched {
+				continue
+			}
+			return nil, fmt.Errorf("Line %d doesn't match the format", s.line)
+		}
+
+		r.SoftMatching = s.SoftMatching
+		r.UseDecimals = s.UseDecimals
+
+		return r, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
@@ -0,0 +1,70 @@
+package record
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const regexpLines = `error: connection refused
+info: started
+not a log line
+error: timeout
+`
+
+func TestRegexpSource(t *testing.T) {
+	f, err := NewRegexpFormat(`^(?P<level>\w+): (?P<message>.*)$`)
+	require.Nil(t, err)
+
+	q, err := ch.QueryFromString(`SELECT _lineno, message FROM app.log WHERE level = "error"`)
+	require.Nil(t, err)
+
+	s := NewRegexpSource(strings.NewReader(regexpLines), f)
+	s.SkipUnmatched = true
+
+	var values [][]*ch.Const
+
+	for {
+		r, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+
+		if m, err := q.Evaluate(r); assert.Nil(t, err) && m {
+			v, err := q.FieldsValues(r)
+			require.Nil(t, err)
+			values = append(values, v)
+		}
+	}
+
+	assert.Equal(t, [][]*ch.Const{
+		{ch.IntConst(1), ch.StringConst("connection refused")},
+		{ch.IntConst(4), ch.StringConst("timeout")},
+	}, values)
+}
+
+func TestRegexpSourceUnmatched(t *testing.T) {
+	f, err := NewRegexpFormat(`^(?P<level>\w+): (?P<message>.*)$`)
+	require.Nil(t, err)
+
+	s := NewRegexpSource(strings.NewReader(regexpLines), f)
+	s.SoftMatching = true
+	s.UseDecimals = true
+
+	for i := 0; i < 2; i++ {
+		r, err := s.Next()
+		require.Nil(t, err)
+		assert.True(t, r.SoftMatching)
+		assert.True(t, r.UseDecimals)
+	}
+
+	_, err = s.Next()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Line 3")
+}
@@ -0,0 +1,28 @@
+package record
+
+import (
+	"io"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readColumn reads all the records returned by next, e.g. a source's Next
+// method, and returns the values of the given field
+func readColumn[R ch.Record](t *testing.T, next func() (R, error), name string) []interface{} {
+	var values []interface{}
+
+	for {
+		r, err := next()
+		if err == io.EOF {
+			return values
+		}
+		require.Nil(t, err)
+
+		c, err := r.Find(ch.NewField(name))
+		require.Nil(t, err)
+		values = append(values, c.Value())
+	}
+}
@@ -0,0 +1,118 @@
+// Package toml provides a source of records for TOML documents
+package toml
+
+import (
+	"fmt"
+	"io"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/BatchLabs/charlatan/record"
+	"github.com/BurntSushi/toml"
+)
+
+// Source reads record.DocumentRecords from a TOML document: the tables of an
+// array of tables, e.g. "services" for the [[services]] tables, or the whole
+// document if the path is empty. Unlike the other sources it decodes the whole
+// document, since TOML tables can be defined in any order.
+//
+// Strings are parsed like the JSON ones, e.g. port = "8080" is an int.
+//
+// The SoftMatching attribute is set on the records it returns. If the
+// UseDecimals attribute is set to true, non-integer numbers are converted to
+// decimals instead of floats.
+type Source struct {
+	r        io.Reader
+	path     ch.FieldPath
+	pathName string
+	// the tables which haven't been returned yet, nil until the document is
+	// decoded
+	tables []*ch.Const
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewSource returns a source which reads the tables of the array of
+// tables at the given path, made of keys separated by dots, e.g. "services"
+// or "servers.alpha.ports"
+func NewSource(r io.Reader, path string) (*Source, error) {
+	s := &Source{r: r, pathName: path}
+
+	if path == "" {
+		return s, nil
+	}
+
+	p, err := ch.ParseFieldPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, seg := range p {
+		if _, ok := seg.Key(); !ok {
+			return nil, fmt.Errorf("Invalid tables path %s: only keys are supported", path)
+		}
+	}
+
+	s.path = p
+
+	return s, nil
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *Source) Next() (*record.DocumentRecord, error) {
+	if s.tables == nil {
+		if err := s.decode(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.tables) == 0 {
+		return nil, io.EOF
+	}
+
+	c := s.tables[0]
+	s.tables = s.tables[1:]
+
+	r, err := record.NewDocumentRecord(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+	r.ParseStrings = true
+	r.UseDecimals = s.UseDecimals
+
+	return r, nil
+}
+
+// decode decodes the document and finds the tables
+func (s *Source) decode() error {
+	var doc map[string]interface{}
+
+	if _, err := toml.NewDecoder(s.r).Decode(&doc); err != nil {
+		return err
+	}
+
+	c, err := record.DocumentValueToConst(doc, s.UseDecimals)
+	if err != nil {
+		return err
+	}
+
+	if len(s.path) == 0 {
+		s.tables = []*ch.Const{c}
+		return nil
+	}
+
+	tables, ok := s.path.Lookup(c)
+	if !ok {
+		return fmt.Errorf("Can't find the '%s' tables", s.pathName)
+	}
+
+	if !tables.IsArray() {
+		return fmt.Errorf("'%s' isn't an array of tables", s.pathName)
+	}
+
+	s.tables = append([]*ch.Const{}, tables.Elements()...)
+
+	return nil
+}
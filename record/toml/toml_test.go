@@ -0,0 +1,184 @@
+package toml
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tomlServices = `
+title = "services"
+
+[[services]]
+name = "api"
+replicas = 4
+price = 0.10
+started = 2016-12-28T10:00:00Z
+
+[services.labels]
+team = "core"
+
+[[services]]
+name = "worker"
+replicas = 1
+
+[cluster]
+[[cluster.nodes]]
+name = "a"
+`
+
+func TestTOMLSource(t *testing.T) {
+	for path, names := range map[string][]string{
+		"services":      {"api", "worker"},
+		"cluster.nodes": {"a"},
+	} {
+		s, err := NewSource(strings.NewReader(tomlServices), path)
+		require.Nil(t, err)
+
+		for _, name := range names {
+			r, err := s.Next()
+			require.Nil(t, err, path)
+
+			c, err := r.Find(ch.NewField("name"))
+			require.Nil(t, err, path)
+			assert.Equal(t, name, c.AsString(), path)
+		}
+
+		_, err = s.Next()
+		assert.Equal(t, io.EOF, err, path)
+	}
+}
+
+func TestTOMLSourceTypes(t *testing.T) {
+	s, err := NewSource(strings.NewReader(`
+hex = 0xff
+oct = 0o17
+bin = 0b101
+max = 9_223_372_036_854_775_807
+inf = -inf
+offset = 1979-05-27T00:32:00-07:00
+local = 1979-05-27T07:32:00
+day = 1979-05-27
+mixed = [1, "2", 2.5]
+`), "")
+	require.Nil(t, err)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	find := func(name string) *ch.Const {
+		c, err := r.Find(ch.NewField(name))
+		require.Nil(t, err, name)
+		return c
+	}
+
+	assert.Equal(t, int64(255), find("hex").Value())
+	assert.Equal(t, int64(15), find("oct").Value())
+	assert.Equal(t, int64(5), find("bin").Value())
+	assert.Equal(t, int64(math.MaxInt64), find("max").Value())
+	assert.True(t, math.IsInf(find("inf").Value().(float64), -1))
+
+	// offset date-times keep their offset, local ones are in UTC
+	assert.Equal(t, "1979-05-27T00:32:00-07:00", find("offset").AsString())
+	local := find("local").Value().(time.Time)
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC).Unix(), local.Unix())
+	day := find("day").Value().(time.Time)
+	assert.Equal(t, time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC).Unix(), day.Unix())
+
+	// arrays can mix types, only the selected strings are parsed
+	assert.Equal(t, `[1,"2",2.5]`, find("mixed").AsString())
+	assert.Equal(t, int64(2), find("mixed[1]").Value())
+}
+
+func TestTOMLSourceValues(t *testing.T) {
+	s, err := NewSource(strings.NewReader(tomlServices), "services")
+	require.Nil(t, err)
+	s.UseDecimals = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for name, expected := range map[string]string{
+		"replicas":    "4",
+		"price":       "0.1",
+		"labels.team": "core",
+		"started":     "2016-12-28T10:00:00Z",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.AsString(), name)
+		}
+	}
+}
+
+func TestTOMLSourceQuotedValues(t *testing.T) {
+	s, err := NewSource(strings.NewReader(`
+port = "8080"
+tls = "true"
+name = "api"
+`), "")
+	require.Nil(t, err)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	// TOML strings are parsed, as in JSON records
+	for name, expected := range map[string]interface{}{
+		"port": int64(8080),
+		"tls":  true,
+		"name": "api",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	// compared as an int, not as a string
+	q, err := ch.QueryFromString(`SELECT name FROM a WHERE port > 900`)
+	require.Nil(t, err)
+
+	match, err := q.Evaluate(r)
+	require.Nil(t, err)
+	assert.True(t, match)
+}
+
+func TestTOMLSourceDocument(t *testing.T) {
+	s, err := NewSource(strings.NewReader(tomlServices), "")
+	require.Nil(t, err)
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("services[1].name"))
+	require.Nil(t, err)
+	assert.Equal(t, "worker", c.AsString())
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestTOMLSourceErrors(t *testing.T) {
+	_, err := NewSource(strings.NewReader(tomlServices), "services[0]")
+	assert.NotNil(t, err)
+
+	for path, doc := range map[string]string{
+		"services": "a = [",
+		"nope":     tomlServices,
+		"title":    tomlServices,
+	} {
+		s, err := NewSource(strings.NewReader(doc), path)
+		require.Nil(t, err)
+
+		_, err = s.Next()
+		assert.NotNil(t, err, path)
+		assert.NotEqual(t, io.EOF, err, path)
+	}
+}
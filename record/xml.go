@@ -0,0 +1,126 @@
+package record
+
+import (
+	"encoding/xml"
+	"strings"
+
+	ch "github.com/BatchLabs/charlatan"
+)
+
+// XMLRecord is a record for XML elements.
+//
+// The element is read as an object whose members are its attributes, prefixed
+// with "@", and its child elements. Child elements repeated under the same
+// name are arrays (see Find for the single ones), elements with only text are
+// strings, and the text of elements which also have attributes or children is
+// the "#text" member. The namespaces are ignored. For instance, the price of
+// <item id="1"><price currency="EUR">12</price></item> is "price.#text" and
+// its currency "price.@currency".
+//
+// It supports the special field "*", which returns the element as JSON.
+//
+// If the SoftMatching attribute is set to true, non-existing fields are
+// returned as null constants instead of failing with an error.
+//
+// If the UseDecimals attribute is set to true, non-integer numbers are
+// returned as decimals instead of floats, so that they're never rounded.
+type XMLRecord struct {
+	value *ch.Const
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+var _ ch.Record = &XMLRecord{}
+
+// the member which holds the text of an element which has attributes or
+// children
+const xmlTextMember = "#text"
+
+// NewXMLRecordFromDecoder creates a new XMLRecord from the element which
+// starts with the given token. The decoder is left after the end of the
+// element. See XMLSource to read the repeated elements of a document.
+func NewXMLRecordFromDecoder(dec *xml.Decoder, start xml.StartElement) (*XMLRecord, error) {
+	c, err := decodeXMLElement(dec, start)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.IsObject() {
+		c = ch.ObjectConst(map[string]*ch.Const{xmlTextMember: c})
+	}
+
+	return &XMLRecord{value: c}, nil
+}
+
+// Find implements the charlatan.Record interface. The field's path can go
+// into child elements (a.b), attributes (a.@id) and repeated elements (a[0]
+// or a[*]). Since a single child element isn't an array, index and wildcard
+// segments treat it as a one-element array, see charlatan.FieldPath.
+// LookupRepeated: a[0].b and a[*].b work whether there are one or many a
+// elements.
+func (r *XMLRecord) Find(field *ch.Field) (*ch.Const, error) {
+	// XML only has text, the type of the values is guessed
+	return lookupField(r.value, field, lookupOptions{
+		softMatching: r.SoftMatching,
+		repeated:     true,
+		parseStrings: true,
+		useDecimals:  r.UseDecimals,
+	})
+}
+
+// decodeXMLElement reads the element which starts with the given token and
+// converts it to a constant
+func decodeXMLElement(dec *xml.Decoder, start xml.StartElement) (*ch.Const, error) {
+	members := make(map[string]*ch.Const)
+	children := make(map[string][]*ch.Const)
+	var text strings.Builder
+
+	for _, attr := range start.Attr {
+		// namespace declarations aren't attributes
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		members["@"+attr.Name.Local] = ch.StringConst(attr.Value)
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			children[t.Name.Local] = append(children[t.Name.Local], child)
+
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+
+			if len(members) == 0 && len(children) == 0 {
+				return ch.StringConst(content), nil
+			}
+
+			for name, values := range children {
+				if len(values) == 1 {
+					members[name] = values[0]
+				} else {
+					members[name] = ch.ArrayConst(values)
+				}
+			}
+
+			if content != "" {
+				members[xmlTextMember] = ch.StringConst(content)
+			}
+
+			return ch.ObjectConst(members), nil
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package record
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// xmlRecord returns the record of the root element of the document
+func xmlRecord(t *testing.T, doc string) *XMLRecord {
+	dec := xml.NewDecoder(strings.NewReader(doc))
+
+	for {
+		tok, err := dec.Token()
+		require.Nil(t, err)
+
+		if start, ok := tok.(xml.StartElement); ok {
+			r, err := NewXMLRecordFromDecoder(dec, start)
+			require.Nil(t, err)
+			return r
+		}
+	}
+}
+
+const xmlItem = `<?xml version="1.0"?>
+<item id="42" xmlns:g="http://base.google.com/ns/1.0">
+	<!-- a comment -->
+	<title>A &amp; B</title>
+	<price currency="EUR">12.50</price>
+	<g:brand>Acme</g:brand>
+	<tags><tag>a</tag><tag>b</tag><tag>3</tag></tags>
+	<stock/>
+	<description>Some <b>bold</b> text</description>
+</item>`
+
+func TestXMLRecordFind(t *testing.T) {
+	r := xmlRecord(t, xmlItem)
+
+	for name, expected := range map[string]interface{}{
+		"@id":               int64(42),
+		"title":             "A & B",
+		"price.#text":       12.5,
+		"price.@currency":   "EUR",
+		"brand":             "Acme",
+		"tags.tag[0]":       "a",
+		"tags.tag[-1]":      int64(3),
+		"stock":             "",
+		"description.#text": "Some  text",
+		"description.b":     "bold",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	c, err := r.Find(ch.NewField("tags.tag"))
+	require.Nil(t, err)
+	assert.True(t, c.IsArray())
+	assert.Equal(t, `["a","b","3"]`, c.AsString())
+
+	c, err = r.Find(ch.NewField("*"))
+	require.Nil(t, err)
+	assert.True(t, strings.HasPrefix(c.AsString(), `{"@id":"42","brand":"Acme",`))
+
+	for _, name := range []string{"", "nope", "title.nope", "tags.tag[3]", "@nope"} {
+		_, err := r.Find(ch.NewField(name))
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestXMLRecordRepeatedElements(t *testing.T) {
+	single := xmlRecord(t, `<order><item><price>12</price></item></order>`)
+	repeated := xmlRecord(t, `<order><item><price>12</price></item><item><price>30</price></item></order>`)
+
+	for name, expected := range map[string][2]string{
+		"item[0].price":  {"12", "12"},
+		"item[-1].price": {"12", "30"},
+		"item[*].price":  {"[12]", "[12,30]"},
+	} {
+		for i, r := range []*XMLRecord{single, repeated} {
+			c, err := r.Find(ch.NewField(name))
+			if assert.Nil(t, err, name) {
+				assert.Equal(t, expected[i], c.AsString(), name)
+			}
+		}
+	}
+
+	q, err := ch.QueryFromString("SELECT item[0].price FROM orders.xml WHERE item[0].price = 12 AND CONTAINS(item[*].price, 12)")
+	require.Nil(t, err)
+
+	for _, r := range []*XMLRecord{single, repeated} {
+		m, err := q.Evaluate(r)
+		require.Nil(t, err)
+		assert.True(t, m)
+	}
+}
+
+func TestXMLRecordSoftMatching(t *testing.T) {
+	r := xmlRecord(t, xmlItem)
+	r.SoftMatching = true
+
+	c, err := r.Find(ch.NewField("nope.nope"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
+
+func TestXMLRecordUseDecimals(t *testing.T) {
+	r := xmlRecord(t, xmlItem)
+	r.UseDecimals = true
+
+	c, err := r.Find(ch.NewField("price.#text"))
+	require.Nil(t, err)
+	assert.Equal(t, "12.50", c.AsString())
+}
+
+func TestXMLRecordText(t *testing.T) {
+	r := xmlRecord(t, `<item> 42 </item>`)
+
+	c, err := r.Find(ch.NewField("#text"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(42), c.Value())
+}
+
+func TestXMLRecordInvalid(t *testing.T) {
+	dec := xml.NewDecoder(strings.NewReader(`<item><a></b></item>`))
+
+	tok, err := dec.Token()
+	require.Nil(t, err)
+
+	_, err = NewXMLRecordFromDecoder(dec, tok.(xml.StartElement))
+	assert.NotNil(t, err)
+}
+
+func TestXMLRecordQuery(t *testing.T) {
+	r := xmlRecord(t, xmlItem)
+
+	q, err := ch.QueryFromString(
+		"SELECT title FROM feed.xml WHERE @id = 42 AND price.#text < 20 AND price.@currency = 'EUR' AND CONTAINS(tags.tag, 'b')")
+	require.Nil(t, err)
+
+	m, err := q.Evaluate(r)
+	require.Nil(t, err)
+	assert.True(t, m)
+}
@@ -0,0 +1,102 @@
+package record
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XMLSource reads XMLRecords from a stream, one at a time, without loading
+// the whole document. The records are the elements at a path, e.g.
+// "/feed/item" for the item elements of the feed root element. A path which
+// doesn't start with a slash matches at any depth, e.g. "item" for all the
+// item elements, except those inside another record.
+//
+// The SoftMatching and UseDecimals attributes are set on the records it
+// returns.
+type XMLSource struct {
+	dec  *xml.Decoder
+	path []string
+	// true if the path starts from the root element
+	absolute bool
+	// the names of the elements which contain the current position
+	stack []string
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewXMLSource returns a source which reads the elements at the given path
+// from the reader
+func NewXMLSource(r io.Reader, path string) (*XMLSource, error) {
+	absolute := strings.HasPrefix(path, "/")
+
+	names := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("Invalid XML element path: %q", path)
+		}
+	}
+
+	return &XMLSource{
+		dec:      xml.NewDecoder(r),
+		path:     names,
+		absolute: absolute,
+	}, nil
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *XMLSource) Next() (*XMLRecord, error) {
+	for {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			s.stack = append(s.stack, t.Name.Local)
+
+			if !s.matches() {
+				continue
+			}
+
+			// the decoder reads the whole element, including its end
+			s.stack = s.stack[:len(s.stack)-1]
+
+			r, err := NewXMLRecordFromDecoder(s.dec, t)
+			if err != nil {
+				return nil, err
+			}
+
+			r.SoftMatching = s.SoftMatching
+			r.UseDecimals = s.UseDecimals
+
+			return r, nil
+
+		case xml.EndElement:
+			s.stack = s.stack[:len(s.stack)-1]
+		}
+	}
+}
+
+// matches tests if the current element is at the path
+func (s *XMLSource) matches() bool {
+	if s.absolute && len(s.stack) != len(s.path) {
+		return false
+	}
+
+	offset := len(s.stack) - len(s.path)
+	if offset < 0 {
+		return false
+	}
+
+	for i, name := range s.path {
+		if s.stack[offset+i] != name {
+			return false
+		}
+	}
+
+	return true
+}
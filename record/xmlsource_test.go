@@ -0,0 +1,89 @@
+package record
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const xmlFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed>
+	<title>Feed</title>
+	<item><name>a</name></item>
+	<item><name>b</name><related><item><name>x</name></item></related></item>
+	<other><item><name>c</name></item></other>
+	<item><name>d</name></item>
+</feed>`
+
+func TestXMLSourceAbsolutePath(t *testing.T) {
+	s, err := NewXMLSource(strings.NewReader(xmlFeed), "/feed/item")
+	require.Nil(t, err)
+
+	assert.Equal(t, []interface{}{"a", "b", "d"}, readColumn(t, s.Next, "name"))
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestXMLSourceRelativePath(t *testing.T) {
+	s, err := NewXMLSource(strings.NewReader(xmlFeed), "item")
+	require.Nil(t, err)
+
+	// items inside other items are part of them
+	assert.Equal(t, []interface{}{"a", "b", "c", "d"}, readColumn(t, s.Next, "name"))
+
+	s, err = NewXMLSource(strings.NewReader(xmlFeed), "other/item")
+	require.Nil(t, err)
+
+	assert.Equal(t, []interface{}{"c"}, readColumn(t, s.Next, "name"))
+}
+
+func TestXMLSourceNoMatch(t *testing.T) {
+	s, err := NewXMLSource(strings.NewReader(xmlFeed), "/item")
+	require.Nil(t, err)
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestXMLSourceInvalidPath(t *testing.T) {
+	for _, path := range []string{"", "/", "a//b", "a/"} {
+		_, err := NewXMLSource(strings.NewReader(xmlFeed), path)
+		assert.NotNil(t, err, path)
+	}
+}
+
+func TestXMLSourceInvalidDocument(t *testing.T) {
+	s, err := NewXMLSource(strings.NewReader(`<feed><item><name>a</name></item><item>`), "item")
+	require.Nil(t, err)
+
+	_, err = s.Next()
+	require.Nil(t, err)
+
+	_, err = s.Next()
+	assert.NotNil(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
+
+func TestXMLSourceRecordsOptions(t *testing.T) {
+	s, err := NewXMLSource(strings.NewReader(`<a><b><price>0.10</price></b></a>`), "b")
+	require.Nil(t, err)
+	s.SoftMatching = true
+	s.UseDecimals = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("price"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.10", c.AsString())
+
+	c, err = r.Find(ch.NewField("nope"))
+	require.Nil(t, err)
+	assert.True(t, c.IsNull())
+}
@@ -0,0 +1,203 @@
+// Package yaml provides a source of records for YAML streams
+package yaml
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	ch "github.com/BatchLabs/charlatan"
+	"github.com/BatchLabs/charlatan/record"
+	"gopkg.in/yaml.v3"
+)
+
+// Source reads record.DocumentRecords from a YAML stream, one document at a
+// time. Each document is a record, except sequences, whose elements are
+// records. Empty documents are skipped.
+//
+// Scalars are converted according to their resolved tag, and the strings are
+// then parsed like the JSON ones: the quoted "8080" is an int, as 8080 is.
+//
+// The SoftMatching attribute is set on the records it returns. If the
+// UseDecimals attribute is set to true, non-integer numbers are converted to
+// decimals instead of floats.
+type Source struct {
+	dec *yaml.Decoder
+	// the elements of the current sequence which haven't been returned yet
+	pending []*ch.Const
+
+	SoftMatching bool
+	UseDecimals  bool
+}
+
+// NewSource returns a source which reads the YAML documents from the reader
+func NewSource(r io.Reader) *Source {
+	return &Source{dec: yaml.NewDecoder(r)}
+}
+
+// Next returns the next record, or io.EOF if there's none left
+func (s *Source) Next() (*record.DocumentRecord, error) {
+	for len(s.pending) == 0 {
+		var node yaml.Node
+
+		if err := s.dec.Decode(&node); err != nil {
+			return nil, err
+		}
+
+		c, err := nodeToConst(&node, s.UseDecimals)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case c.IsNull():
+			// an empty document
+		case c.IsArray():
+			s.pending = c.Elements()
+		default:
+			s.pending = []*ch.Const{c}
+		}
+	}
+
+	c := s.pending[0]
+	s.pending = s.pending[1:]
+
+	r, err := record.NewDocumentRecord(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SoftMatching = s.SoftMatching
+	r.ParseStrings = true
+	r.UseDecimals = s.UseDecimals
+
+	return r, nil
+}
+
+// nodeToConst converts a YAML node to a constant. Scalars are converted
+// according to their resolved tag, e.g. 0x1f is an int and .inf a float.
+func nodeToConst(node *yaml.Node, useDecimals bool) (*ch.Const, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return ch.NullConst(), nil
+		}
+		return nodeToConst(node.Content[0], useDecimals)
+
+	case yaml.AliasNode:
+		return nodeToConst(node.Alias, useDecimals)
+
+	case yaml.SequenceNode:
+		elements := make([]*ch.Const, len(node.Content))
+		for i, child := range node.Content {
+			c, err := nodeToConst(child, useDecimals)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = c
+		}
+		return ch.ArrayConst(elements), nil
+
+	case yaml.MappingNode:
+		return mappingToConst(node, useDecimals)
+
+	case yaml.ScalarNode:
+		return scalarToConst(node, useDecimals)
+	}
+
+	return nil, fmt.Errorf("Unexpected YAML node at line %d", node.Line)
+}
+
+// mappingToConst converts a YAML mapping to an object. The members of the
+// mappings merged with "<<" are overridden by the mapping's own ones.
+func mappingToConst(node *yaml.Node, useDecimals bool) (*ch.Const, error) {
+	members := make(map[string]*ch.Const, len(node.Content)/2)
+	merged := make(map[string]*ch.Const)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		c, err := nodeToConst(value, useDecimals)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.ShortTag() != "!!merge" {
+			members[key.Value] = c
+			continue
+		}
+
+		sources := []*ch.Const{c}
+		if c.IsArray() {
+			sources = c.Elements()
+		}
+
+		for _, source := range sources {
+			if !source.IsObject() {
+				return nil, fmt.Errorf("Can't merge a non-mapping value at line %d", key.Line)
+			}
+			for k, v := range source.Members() {
+				// the first merged mapping wins
+				if _, ok := merged[k]; !ok {
+					merged[k] = v
+				}
+			}
+		}
+	}
+
+	for k, v := range merged {
+		if _, ok := members[k]; !ok {
+			members[k] = v
+		}
+	}
+
+	return ch.ObjectConst(members), nil
+}
+
+// scalarToConst converts a YAML scalar to a constant
+func scalarToConst(node *yaml.Node, useDecimals bool) (*ch.Const, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return ch.NullConst(), nil
+
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, err
+		}
+		return ch.BoolConst(b), nil
+
+	case "!!int":
+		var i int64
+		if err := node.Decode(&i); err == nil {
+			return ch.IntConst(i), nil
+		}
+		if b, ok := new(big.Int).SetString(node.Value, 0); ok {
+			return ch.BigIntConst(b), nil
+		}
+		return nil, fmt.Errorf("Invalid YAML integer at line %d: %s", node.Line, node.Value)
+
+	case "!!float":
+		if useDecimals {
+			if d, err := ch.DecimalConstFromString(node.Value); err == nil {
+				return d, nil
+			}
+		}
+
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, err
+		}
+		return ch.FloatConst(f), nil
+
+	case "!!timestamp":
+		var t time.Time
+		if err := node.Decode(&t); err != nil {
+			return nil, err
+		}
+		return ch.TimestampConst(t), nil
+	}
+
+	return ch.StringConst(node.Value), nil
+}
@@ -0,0 +1,228 @@
+package yaml
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	ch "github.com/BatchLabs/charlatan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const yamlServices = `
+defaults: &defaults
+  replicas: 1
+  env: prod
+name: api
+<<: *defaults
+replicas: 4
+ports: [80, 0x1bb]
+---
+name: worker
+<<: *defaults
+---
+# an empty document
+---
+- name: cron
+  replicas: 2
+- name: batch
+  replicas: 6
+`
+
+func TestYAMLSource(t *testing.T) {
+	s := NewSource(strings.NewReader(yamlServices))
+
+	// the empty document is skipped and the sequence elements are records,
+	// the merged defaults are overridden by the document's own keys
+	for _, expected := range []struct {
+		name     string
+		replicas int64
+	}{
+		{"api", 4},
+		{"worker", 1},
+		{"cron", 2},
+		{"batch", 6},
+	} {
+		r, err := s.Next()
+		require.Nil(t, err, expected.name)
+
+		c, err := r.Find(ch.NewField("name"))
+		require.Nil(t, err, expected.name)
+		assert.Equal(t, expected.name, c.AsString())
+
+		c, err = r.Find(ch.NewField("replicas"))
+		require.Nil(t, err, expected.name)
+		assert.Equal(t, expected.replicas, c.Value(), expected.name)
+	}
+
+	_, err := s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestYAMLSourceValues(t *testing.T) {
+	s := NewSource(strings.NewReader(yamlServices))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	for name, expected := range map[string]interface{}{
+		"replicas":          int64(4),
+		"env":               "prod",
+		"ports[1]":          int64(443),
+		"defaults.replicas": int64(1),
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	r, err = s.Next()
+	require.Nil(t, err)
+
+	c, err := r.Find(ch.NewField("replicas"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), c.Value())
+}
+
+func TestYAMLScalars(t *testing.T) {
+	s := NewSource(strings.NewReader(`
+nil: ~
+bool: yes
+upper: True
+int: 1_000
+big: 99999999999999999999
+float: 0.10
+inf: .inf
+ts: 2016-12-28T10:00:00Z
+date: 2016-12-28
+str: "42"
+`))
+	s.UseDecimals = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	find := func(name string) *ch.Const {
+		c, err := r.Find(ch.NewField(name))
+		require.Nil(t, err, name)
+		return c
+	}
+
+	assert.True(t, find("nil").IsNull())
+	assert.Equal(t, "yes", find("bool").AsString())
+	assert.Equal(t, true, find("upper").Value())
+	assert.Equal(t, "99999999999999999999", find("big").AsString())
+	assert.Equal(t, "0.10", find("float").AsString())
+	assert.True(t, find("inf").IsNumeric())
+	assert.Equal(t, "2016-12-28T10:00:00Z", find("ts").AsString())
+	assert.Equal(t, "2016-12-28T00:00:00Z", find("date").AsString())
+
+	// strings are parsed, as in JSON records
+	assert.Equal(t, int64(42), find("str").Value())
+}
+
+func TestYAMLSourceTags(t *testing.T) {
+	s := NewSource(strings.NewReader(`
+float: !!float 1
+int: !!int "0x10"
+str: !!str 0.10
+custom: !celsius 21
+octal: 0o17
+sexagesimal: 1:20
+`))
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	// the explicit tags win over the guessed ones, the strings are then
+	// parsed like the other ones
+	for name, expected := range map[string]interface{}{
+		"float":       1.0,
+		"int":         int64(16),
+		"str":         0.1,
+		"custom":      int64(21),
+		"octal":       int64(15),
+		"sexagesimal": "1:20",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+}
+
+func TestYAMLSourceQuotedScalars(t *testing.T) {
+	s := NewSource(strings.NewReader(`{port: "8080", tls: "true", price: "0.10", name: api, ports: ["80", "443"]}`))
+	s.UseDecimals = true
+
+	r, err := s.Next()
+	require.Nil(t, err)
+
+	// quoted scalars are parsed, as in JSON records
+	for name, expected := range map[string]interface{}{
+		"port": int64(8080),
+		"tls":  true,
+		"name": "api",
+	} {
+		c, err := r.Find(ch.NewField(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, expected, c.Value(), name)
+		}
+	}
+
+	c, err := r.Find(ch.NewField("price"))
+	require.Nil(t, err)
+	assert.Equal(t, "0.10", c.AsString())
+
+	// the elements selected by a wildcard too
+	c, err = r.Find(ch.NewField("ports[*]"))
+	require.Nil(t, err)
+	assert.Equal(t, "[80,443]", c.AsString())
+
+	// compared as an int, not as a string
+	q, err := ch.QueryFromString(`SELECT name FROM a WHERE port > 900 AND tls = true`)
+	require.Nil(t, err)
+
+	match, err := q.Evaluate(r)
+	require.Nil(t, err)
+	assert.True(t, match)
+}
+
+func TestYAMLSourceErrors(t *testing.T) {
+	for _, doc := range []string{
+		"a: [1, 2",
+		"- 1\n- 2\n",
+		"a: &a 1\n<<: *a\n",
+	} {
+		_, err := NewSource(strings.NewReader(doc)).Next()
+		assert.NotNil(t, err, doc)
+		assert.NotEqual(t, io.EOF, err, doc)
+	}
+}
+
+func TestYAMLSourceQuery(t *testing.T) {
+	q, err := ch.QueryFromString("SELECT name FROM services.yaml WHERE replicas > 3")
+	require.Nil(t, err)
+
+	s := NewSource(strings.NewReader(yamlServices))
+
+	var names []string
+	for {
+		r, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+
+		if m, err := q.Evaluate(r); assert.Nil(t, err) && m {
+			values, err := q.FieldsValues(r)
+			require.Nil(t, err)
+			names = append(names, values[0].AsString())
+		}
+	}
+
+	assert.Equal(t, []string{"api", "batch"}, names)
+}
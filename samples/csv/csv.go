@@ -1,10 +1,10 @@
 package main
 
 import (
-	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/BatchLabs/charlatan"
 	"github.com/BatchLabs/charlatan/record"
@@ -33,15 +33,26 @@ func main() {
 		return
 	}
 
-	executeRequest(csv.NewReader(reader), query)
+	source := record.NewCSVSource(reader)
+	if strings.HasSuffix(query.From(), ".tsv") {
+		source.Delimiter = '\t'
+	}
+
+	// look the columns up once instead of for each record
+	query, err = source.Bind(query)
+	if err != nil {
+		fmt.Println(">>> ", err)
+		return
+	}
+
+	executeRequest(source, query)
 }
 
-func executeRequest(reader *csv.Reader, query *charlatan.Query) {
+func executeRequest(source *record.CSVSource, query *charlatan.Query) {
 
 	fmt.Println("$ ", query)
 	fmt.Println("$")
 
-	var header []string
 	var limit int64
 
 	hasLimit := query.HasLimit()
@@ -59,7 +70,7 @@ func executeRequest(reader *csv.Reader, query *charlatan.Query) {
 
 	for {
 
-		records, err := reader.Read()
+		r, err := source.Next()
 
 		// end of file
 		if err == io.EOF {
@@ -71,14 +82,6 @@ func executeRequest(reader *csv.Reader, query *charlatan.Query) {
 			return
 		}
 
-		// set the header
-		if header == nil {
-			header = records
-			continue
-		}
-
-		// creates the record
-		r := record.NewCSVRecordWithHeader(records, header)
 		// evaluate the query
 		match, err := query.Evaluate(r)
 		if err != nil {
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -39,7 +38,7 @@ func main() {
 
 	defer reader.Close()
 
-	decoder := json.NewDecoder(reader)
+	source := record.NewJSONSource(reader)
 
 	hasLimit := query.HasLimit()
 
@@ -55,7 +54,7 @@ func main() {
 	offset := query.StartingAt()
 
 	for {
-		r, err := record.NewJSONRecordFromDecoder(decoder)
+		r, err := source.Next()
 
 		// end of file
 		if err == io.EOF {
@@ -0,0 +1,138 @@
+package charlatan
+
+// FieldRange is the range of the values of a field in a set of records, e.g.
+// the statistics of a block of a columnar file. A nil bound is unknown.
+type FieldRange struct {
+	Min, Max *Const
+	// HasNull is true if the field may be null in some of the records
+	HasNull bool
+}
+
+// MayMatch tests if some of the records whose fields are within the ranges
+// returned by the given function may match the query, so that a set of
+// records can be skipped without reading them when it's false. The function
+// returns false for the fields whose range isn't known.
+//
+// Only the comparisons and BETWEEN of a field with a constant, combined with
+// AND and OR, are used, and mismatching types are never ruled out. The result
+// is true if the query has no condition.
+func (q *Query) MayMatch(ranges func(*Field) (FieldRange, bool)) bool {
+	if q.expression == nil {
+		return true
+	}
+	return mayMatch(q.expression, ranges)
+}
+
+// mayMatch tests if an operand may be true for the given ranges
+func mayMatch(op operand, ranges func(*Field) (FieldRange, bool)) bool {
+	switch o := op.(type) {
+	case *groupOperand:
+		return mayMatch(o.operand, ranges)
+
+	case *logicalOperation:
+		if o.right == nil {
+			return mayMatch(o.left, ranges)
+		}
+		if o.operator == operatorAnd {
+			return mayMatch(o.left, ranges) && mayMatch(o.right, ranges)
+		}
+		return mayMatch(o.left, ranges) || mayMatch(o.right, ranges)
+
+	case *comparison:
+		if f, ok := o.left.(*Field); ok {
+			if c, ok := o.right.(*Const); ok {
+				return comparisonMayMatch(f, o.operator, c, ranges)
+			}
+		}
+		if c, ok := o.left.(*Const); ok {
+			if f, ok := o.right.(*Field); ok {
+				return comparisonMayMatch(f, swapComparison(o.operator), c, ranges)
+			}
+		}
+
+	case *rangeTestOperation:
+		f, ok := o.test.(*Field)
+		if !ok {
+			return true
+		}
+		min, ok1 := o.min.(*Const)
+		max, ok2 := o.max.(*Const)
+		if !ok1 || !ok2 {
+			return true
+		}
+		return comparisonMayMatch(f, operatorGte, min, ranges) &&
+			comparisonMayMatch(f, operatorLte, max, ranges)
+	}
+
+	return true
+}
+
+// comparisonMayMatch tests if "field operator c" may be true for the given
+// ranges
+func comparisonMayMatch(f *Field, operator operatorType, c *Const, ranges func(*Field) (FieldRange, bool)) bool {
+	rg, ok := ranges(f)
+	if !ok || rg.Min == nil || rg.Max == nil {
+		return true
+	}
+
+	// null is lower than any value
+	low := rg.Min
+	if rg.HasNull {
+		low = NullConst()
+	}
+
+	if !sameOrdering(rg.Min, c) || !sameOrdering(rg.Max, c) {
+		return true
+	}
+
+	lowComp, err := low.CompareTo(c)
+	if err != nil {
+		return true
+	}
+	maxComp, err := rg.Max.CompareTo(c)
+	if err != nil {
+		return true
+	}
+
+	switch operator {
+	case operatorEq:
+		return lowComp <= 0 && maxComp >= 0
+	case operatorNeq:
+		return lowComp != 0 || maxComp != 0
+	case operatorLt:
+		return lowComp < 0
+	case operatorLte:
+		return lowComp <= 0
+	case operatorGt:
+		return maxComp > 0
+	case operatorGte:
+		return maxComp >= 0
+	}
+
+	return true
+}
+
+// swapComparison returns the operator of a comparison whose operands are
+// swapped, e.g. > for <
+func swapComparison(operator operatorType) operatorType {
+	switch operator {
+	case operatorLt:
+		return operatorGt
+	case operatorLte:
+		return operatorGte
+	case operatorGt:
+		return operatorLt
+	case operatorGte:
+		return operatorLte
+	}
+	return operator
+}
+
+// sameOrdering tests if the constants are ordered the same way, so that a
+// value between two of them is too
+func sameOrdering(c1, c2 *Const) bool {
+	if c1.IsNumeric() && c2.IsNumeric() {
+		return true
+	}
+	return c1.constType == c2.constType && !c1.IsNull()
+}
@@ -0,0 +1,62 @@
+package charlatan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryMayMatch(t *testing.T) {
+	ranges := map[string]FieldRange{
+		"age":  {Min: IntConst(20), Max: IntConst(30)},
+		"name": {Min: StringConst("bob"), Max: StringConst("joe")},
+		"n":    {Min: IntConst(5), Max: IntConst(5), HasNull: true},
+		"only": {HasNull: true},
+	}
+	lookup := func(f *Field) (FieldRange, bool) {
+		rg, ok := ranges[f.Name()]
+		return rg, ok
+	}
+
+	for _, tc := range []struct {
+		where string
+		may   bool
+	}{
+		{"age = 25", true},
+		{"age = 31", false},
+		{"age = 19.5", false},
+		{"age < 20", false},
+		{"age <= 20", true},
+		{"age > 30", false},
+		{"age >= 30", true},
+		{"age != 25", true},
+		{"40 < age", false},
+		{"10 < age", true},
+		{"age BETWEEN 31 AND 40", false},
+		{"age BETWEEN 25 AND 40", true},
+		{"name = \"alice\"", false},
+		{"name = \"bobby\"", true},
+		{"age = 40 AND name = \"bobby\"", false},
+		{"age = 40 OR name = \"bobby\"", true},
+		{"(age = 40 OR age = 50) AND name = \"bobby\"", false},
+		// mismatching types are never ruled out
+		{"age = \"40\"", true},
+		// nulls are lower than any value
+		{"n < 5", true},
+		{"n > 5", false},
+		{"n != 5", true},
+		{"only = 1", true},
+		// unknown fields and other operands
+		{"unknown = 1", true},
+		{"age + 1 = 100", true},
+	} {
+		q, err := QueryFromString("SELECT age FROM x WHERE " + tc.where)
+		require.Nil(t, err)
+		assert.Equal(t, tc.may, q.MayMatch(lookup), tc.where)
+	}
+
+	q, err := QueryFromString("SELECT age FROM x")
+	require.Nil(t, err)
+	assert.True(t, q.MayMatch(lookup))
+}
@@ -42,10 +42,18 @@ const (
 	tokGte // >=
 	tokComparisonOperatorEnd
 
+	tokArithmeticOperatorStart
+	tokPlus  // +
+	tokMinus // -
+	tokArithmeticOperatorEnd
+
 	tokInt
 	tokFloat
 	tokString
 
+	// tokParameter is a bind parameter, either positional (?) or named (:name)
+	tokParameter
+
 	// special values
 
 	tokTrue  // true
@@ -57,6 +65,7 @@ const (
 	tokLeftParenthesis  // (
 	tokRightParenthesis // )
 	tokComma            // ,
+	tokComment          // -- comment or /* comment */
 
 	// tokEnd is the end token
 	tokEnd = -1
@@ -70,6 +79,8 @@ type token struct {
 	Value string
 	// the position into the parsed string
 	Pos int
+	// the position right after the token's last character
+	End int
 }
 
 // Const returns the token's value as a Const
@@ -84,7 +95,7 @@ func (tok token) Const() (*Const, error) {
 	case tokString:
 		return StringConst(tok.Value), nil
 	case tokInt, tokFloat:
-		return ConstFromString(tok.Value), nil
+		return numericLiteralConst(tok.Value), nil
 	default:
 		return nil, fmt.Errorf("Token type %v isn't a const", tok.Type)
 	}
@@ -100,7 +111,9 @@ func (tok token) isNumeric() bool { return tok.Type == tokInt || tok.Type == tok
 func (tok token) isKeyword() bool { return tok.Type > tokKeywordStart && tok.Type < tokKeywordEnd }
 
 // isOperator checks if the token is an operator
-func (tok token) isOperator() bool { return tok.isLogicalOperator() || tok.isComparisonOperator() }
+func (tok token) isOperator() bool {
+	return tok.isLogicalOperator() || tok.isComparisonOperator() || tok.isArithmeticOperator()
+}
 
 // isLogicalOperator checks if the token is a logical operator
 func (tok token) isLogicalOperator() bool {
@@ -112,6 +125,11 @@ func (tok token) isComparisonOperator() bool {
 	return tok.Type > tokComparisonOperatorStart && tok.Type < tokComparisonOperatorEnd
 }
 
+// isArithmeticOperator checks if the token is an arithmetic operator
+func (tok token) isArithmeticOperator() bool {
+	return tok.Type > tokArithmeticOperatorStart && tok.Type < tokArithmeticOperatorEnd
+}
+
 // isConst tests if the token represents a constant value. If so, one can use
 // the Const() method to get the const value.
 func (tok token) isConst() bool {
@@ -119,6 +137,11 @@ func (tok token) isConst() bool {
 		tok.Type == tokFalse || tok.Type == tokNull
 }
 
+// isParameter tests if the token represents a bind parameter
+func (tok token) isParameter() bool {
+	return tok.Type == tokParameter
+}
+
 // isField tests if the token represents a field
 func (tok token) isField() bool {
 	return tok.Type == tokField
@@ -144,6 +167,8 @@ func (t tokenType) String() string {
 		return "Int"
 	case tokFloat:
 		return "Float"
+	case tokParameter:
+		return "Parameter"
 	case tokSelect:
 		return "Select"
 	case tokFrom:
@@ -174,12 +199,18 @@ func (t tokenType) String() string {
 		return "Gt"
 	case tokGte:
 		return "Gte"
+	case tokPlus:
+		return "Plus"
+	case tokMinus:
+		return "Minus"
 	case tokLeftParenthesis:
 		return "tokLeftParenthesis"
 	case tokRightParenthesis:
 		return "tokRightParenthesis"
 	case tokComma:
 		return "Comma"
+	case tokComment:
+		return "Comment"
 	}
 
 	return "UNKNOWN"
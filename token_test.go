@@ -30,7 +30,8 @@ func TestTokenIsKeyword(t *testing.T) {
 
 func TestTokenIsOperator(t *testing.T) {
 	for _, ty := range []tokenType{
-		tokAnd, tokOr, tokEq, tokNeq, tokLt, tokLte, tokGt, tokGte,
+		tokAnd, tokOr, tokEq, tokNeq, tokLt, tokLte, tokGt, tokGte, tokPlus,
+		tokMinus,
 	} {
 		assert.True(t, token{Type: ty}.isOperator())
 	}
@@ -51,12 +52,21 @@ func TestTokenIsComparisonOperator(t *testing.T) {
 	}
 }
 
+func TestTokenIsArithmeticOperator(t *testing.T) {
+	for _, ty := range []tokenType{tokPlus, tokMinus} {
+		assert.True(t, token{Type: ty}.isArithmeticOperator())
+	}
+
+	assert.False(t, token{Type: tokEq}.isArithmeticOperator())
+}
+
 func TestTokenTypeString(t *testing.T) {
 	for _, ty := range []tokenType{
 		tokField, tokInt, tokFloat, tokTrue, tokFalse, tokNull, tokSelect,
 		tokFrom, tokWhere, tokStarting, tokAt, tokAnd, tokOr, tokEq, tokNeq,
 		tokLt, tokLte, tokGt, tokGte, tokLeftParenthesis, tokRightParenthesis,
-		tokComma, tokBetween, tokEnd,
+		tokComma, tokBetween, tokParameter, tokComment, tokPlus, tokMinus,
+		tokEnd,
 	} {
 		assert.NotEqual(t, "", ty.String())
 		assert.NotEqual(t, "UNKNOWN", ty.String())